@@ -1,5 +1,5 @@
 import React, { useState, useEffect } from 'react';
-import { vehicleAPI, brandAPI, bookingAPI, analyticsAPI } from '../services/api';
+import { vehicleAPI, brandAPI, bookingAPI, analyticsAPI, searchAPI, apiErrorCode, apiErrorMessage } from '../services/api';
 import { demoBrands, demoVehicles, demoAnalytics } from './DemoData';
 import LoadingSpinner from './LoadingSpinner';
 import './AdminPanel.css';
@@ -9,6 +9,7 @@ const AdminPanel = () => {
   const [vehicles, setVehicles] = useState([]);
   const [brands, setBrands] = useState([]);
   const [bookings, setBookings] = useState([]);
+  const [synonyms, setSynonyms] = useState([]);
   const [analytics, setAnalytics] = useState(null);
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState('');
@@ -18,6 +19,7 @@ const AdminPanel = () => {
   const [showBrandForm, setShowBrandForm] = useState(false);
   const [editingVehicle, setEditingVehicle] = useState(null);
   const [editingBrand, setEditingBrand] = useState(null);
+  const [newSynonym, setNewSynonym] = useState({ term: '', synonym: '' });
 
   useEffect(() => {
     if (activeTab === 'dashboard') {
@@ -29,6 +31,8 @@ const AdminPanel = () => {
       fetchBrands();
     } else if (activeTab === 'bookings') {
       fetchBookings();
+    } else if (activeTab === 'search') {
+      fetchSynonyms();
     }
   }, [activeTab]);
 
@@ -83,34 +87,75 @@ const AdminPanel = () => {
     }
   };
 
-  const handleDeleteVehicle = async (id) => {
+  const fetchSynonyms = async () => {
+    try {
+      setLoading(true);
+      const response = await searchAPI.getSynonyms();
+      setSynonyms(response.data);
+    } catch (err) {
+      setError('Failed to load search synonyms');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  // After a version conflict the list is stale, so reload it to show the
+  // other admin's changes
+  const reloadOnConflict = (err, reload) => {
+    if (apiErrorCode(err) === 'version_conflict') reload();
+  };
+
+  const handleDeleteVehicle = async (vehicle) => {
     if (window.confirm('Are you sure you want to delete this vehicle?')) {
       try {
-        await vehicleAPI.deleteVehicle(id);
+        await vehicleAPI.deleteVehicle(vehicle.id, vehicle.version);
         fetchVehicles();
       } catch (err) {
-        setError('Failed to delete vehicle');
+        setError(apiErrorMessage(err, 'Failed to delete vehicle'));
+        reloadOnConflict(err, fetchVehicles);
       }
     }
   };
 
-  const handleDeleteBrand = async (id) => {
+  const handleDeleteBrand = async (brand) => {
     if (window.confirm('Are you sure you want to delete this brand?')) {
       try {
-        await brandAPI.deleteBrand(id);
+        await brandAPI.deleteBrand(brand.id, brand.version);
         fetchBrands();
       } catch (err) {
-        setError('Failed to delete brand');
+        setError(apiErrorMessage(err, 'Failed to delete brand'));
+        reloadOnConflict(err, fetchBrands);
       }
     }
   };
 
-  const handleUpdateBookingStatus = async (id, status) => {
+  const handleUpdateBookingStatus = async (booking, status) => {
     try {
-      await bookingAPI.updateBookingStatus(id, status);
+      await bookingAPI.updateBookingStatus(booking.id, status, booking.version);
       fetchBookings();
     } catch (err) {
-      setError('Failed to update booking status');
+      setError(apiErrorMessage(err, 'Failed to update booking status'));
+      reloadOnConflict(err, fetchBookings);
+    }
+  };
+
+  const handleAddSynonym = async (e) => {
+    e.preventDefault();
+    try {
+      await searchAPI.createSynonym(newSynonym);
+      setNewSynonym({ term: '', synonym: '' });
+      fetchSynonyms();
+    } catch (err) {
+      setError(apiErrorMessage(err, 'Failed to add synonym'));
+    }
+  };
+
+  const handleDeleteSynonym = async (synonym) => {
+    try {
+      await searchAPI.deleteSynonym(synonym.id);
+      fetchSynonyms();
+    } catch (err) {
+      setError(apiErrorMessage(err, 'Failed to delete synonym'));
     }
   };
 
@@ -175,6 +220,12 @@ const AdminPanel = () => {
         >
           📞 Bookings
         </button>
+        <button
+          className={`tab-btn ${activeTab === 'search' ? 'active' : ''}`}
+          onClick={() => setActiveTab('search')}
+        >
+          🔍 Search
+        </button>
       </div>
 
       {error && (
@@ -335,7 +386,7 @@ const AdminPanel = () => {
                             Edit
                           </button>
                           <button
-                            onClick={() => handleDeleteVehicle(vehicle.id)}
+                            onClick={() => handleDeleteVehicle(vehicle)}
                             className="delete-btn"
                           >
                             Delete
@@ -389,7 +440,7 @@ const AdminPanel = () => {
                         Edit
                       </button>
                       <button
-                        onClick={() => handleDeleteBrand(brand.id)}
+                        onClick={() => handleDeleteBrand(brand)}
                         className="delete-btn"
                       >
                         Delete
@@ -428,7 +479,14 @@ const AdminPanel = () => {
                     {bookings.map(booking => (
                       <tr key={booking.id}>
                         <td>{formatDate(booking.created_at)}</td>
-                        <td>{booking.customer_name}</td>
+                        <td>
+                          {booking.customer_name}
+                          {booking.suspected_spam && (
+                            <span className="status-badge spam" title={booking.spam_reasons}>
+                              Suspected spam
+                            </span>
+                          )}
+                        </td>
                         <td>
                           {booking.vehicle?.brand?.name} {booking.vehicle?.name}
                         </td>
@@ -443,7 +501,7 @@ const AdminPanel = () => {
                         <td>
                           <select
                             value={booking.status}
-                            onChange={(e) => handleUpdateBookingStatus(booking.id, e.target.value)}
+                            onChange={(e) => handleUpdateBookingStatus(booking, e.target.value)}
                             className={`status-select ${getStatusBadgeClass(booking.status)}`}
                           >
                             <option value="pending">Pending</option>
@@ -470,6 +528,70 @@ const AdminPanel = () => {
             )}
           </div>
         )}
+
+        {/* Search Tab */}
+        {activeTab === 'search' && (
+          <div className="search-tab">
+            <div className="tab-header">
+              <h2>Search Synonyms</h2>
+            </div>
+            <p className="search-tab-hint">
+              Searches for a term also find vehicles matching its synonyms, e.g. "ev" finds Electric vehicles.
+            </p>
+
+            <form onSubmit={handleAddSynonym} className="synonym-form">
+              <input
+                type="text"
+                value={newSynonym.term}
+                onChange={(e) => setNewSynonym({ ...newSynonym, term: e.target.value })}
+                placeholder="Term, e.g. merc"
+                required
+              />
+              <input
+                type="text"
+                value={newSynonym.synonym}
+                onChange={(e) => setNewSynonym({ ...newSynonym, synonym: e.target.value })}
+                placeholder="Synonym, e.g. Mercedes-Benz"
+                required
+              />
+              <button type="submit" className="add-btn">
+                + Add Synonym
+              </button>
+            </form>
+
+            {loading ? (
+              <LoadingSpinner message="Loading synonyms..." />
+            ) : (
+              <div className="synonyms-table">
+                <table>
+                  <thead>
+                    <tr>
+                      <th>Term</th>
+                      <th>Synonym</th>
+                      <th>Actions</th>
+                    </tr>
+                  </thead>
+                  <tbody>
+                    {synonyms.map(synonym => (
+                      <tr key={synonym.id}>
+                        <td>{synonym.term}</td>
+                        <td>{synonym.synonym}</td>
+                        <td>
+                          <button
+                            onClick={() => handleDeleteSynonym(synonym)}
+                            className="delete-btn"
+                          >
+                            Delete
+                          </button>
+                        </td>
+                      </tr>
+                    ))}
+                  </tbody>
+                </table>
+              </div>
+            )}
+          </div>
+        )}
       </div>
 
       {/* Vehicle Form Modal */}
@@ -550,14 +672,14 @@ const VehicleForm = ({ vehicle, brands, onClose, onSuccess }) => {
       };
 
       if (vehicle) {
-        await vehicleAPI.updateVehicle(vehicle.id, submitData);
+        await vehicleAPI.updateVehicle(vehicle.id, submitData, vehicle.version);
       } else {
         await vehicleAPI.createVehicle(submitData);
       }
 
       onSuccess();
     } catch (err) {
-      setError(err.response?.data?.error || 'Failed to save vehicle');
+      setError(apiErrorMessage(err, 'Failed to save vehicle'));
     } finally {
       setLoading(false);
     }
@@ -720,13 +842,13 @@ const BrandForm = ({ brand, onClose, onSuccess }) => {
 
     try {
       if (brand) {
-        await brandAPI.updateBrand(brand.id, formData);
+        await brandAPI.updateBrand(brand.id, formData, brand.version);
       } else {
         await brandAPI.createBrand(formData);
       }
       onSuccess();
     } catch (err) {
-      setError(err.response?.data?.error || 'Failed to save brand');
+      setError(apiErrorMessage(err, 'Failed to save brand'));
     } finally {
       setLoading(false);
     }
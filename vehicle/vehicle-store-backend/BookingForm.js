@@ -1,5 +1,5 @@
 import React, { useState } from 'react';
-import { bookingAPI } from '../services/api';
+import { bookingAPI, apiErrorMessage } from '../services/api';
 import './BookingForm.css';
 
 const BookingForm = ({ vehicle, onClose, onSuccess }) => {
@@ -7,11 +7,16 @@ const BookingForm = ({ vehicle, onClose, onSuccess }) => {
     customer_name: '',
     customer_email: '',
     customer_phone: '',
-    message: ''
+    message: '',
+    website: '' // honeypot, hidden from people
   });
+  // Sent with the booking so the server can spot instant bot submissions
+  const [formStartedAt] = useState(() => new Date().toISOString());
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState('');
   const [success, setSuccess] = useState(false);
+  // One key per submission attempt; resubmitting unchanged details reuses it
+  const [idempotencyKey, setIdempotencyKey] = useState(() => crypto.randomUUID());
 
   const handleInputChange = (e) => {
     const { name, value } = e.target;
@@ -19,6 +24,7 @@ const BookingForm = ({ vehicle, onClose, onSuccess }) => {
       ...prev,
       [name]: value
     }));
+    setIdempotencyKey(crypto.randomUUID());
   };
 
   const handleSubmit = async (e) => {
@@ -29,10 +35,11 @@ const BookingForm = ({ vehicle, onClose, onSuccess }) => {
     try {
       const bookingData = {
         vehicle_id: vehicle.id,
-        ...formData
+        ...formData,
+        form_started_at: formStartedAt
       };
 
-      await bookingAPI.createBooking(bookingData);
+      await bookingAPI.createBooking(bookingData, idempotencyKey);
       setSuccess(true);
       
       // Show success message for 2 seconds, then close
@@ -41,7 +48,7 @@ const BookingForm = ({ vehicle, onClose, onSuccess }) => {
       }, 2000);
     } catch (err) {
       console.error('Booking error:', err);
-      setError(err.response?.data?.error || 'Failed to submit booking request');
+      setError(apiErrorMessage(err, 'Failed to submit booking request'));
     } finally {
       setLoading(false);
     }
@@ -103,6 +110,19 @@ const BookingForm = ({ vehicle, onClose, onSuccess }) => {
         </div>
 
         <form onSubmit={handleSubmit} className="booking-form">
+          <div className="form-honeypot" aria-hidden="true">
+            <label htmlFor="website">Website</label>
+            <input
+              type="text"
+              id="website"
+              name="website"
+              value={formData.website}
+              onChange={handleInputChange}
+              tabIndex="-1"
+              autoComplete="off"
+            />
+          </div>
+
           <div className="form-group">
             <label htmlFor="customer_name">Full Name *</label>
             <input
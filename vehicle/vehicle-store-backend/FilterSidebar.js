@@ -1,14 +1,110 @@
-import React from 'react';
+import React, { useState, useEffect } from 'react';
+import { vehicleAPI } from '../services/api';
 import './FilterSidebar.css';
 
-const FilterSidebar = ({ filters, brands, fuelTypes, onFilterChange, onClearFilters, loading }) => {
+// How long typing must pause before suggestions are fetched
+const SUGGEST_DELAY_MS = 150;
+
+// Label for a price range facet, e.g. "$20k–$30k"
+const formatPriceRange = ({ min, max }) => {
+  const k = (price) => `$${Math.round(price / 1000)}k`;
+  if (!min) return `Under ${k(max)}`;
+  if (!max) return `${k(min)}+`;
+  return `${k(min)}–${k(max)}`;
+};
+
+const FilterSidebar = ({ filters, brands, fuelTypes, facets, onFilterChange, onClearFilters, loading }) => {
+  // The search box is only applied as a filter on Enter or when a suggestion
+  // is picked; while typing it just fetches suggestions
+  const [searchText, setSearchText] = useState(filters.search);
+  const [suggestions, setSuggestions] = useState([]);
+  const [showSuggestions, setShowSuggestions] = useState(false);
+
+  useEffect(() => {
+    setSearchText(filters.search);
+  }, [filters.search]);
+
+  useEffect(() => {
+    if (!searchText.trim() || searchText === filters.search) {
+      setSuggestions([]);
+      return undefined;
+    }
+
+    let cancelled = false;
+    const timer = setTimeout(async () => {
+      try {
+        const response = await vehicleAPI.suggest(searchText);
+        if (!cancelled) setSuggestions(response.data.suggestions || []);
+      } catch (err) {
+        if (!cancelled) setSuggestions([]);
+      }
+    }, SUGGEST_DELAY_MS);
+
+    return () => {
+      cancelled = true;
+      clearTimeout(timer);
+    };
+  }, [searchText]); // eslint-disable-line react-hooks/exhaustive-deps
+
   const handleInputChange = (key, value) => {
     const newFilters = { ...filters, [key]: value };
     onFilterChange(newFilters);
   };
 
+  // Facet counts say how many vehicles each option would match under the
+  // other filters. Without them every option is shown, without a count.
+  const facetCount = (facet, value) => {
+    if (!facets) return undefined;
+    const match = (facets[facet] || []).find(f => f.value === value.toString());
+    return match ? match.count : 0;
+  };
+
+  const withCount = (label, count) => (count === undefined ? label : `${label} (${count})`);
+
+  // Options matching nothing are hidden unless they are the current choice
+  const showOption = (facet, value, selected) => (
+    facetCount(facet, value) !== 0 || value.toString() === selected.toString()
+  );
+
+  // Options of the facets that have no fixed list, with the current choice
+  // kept even when it matches nothing
+  const facetValues = (facet, selected) => {
+    const values = (facets?.[facet] || []).map(f => f.value);
+    return selected && !values.includes(selected.toString()) ? [selected.toString(), ...values] : values;
+  };
+
+  const handlePriceRange = (range) => {
+    onFilterChange({
+      ...filters,
+      minPrice: range.min ? range.min.toString() : '',
+      maxPrice: range.max ? range.max.toString() : '',
+    });
+  };
+
+  const isPriceRange = (range) => (
+    filters.minPrice === (range.min ? range.min.toString() : '') &&
+    filters.maxPrice === (range.max ? range.max.toString() : '')
+  );
+
+  const applySearch = (text) => {
+    setSearchText(text);
+    setSuggestions([]);
+    setShowSuggestions(false);
+    if (text !== filters.search) handleInputChange('search', text);
+  };
+
   const handleSearchChange = (e) => {
-    handleInputChange('search', e.target.value);
+    setSearchText(e.target.value);
+    setShowSuggestions(true);
+  };
+
+  const handleSearchKeyDown = (e) => {
+    if (e.key === 'Enter') {
+      e.preventDefault();
+      applySearch(searchText.trim());
+    } else if (e.key === 'Escape') {
+      setShowSuggestions(false);
+    }
   };
 
   const handleBrandChange = (e) => {
@@ -27,7 +123,8 @@ const FilterSidebar = ({ filters, brands, fuelTypes, onFilterChange, onClearFilt
     handleInputChange('maxPrice', e.target.value);
   };
 
-  const hasActiveFilters = filters.brandId || filters.fuelType || filters.search || filters.minPrice || filters.maxPrice;
+  const hasActiveFilters = filters.brandId || filters.fuelType || filters.transmission || filters.year ||
+    filters.color || filters.search || filters.minPrice || filters.maxPrice;
 
   return (
     <div className="filter-sidebar">
@@ -43,14 +140,39 @@ const FilterSidebar = ({ filters, brands, fuelTypes, onFilterChange, onClearFilt
       {/* Search */}
       <div className="filter-group">
         <label htmlFor="search">Search</label>
-        <input
-          id="search"
-          type="text"
-          placeholder="Search vehicles, brands..."
-          value={filters.search}
-          onChange={handleSearchChange}
-          className="filter-input"
-        />
+        <div className="search-box">
+          <input
+            id="search"
+            type="text"
+            placeholder="Search vehicles, brands..."
+            value={searchText}
+            onChange={handleSearchChange}
+            onKeyDown={handleSearchKeyDown}
+            onFocus={() => setShowSuggestions(true)}
+            onBlur={() => setShowSuggestions(false)}
+            className="filter-input"
+            autoComplete="off"
+          />
+          {showSuggestions && suggestions.length > 0 && (
+            <ul className="search-suggestions">
+              {suggestions.map(suggestion => (
+                <li key={`${suggestion.type}:${suggestion.text}`}>
+                  <button
+                    type="button"
+                    // Picked on mouse down, before the input's blur hides the list
+                    onMouseDown={(e) => {
+                      e.preventDefault();
+                      applySearch(suggestion.text);
+                    }}
+                    className={`search-suggestion ${suggestion.type}`}
+                  >
+                    {suggestion.text}
+                  </button>
+                </li>
+              ))}
+            </ul>
+          )}
+        </div>
       </div>
 
       {/* Brand Filter */}
@@ -64,9 +186,9 @@ const FilterSidebar = ({ filters, brands, fuelTypes, onFilterChange, onClearFilt
           disabled={loading}
         >
           <option value="">All Brands</option>
-          {brands.map(brand => (
+          {brands.filter(brand => showOption('brand', brand.id, filters.brandId)).map(brand => (
             <option key={brand.id} value={brand.id}>
-              {brand.name}
+              {withCount(brand.name, facetCount('brand', brand.id))}
             </option>
           ))}
         </select>
@@ -82,14 +204,70 @@ const FilterSidebar = ({ filters, brands, fuelTypes, onFilterChange, onClearFilt
           className="filter-select"
         >
           <option value="">All Fuel Types</option>
-          {fuelTypes.map(type => (
+          {fuelTypes.filter(type => showOption('fuel_type', type, filters.fuelType)).map(type => (
             <option key={type} value={type}>
-              {type}
+              {withCount(type, facetCount('fuel_type', type))}
             </option>
           ))}
         </select>
       </div>
 
+      {/* Transmission, year and colour only have options once facets load */}
+      {facets && (
+        <>
+          <div className="filter-group">
+            <label htmlFor="transmission">Transmission</label>
+            <select
+              id="transmission"
+              value={filters.transmission}
+              onChange={(e) => handleInputChange('transmission', e.target.value)}
+              className="filter-select"
+            >
+              <option value="">All Transmissions</option>
+              {facetValues('transmission', filters.transmission).map(value => (
+                <option key={value} value={value}>
+                  {withCount(value, facetCount('transmission', value))}
+                </option>
+              ))}
+            </select>
+          </div>
+
+          <div className="filter-group">
+            <label htmlFor="year">Year</label>
+            <select
+              id="year"
+              value={filters.year}
+              onChange={(e) => handleInputChange('year', e.target.value)}
+              className="filter-select"
+            >
+              <option value="">All Years</option>
+              {facetValues('year', filters.year).map(value => (
+                <option key={value} value={value}>
+                  {withCount(value, facetCount('year', value))}
+                </option>
+              ))}
+            </select>
+          </div>
+
+          <div className="filter-group">
+            <label htmlFor="color">Colour</label>
+            <select
+              id="color"
+              value={filters.color}
+              onChange={(e) => handleInputChange('color', e.target.value)}
+              className="filter-select"
+            >
+              <option value="">All Colours</option>
+              {facetValues('color', filters.color).map(value => (
+                <option key={value} value={value}>
+                  {withCount(value, facetCount('color', value))}
+                </option>
+              ))}
+            </select>
+          </div>
+        </>
+      )}
+
       {/* Price Range */}
       <div className="filter-group">
         <label>Price Range</label>
@@ -112,6 +290,19 @@ const FilterSidebar = ({ filters, brands, fuelTypes, onFilterChange, onClearFilt
             min="0"
           />
         </div>
+        {facets && (
+          <div className="price-ranges">
+            {facets.price.filter(range => range.count > 0 || isPriceRange(range)).map(range => (
+              <button
+                key={`${range.min || 0}-${range.max || ''}`}
+                onClick={() => handlePriceRange(range)}
+                className={`quick-filter-btn ${isPriceRange(range) ? 'active' : ''}`}
+              >
+                {withCount(formatPriceRange(range), range.count)}
+              </button>
+            ))}
+          </div>
+        )}
       </div>
 
       {/* Quick Filter Buttons */}
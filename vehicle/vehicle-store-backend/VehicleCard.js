@@ -34,6 +34,23 @@ const VehicleCard = ({ vehicle, onClick, onBookmarkToggle, isBookmarked }) => {
     return vehicle.availability ? 'Available' : 'Sold Out';
   };
 
+  // Search results carry an HTML-escaped snippet with matches wrapped in
+  // <mark></mark>. Split on the markers and unescape the text rather than
+  // injecting HTML, so React still escapes everything it renders.
+  const unescapeHTML = (text) =>
+    text.replace(/&(lt|gt|quot|#34|#39|amp);/g, (entity, name) => ({
+      lt: '<', gt: '>', quot: '"', '#34': '"', '#39': "'", amp: '&',
+    }[name]));
+
+  const renderSnippet = (snippet) => {
+    return snippet.split(/(<mark>.*?<\/mark>)/).map((part, i) => {
+      const match = part.match(/^<mark>(.*)<\/mark>$/);
+      return match
+        ? <mark key={i}>{unescapeHTML(match[1])}</mark>
+        : unescapeHTML(part);
+    });
+  };
+
   return (
     <div 
       className={`vehicle-card ${!vehicle.availability ? 'unavailable' : ''}`}
@@ -69,6 +86,10 @@ const VehicleCard = ({ vehicle, onClick, onBookmarkToggle, isBookmarked }) => {
           <span className="vehicle-model">{vehicle.model}</span>
         </div>
 
+        {vehicle.search_snippet && (
+          <p className="vehicle-search-snippet">{renderSnippet(vehicle.search_snippet)}</p>
+        )}
+
         <div className="vehicle-price">
           <span className="price">{formatPrice(vehicle.price)}</span>
           <span className="year">{vehicle.year}</span>
@@ -14,6 +14,8 @@ const VehicleStore = ({ bookmarks, addBookmark, removeBookmark, isBookmarked })
   const [error, setError] = useState('');
   const [selectedVehicle, setSelectedVehicle] = useState(null);
   const [showModal, setShowModal] = useState(false);
+  const [didYouMean, setDidYouMean] = useState('');
+  const [facets, setFacets] = useState(null);
   
   // Filter states
   const [filters, setFilters] = useState({
@@ -22,8 +24,15 @@ const VehicleStore = ({ bookmarks, addBookmark, removeBookmark, isBookmarked })
     minPrice: '',
     maxPrice: '',
     search: '',
+    transmission: '',
+    year: '',
+    color: '',
   });
 
+  // Sort keys as the API takes them, e.g. "-price,year"; empty keeps the
+  // server's order (relevance when searching)
+  const [sort, setSort] = useState('');
+
   // Pagination
   const [pagination, setPagination] = useState({
     total: 0,
@@ -34,6 +43,18 @@ const VehicleStore = ({ bookmarks, addBookmark, removeBookmark, isBookmarked })
   // Fuel type options
   const fuelTypes = ['Petrol', 'Diesel', 'Electric', 'Hybrid'];
 
+  // Sort options
+  const sortOptions = [
+    { value: '', label: 'Best match' },
+    { value: '-created_at', label: 'Newest listings' },
+    { value: 'price', label: 'Price: low to high' },
+    { value: '-price', label: 'Price: high to low' },
+    { value: '-year', label: 'Year: newest first' },
+    { value: 'mileage', label: 'Mileage: lowest first' },
+    { value: '-popularity', label: 'Most popular' },
+    { value: 'name', label: 'Name: A to Z' },
+  ];
+
   useEffect(() => {
     fetchBrands();
     fetchVehicles();
@@ -41,7 +62,11 @@ const VehicleStore = ({ bookmarks, addBookmark, removeBookmark, isBookmarked })
 
   useEffect(() => {
     fetchVehicles();
-  }, [filters, pagination.offset]); // eslint-disable-line react-hooks/exhaustive-deps
+  }, [filters, sort, pagination.offset]); // eslint-disable-line react-hooks/exhaustive-deps
+
+  useEffect(() => {
+    fetchFacets();
+  }, [filters]); // eslint-disable-line react-hooks/exhaustive-deps
 
   const fetchBrands = async () => {
     try {
@@ -55,11 +80,23 @@ const VehicleStore = ({ bookmarks, addBookmark, removeBookmark, isBookmarked })
     }
   };
 
+  // Facet counts only label the filter options, so without them the sidebar
+  // simply shows every option
+  const fetchFacets = async () => {
+    try {
+      const response = await vehicleAPI.getFacets(filters);
+      setFacets(response.data.facets);
+    } catch (err) {
+      setFacets(null);
+    }
+  };
+
   const fetchVehicles = async () => {
     try {
       setLoading(true);
       const filterParams = {
         ...filters,
+        sort,
         limit: pagination.limit,
         offset: pagination.offset,
       };
@@ -73,6 +110,7 @@ const VehicleStore = ({ bookmarks, addBookmark, removeBookmark, isBookmarked })
 
       const response = await vehicleAPI.getVehicles(filterParams);
       setVehicles(response.data.vehicles || []);
+      setDidYouMean(response.data.did_you_mean || '');
       setPagination(prev => ({
         ...prev,
         total: response.data.total || 0,
@@ -91,6 +129,15 @@ const VehicleStore = ({ bookmarks, addBookmark, removeBookmark, isBookmarked })
       if (filters.fuelType) {
         filteredVehicles = filteredVehicles.filter(v => v.fuel_type === filters.fuelType);
       }
+      if (filters.transmission) {
+        filteredVehicles = filteredVehicles.filter(v => v.transmission === filters.transmission);
+      }
+      if (filters.year) {
+        filteredVehicles = filteredVehicles.filter(v => v.year.toString() === filters.year.toString());
+      }
+      if (filters.color) {
+        filteredVehicles = filteredVehicles.filter(v => v.exterior_color === filters.color);
+      }
       if (filters.minPrice) {
         filteredVehicles = filteredVehicles.filter(v => v.price >= parseFloat(filters.minPrice));
       }
@@ -104,8 +151,21 @@ const VehicleStore = ({ bookmarks, addBookmark, removeBookmark, isBookmarked })
           v.brand.name.toLowerCase().includes(searchTerm)
         );
       }
+
+      // Demo data has no booking counts, so popularity keeps the demo order
+      const sortField = sort.replace(/^-/, '');
+      if (sortField && sortField !== 'popularity') {
+        const direction = sort.startsWith('-') ? -1 : 1;
+        filteredVehicles.sort((a, b) => {
+          const x = a[sortField];
+          const y = b[sortField];
+          const order = typeof x === 'string' ? x.localeCompare(y) : x - y;
+          return direction * order || a.id - b.id;
+        });
+      }
       
       setVehicles(filteredVehicles);
+      setDidYouMean('');
       setPagination(prev => ({
         ...prev,
         total: filteredVehicles.length,
@@ -121,6 +181,11 @@ const VehicleStore = ({ bookmarks, addBookmark, removeBookmark, isBookmarked })
     setPagination(prev => ({ ...prev, offset: 0 })); // Reset to first page
   };
 
+  const handleSortChange = (newSort) => {
+    setSort(newSort);
+    setPagination(prev => ({ ...prev, offset: 0 }));
+  };
+
   const handleVehicleClick = (vehicle) => {
     setSelectedVehicle(vehicle);
     setShowModal(true);
@@ -144,6 +209,10 @@ const VehicleStore = ({ bookmarks, addBookmark, removeBookmark, isBookmarked })
     window.scrollTo({ top: 0, behavior: 'smooth' });
   };
 
+  const searchSuggestion = () => {
+    handleFilterChange({ ...filters, search: didYouMean });
+  };
+
   const clearFilters = () => {
     setFilters({
       brandId: '',
@@ -151,6 +220,9 @@ const VehicleStore = ({ bookmarks, addBookmark, removeBookmark, isBookmarked })
       minPrice: '',
       maxPrice: '',
       search: '',
+      transmission: '',
+      year: '',
+      color: '',
     });
   };
 
@@ -179,6 +251,7 @@ const VehicleStore = ({ bookmarks, addBookmark, removeBookmark, isBookmarked })
           filters={filters}
           brands={brands}
           fuelTypes={fuelTypes}
+          facets={facets}
           onFilterChange={handleFilterChange}
           onClearFilters={clearFilters}
           loading={loading}
@@ -190,13 +263,35 @@ const VehicleStore = ({ bookmarks, addBookmark, removeBookmark, isBookmarked })
               <span className="results-count">
                 {pagination.total} vehicles found
               </span>
-              {(filters.brandId || filters.fuelType || filters.search || filters.minPrice || filters.maxPrice) && (
+              {didYouMean && (
+                <span className="did-you-mean">
+                  Did you mean{' '}
+                  <button onClick={searchSuggestion} className="did-you-mean-btn">
+                    {didYouMean}
+                  </button>
+                  ?
+                </span>
+              )}
+              {(filters.brandId || filters.fuelType || filters.transmission || filters.year || filters.color ||
+                filters.search || filters.minPrice || filters.maxPrice) && (
                 <button onClick={clearFilters} className="clear-filters-btn">
                   Clear all filters
                 </button>
               )}
             </div>
-            {loading && <LoadingSpinner size="small" />}
+            <div className="results-actions">
+              {loading && <LoadingSpinner size="small" />}
+              <label className="sort-select">
+                Sort by
+                <select value={sort} onChange={(e) => handleSortChange(e.target.value)}>
+                  {sortOptions.map(option => (
+                    <option key={option.value} value={option.value}>
+                      {option.label}
+                    </option>
+                  ))}
+                </select>
+              </label>
+            </div>
           </div>
 
           <VehicleGrid
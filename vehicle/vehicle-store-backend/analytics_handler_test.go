@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"vehicle-store-backend/internal/models"
+)
+
+func TestGetPopularVehiclesSkipsSpam(t *testing.T) {
+	ts := newTestServer(t)
+	civic := ts.addVehicle(t, models.Vehicle{Name: "Civic", Year: 2022, Price: 24000})
+	camry := ts.addVehicle(t, models.Vehicle{Name: "Camry", Year: 2024, Price: 28000})
+	accord := ts.addVehicle(t, models.Vehicle{Name: "Accord", Year: 2024, Price: 26000})
+
+	// Accord is only booked by spam, which must not rank it
+	for _, booking := range []models.Booking{
+		{VehicleID: civic.ID},
+		{VehicleID: camry.ID},
+		{VehicleID: camry.ID},
+		{VehicleID: accord.ID, SuspectedSpam: true},
+		{VehicleID: accord.ID, SuspectedSpam: true},
+		{VehicleID: accord.ID, SuspectedSpam: true},
+		{VehicleID: civic.ID, SuspectedSpam: true},
+	} {
+		booking.CustomerName = "Test Customer"
+		booking.CustomerEmail = "test@example.com"
+		booking.Status = "pending"
+		if err := ts.repos.Bookings.Create(&booking); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := ts.do(http.MethodGet, "/api/admin/analytics/popular-vehicles", "", "Authorization", ts.adminToken(t, RoleInventoryManager))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body.String())
+	}
+
+	var body struct {
+		PopularVehicles []models.PopularVehicle `json:"popular_vehicles"`
+	}
+	decode(t, w, &body)
+
+	var got []string
+	for _, p := range body.PopularVehicles {
+		got = append(got, p.VehicleName)
+		if p.VehicleName == "Civic" && p.BookingCount != 1 {
+			t.Errorf("Civic booking count = %d, want 1", p.BookingCount)
+		}
+	}
+	if want := []string{"Camry", "Civic"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("popular vehicles = %v, want %v", got, want)
+	}
+}
@@ -9,6 +9,15 @@ const api = axios.create({
   },
 });
 
+// Attach the admin access token when logged in
+api.interceptors.request.use((config) => {
+  const token = localStorage.getItem('admin-access-token');
+  if (token) {
+    config.headers.Authorization = `Bearer ${token}`;
+  }
+  return config;
+});
+
 // Request interceptor for error handling
 api.interceptors.response.use(
   (response) => response,
@@ -18,34 +27,113 @@ api.interceptors.response.use(
   }
 );
 
+// Errors arrive as { error: { code, message, details, request_id } }.
+// apiErrorCode returns the machine-readable code to branch on, if any.
+export const apiErrorCode = (err) => err.response?.data?.error?.code;
+
+// apiErrorMessage turns an API error into text for the user, listing any
+// invalid fields
+export const apiErrorMessage = (err, fallback) => {
+  const apiError = err.response?.data?.error;
+  if (!apiError) return fallback;
+  if (apiError.details?.length) {
+    return apiError.details
+      .map(d => `${d.field.replace(/_/g, ' ')} ${d.message}`)
+      .join('. ');
+  }
+  return apiError.message || fallback;
+};
+
+// Changes to vehicles, brands and bookings must say which version of the
+// record they were based on (its "version" field, sent back as the ETag).
+// The server answers 412 with code version_conflict if someone else changed
+// it in the meantime.
+const ifMatch = (version) => ({ headers: { 'If-Match': `"${version}"` } });
+
+// Request options for PATCH calls, which send JSON Merge Patch documents
+const mergePatch = (version) => ({
+  headers: { 'Content-Type': 'application/merge-patch+json', 'If-Match': `"${version}"` },
+});
+
+// Admin authentication API calls
+export const authAPI = {
+  // Log in and store the issued tokens
+  login: async (email, password) => {
+    const response = await api.post('/admin/login', { email, password });
+    localStorage.setItem('admin-access-token', response.data.access_token);
+    localStorage.setItem('admin-refresh-token', response.data.refresh_token);
+    return response;
+  },
+
+  // Exchange the refresh token for a new token pair
+  refresh: async () => {
+    const refreshToken = localStorage.getItem('admin-refresh-token');
+    const response = await api.post('/admin/refresh', { refresh_token: refreshToken });
+    localStorage.setItem('admin-access-token', response.data.access_token);
+    localStorage.setItem('admin-refresh-token', response.data.refresh_token);
+    return response;
+  },
+
+  // Forget the stored tokens
+  logout: () => {
+    localStorage.removeItem('admin-access-token');
+    localStorage.removeItem('admin-refresh-token');
+  },
+};
+
+// Query string for the vehicle filters
+const vehicleFilterParams = (filters) => {
+  const params = new URLSearchParams();
+
+  if (filters.brandId) params.append('brand_id', filters.brandId);
+  if (filters.fuelType) params.append('fuel_type', filters.fuelType);
+  if (filters.transmission) params.append('transmission', filters.transmission);
+  if (filters.year) params.append('year', filters.year);
+  if (filters.color) params.append('color', filters.color);
+  if (filters.minPrice) params.append('min_price', filters.minPrice);
+  if (filters.maxPrice) params.append('max_price', filters.maxPrice);
+  if (filters.search) params.append('search', filters.search);
+
+  return params;
+};
+
 // Vehicle API calls
 export const vehicleAPI = {
   // Get all vehicles with filters
   getVehicles: (filters = {}) => {
-    const params = new URLSearchParams();
-    
-    if (filters.brandId) params.append('brand_id', filters.brandId);
-    if (filters.fuelType) params.append('fuel_type', filters.fuelType);
-    if (filters.minPrice) params.append('min_price', filters.minPrice);
-    if (filters.maxPrice) params.append('max_price', filters.maxPrice);
-    if (filters.search) params.append('search', filters.search);
+    const params = vehicleFilterParams(filters);
+
+    if (filters.sort) params.append('sort', filters.sort);
     if (filters.limit) params.append('limit', filters.limit);
     if (filters.offset) params.append('offset', filters.offset);
     
     return api.get(`/vehicles?${params.toString()}`);
   },
 
+  // Get how many vehicles each brand, fuel type, transmission, year, colour
+  // and price range would match under the other filters
+  getFacets: (filters = {}) => api.get(`/vehicles/facets?${vehicleFilterParams(filters).toString()}`),
+
+  // Get completions for a partly typed search
+  suggest: (query, limit) => api.get('/vehicles/suggest', { params: { q: query, limit } }),
+
   // Get vehicle by ID
   getVehicle: (id) => api.get(`/vehicles/${id}`),
 
+  // Get test drive slots for a date (YYYY-MM-DD)
+  getSlots: (id, date) => api.get(`/vehicles/${id}/slots`, { params: { date } }),
+
   // Admin: Create vehicle
   createVehicle: (vehicleData) => api.post('/admin/vehicles', vehicleData),
 
   // Admin: Update vehicle
-  updateVehicle: (id, vehicleData) => api.put(`/admin/vehicles/${id}`, vehicleData),
+  updateVehicle: (id, vehicleData, version) => api.put(`/admin/vehicles/${id}`, vehicleData, ifMatch(version)),
+
+  // Admin: Change only the given vehicle fields (null clears a field)
+  patchVehicle: (id, changes, version) => api.patch(`/admin/vehicles/${id}`, changes, mergePatch(version)),
 
   // Admin: Delete vehicle
-  deleteVehicle: (id) => api.delete(`/admin/vehicles/${id}`),
+  deleteVehicle: (id, version) => api.delete(`/admin/vehicles/${id}`, ifMatch(version)),
 };
 
 // Brand API calls
@@ -60,31 +148,64 @@ export const brandAPI = {
   createBrand: (brandData) => api.post('/admin/brands', brandData),
 
   // Admin: Update brand
-  updateBrand: (id, brandData) => api.put(`/admin/brands/${id}`, brandData),
+  updateBrand: (id, brandData, version) => api.put(`/admin/brands/${id}`, brandData, ifMatch(version)),
+
+  // Admin: Change only the given brand fields (null clears a field)
+  patchBrand: (id, changes, version) => api.patch(`/admin/brands/${id}`, changes, mergePatch(version)),
 
   // Admin: Delete brand
-  deleteBrand: (id) => api.delete(`/admin/brands/${id}`),
+  deleteBrand: (id, version) => api.delete(`/admin/brands/${id}`, ifMatch(version)),
 };
 
 // Booking API calls
 export const bookingAPI = {
   // Create booking
-  createBooking: (bookingData) => api.post('/bookings', bookingData),
+  // Pass the same idempotencyKey when retrying so the booking is only made once
+  createBooking: (bookingData, idempotencyKey) => api.post('/bookings', bookingData, {
+    headers: idempotencyKey ? { 'Idempotency-Key': idempotencyKey } : {},
+  }),
 
   // Admin: Get all bookings
-  getBookings: (status = '') => {
-    const params = status ? `?status=${status}` : '';
-    return api.get(`/admin/bookings${params}`);
+  // suspectedSpam: true for flagged bookings only, false to hide them, null for all
+  getBookings: (status = '', suspectedSpam = null) => {
+    const params = new URLSearchParams();
+    if (status) params.append('status', status);
+    if (suspectedSpam !== null) params.append('suspected_spam', suspectedSpam);
+    const query = params.toString();
+    return api.get(`/admin/bookings${query ? `?${query}` : ''}`);
   },
 
   // Admin: Get booking by ID
   getBooking: (id) => api.get(`/admin/bookings/${id}`),
 
   // Admin: Update booking status
-  updateBookingStatus: (id, status) => api.put(`/admin/bookings/${id}`, { status }),
+  updateBookingStatus: (id, status, version, note = '') => (
+    api.put(`/admin/bookings/${id}`, { status, note }, ifMatch(version))
+  ),
+
+  // Admin: Change only the given booking fields, e.g. { suspected_spam: false }
+  patchBooking: (id, changes, version) => api.patch(`/admin/bookings/${id}`, changes, mergePatch(version)),
+
+  // Admin: Get booking status history
+  getBookingHistory: (id) => api.get(`/admin/bookings/${id}/history`),
 
   // Admin: Delete booking
-  deleteBooking: (id) => api.delete(`/admin/bookings/${id}`),
+  deleteBooking: (id, version) => api.delete(`/admin/bookings/${id}`, ifMatch(version)),
+};
+
+// Search API calls
+export const searchAPI = {
+  // Admin: Get search synonyms
+  getSynonyms: () => api.get('/admin/search-synonyms'),
+
+  // Admin: Make a search word also match a synonym, e.g. { term: 'ev', synonym: 'Electric' }
+  createSynonym: (synonymData) => api.post('/admin/search-synonyms', synonymData),
+
+  // Admin: Update search synonym
+  updateSynonym: (id, synonymData) => api.put(`/admin/search-synonyms/${id}`, synonymData),
+
+  // Admin: Delete search synonym
+  deleteSynonym: (id) => api.delete(`/admin/search-synonyms/${id}`),
 };
 
 // Analytics API calls
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Error codes sent in the error envelope. Clients branch on these; messages
+// are for people and may change.
+const (
+	CodeBadRequest           = "bad_request"
+	CodeInvalidJSON          = "invalid_json"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeValidationFailed     = "validation_failed"
+	CodeUnauthorized         = "unauthorized"
+	CodeInvalidCredentials   = "invalid_credentials"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeRateLimited          = "rate_limited"
+	CodeVehicleUnavailable   = "vehicle_unavailable"
+	CodeInvalidTestDrive     = "invalid_test_drive"
+	CodeSlotTaken            = "slot_taken"
+	CodeInvalidTransition    = "invalid_status_transition"
+	CodeBrandHasVehicles     = "brand_has_vehicles"
+	CodeAlreadyHeld          = "already_held"
+	CodeAlreadyReleased      = "already_released"
+	CodeIdempotencyMismatch  = "idempotency_key_reused"
+	CodeIdempotencyBusy      = "idempotency_key_in_use"
+	CodePreconditionRequired = "precondition_required"
+	CodeVersionConflict      = "version_conflict"
+	CodeConflict             = "conflict"
+	CodeSynonymExists        = "synonym_exists"
+	CodeInternal             = "internal_error"
+)
+
+const (
+	requestIDHeader     = "X-Request-ID"
+	requestIDContextKey = "request_id"
+)
+
+// validRequestID limits the client-supplied request IDs that are echoed back
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// APIError is the body of every error response, sent as {"error": {...}}
+type APIError struct {
+	Status    int          `json:"-"`
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Details   []FieldError `json:"details,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	// Err is the underlying cause. It is logged for server errors and never
+	// sent to the client.
+	Err error `json:"-"`
+}
+
+func (e *APIError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// NewAPIError returns an APIError with the given status, code and message
+func NewAPIError(status int, code, message string) *APIError {
+	return &APIError{Status: status, Code: code, Message: message}
+}
+
+// notFoundError reports that the named resource does not exist
+func notFoundError(resource string) *APIError {
+	return NewAPIError(http.StatusNotFound, CodeNotFound, resource+" not found")
+}
+
+// badRequestError reports a malformed request
+func badRequestError(message string) *APIError {
+	return NewAPIError(http.StatusBadRequest, CodeBadRequest, message)
+}
+
+// validationError reports request values that are well-formed but invalid
+func validationError(details ...FieldError) *APIError {
+	apiErr := NewAPIError(http.StatusUnprocessableEntity, CodeValidationFailed, "Validation failed")
+	apiErr.Details = details
+	return apiErr
+}
+
+// internalError reports a server-side failure caused by err
+func internalError(message string, err error) *APIError {
+	apiErr := NewAPIError(http.StatusInternalServerError, CodeInternal, message)
+	apiErr.Err = err
+	return apiErr
+}
+
+// respondError hands err to errorMiddleware to render and stops the handler
+// chain. Errors that are not an *APIError are rendered as internal errors.
+func respondError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// requestID returns the ID assigned to the request by requestIDMiddleware
+func requestID(c *gin.Context) string {
+	return c.GetString(requestIDContextKey)
+}
+
+// requestIDMiddleware tags each request with an ID, reusing a sane
+// X-Request-ID from the client or proxy, and echoes it in the response
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+
+		c.Set(requestIDContextKey, id)
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+// newRequestID returns a random 32-character hex ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// errorMiddleware renders the last error recorded with respondError as the
+// JSON error envelope, logging the cause of server errors
+func errorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			apiErr = internalError("Internal server error", err)
+		}
+
+		body := *apiErr
+		body.RequestID = requestID(c)
+
+		if body.Status >= http.StatusInternalServerError {
+			log.Printf("Request %s failed: %v", body.RequestID, apiErr)
+		}
+
+		c.JSON(body.Status, gin.H{"error": body})
+	}
+}
+
+// recoverPanic turns a panicking handler into an internal error response
+func recoverPanic(c *gin.Context, recovered interface{}) {
+	respondError(c, internalError("Internal server error", fmt.Errorf("panic: %v", recovered)))
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"vehicle-store-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 7 * 24 * time.Hour
+
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+
+	// adminContextKey is the gin context key holding the authenticated admin
+	adminContextKey = "admin"
+)
+
+// jwtSecret signs and verifies admin tokens. It comes from JWT_SECRET so
+// tokens survive restarts; without it a random secret is generated per run.
+var jwtSecret = loadJWTSecret()
+
+// authClaims are the JWT claims issued to admin users
+type authClaims struct {
+	Email string `json:"email"`
+	Type  string `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+// loadJWTSecret reads JWT_SECRET or falls back to a random per-process secret
+func loadJWTSecret() []byte {
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+
+	log.Println("JWT_SECRET not set; using a random secret, admin sessions will not survive a restart")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatal("Failed to generate JWT secret:", err)
+	}
+	return secret
+}
+
+// HashPassword returns the bcrypt hash of password
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches the bcrypt hash
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// issueToken signs a token of the given type for admin
+func issueToken(admin models.AdminUser, tokenType string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := authClaims{
+		Email: admin.Email,
+		Type:  tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(admin.ID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+}
+
+// parseToken validates a signed token and checks it is of the expected type
+func parseToken(tokenString, tokenType string) (*authClaims, error) {
+	claims := &authClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return jwtSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Type != tokenType {
+		return nil, errors.New("unexpected token type")
+	}
+
+	return claims, nil
+}
+
+// issueTokenPair responds with a fresh access and refresh token for admin
+func issueTokenPair(c *gin.Context, admin models.AdminUser) {
+	accessToken, err := issueToken(admin, tokenTypeAccess, accessTokenTTL)
+	if err != nil {
+		respondError(c, internalError("Failed to issue token", err))
+		return
+	}
+
+	refreshToken, err := issueToken(admin, tokenTypeRefresh, refreshTokenTTL)
+	if err != nil {
+		respondError(c, internalError("Failed to issue token", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"token_type":    "Bearer",
+		"expires_in":    int(accessTokenTTL.Seconds()),
+		"user":          admin,
+	})
+}
+
+// adminFromClaims loads the admin identified by the token subject
+func (s *Server) adminFromClaims(claims *authClaims) (*models.AdminUser, error) {
+	id, err := strconv.ParseUint(claims.Subject, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	return s.Admins.GetByID(uint(id))
+}
+
+// Login handles POST /api/admin/login
+func (s *Server) Login(c *gin.Context) {
+	var credentials struct {
+		Email    string `json:"email" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+
+	if !bindJSON(c, &credentials) {
+		return
+	}
+
+	admin, err := s.Admins.GetByEmail(credentials.Email)
+	if err != nil {
+		respondError(c, NewAPIError(http.StatusUnauthorized, CodeInvalidCredentials, "Invalid email or password"))
+		return
+	}
+
+	if !CheckPassword(admin.PasswordHash, credentials.Password) {
+		respondError(c, NewAPIError(http.StatusUnauthorized, CodeInvalidCredentials, "Invalid email or password"))
+		return
+	}
+
+	issueTokenPair(c, *admin)
+}
+
+// RefreshToken handles POST /api/admin/refresh
+func (s *Server) RefreshToken(c *gin.Context) {
+	var body struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if !bindJSON(c, &body) {
+		return
+	}
+
+	claims, err := parseToken(body.RefreshToken, tokenTypeRefresh)
+	if err != nil {
+		respondError(c, NewAPIError(http.StatusUnauthorized, CodeUnauthorized, "Invalid refresh token"))
+		return
+	}
+
+	admin, err := s.adminFromClaims(claims)
+	if err != nil {
+		respondError(c, NewAPIError(http.StatusUnauthorized, CodeUnauthorized, "Invalid refresh token"))
+		return
+	}
+
+	issueTokenPair(c, *admin)
+}
+
+// AuthRequired rejects requests without a valid admin access token and
+// stores the authenticated admin in the context
+func (s *Server) AuthRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		tokenString, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || tokenString == "" {
+			respondError(c, NewAPIError(http.StatusUnauthorized, CodeUnauthorized, "Authorization required"))
+			return
+		}
+
+		claims, err := parseToken(tokenString, tokenTypeAccess)
+		if err != nil {
+			respondError(c, NewAPIError(http.StatusUnauthorized, CodeUnauthorized, "Invalid or expired token"))
+			return
+		}
+
+		admin, err := s.adminFromClaims(claims)
+		if err != nil {
+			respondError(c, NewAPIError(http.StatusUnauthorized, CodeUnauthorized, "Invalid or expired token"))
+			return
+		}
+
+		c.Set(adminContextKey, *admin)
+		c.Next()
+	}
+}
+
+// currentAdmin returns the admin stored by AuthRequired
+func currentAdmin(c *gin.Context) (models.AdminUser, bool) {
+	value, ok := c.Get(adminContextKey)
+	if !ok {
+		return models.AdminUser{}, false
+	}
+	admin, ok := value.(models.AdminUser)
+	return admin, ok
+}
@@ -1,66 +1,169 @@
 package main
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
+	"time"
 
-	"vehicle-store-backend/internal/database"
 	"vehicle-store-backend/internal/models"
 
 	"github.com/gin-gonic/gin"
 )
 
+// errVehicleUnavailable aborts a booking transaction for an unavailable vehicle
+var errVehicleUnavailable = errors.New("vehicle is not available")
+
 // CreateBooking handles POST /api/bookings
-func CreateBooking(c *gin.Context) {
-	var booking models.Booking
+func (s *Server) CreateBooking(c *gin.Context) {
+	var sub bookingSubmission
 
-	if err := c.ShouldBindJSON(&booking); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &sub) {
 		return
 	}
 
-	// Verify vehicle exists and is available
-	var vehicle models.Vehicle
-	if err := database.DB.First(&vehicle, booking.VehicleID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle not found"})
+	booking := &sub.Booking
+
+	// Check availability and insert under a lock on the vehicle row so it
+	// cannot be marked unavailable in between
+	err := s.Tx.WithinTransaction(func(repos Repositories) error {
+		vehicle, err := repos.Vehicles.GetByIDForUpdate(booking.VehicleID)
+		if err != nil {
+			return err
+		}
+
+		if !vehicle.Availability {
+			return errVehicleUnavailable
+		}
+
+		if booking.RequestedStart != nil {
+			if err := s.checkTestDriveSlot(repos, vehicle, booking); err != nil {
+				return err
+			}
+		} else if booking.RequestedEnd != nil {
+			return &TestDriveError{Reason: "requested_end requires requested_start"}
+		}
+
+		// The vehicle lock also serializes the duplicate check
+		if err := flagSuspectedSpam(repos, &sub, time.Now()); err != nil {
+			return err
+		}
+
+		// New bookings always start pending
+		booking.Status = BookingPending
+
+		if err := repos.Bookings.Create(booking); err != nil {
+			return err
+		}
+
+		entry := models.BookingStatusHistory{
+			BookingID: booking.ID,
+			ToStatus:  BookingPending,
+			Actor:     "customer",
+		}
+		if booking.SuspectedSpam {
+			entry.Note = "Flagged as suspected spam: " + booking.SpamReasons
+		}
+		if err := repos.Bookings.AddStatusHistory(&entry); err != nil {
+			return err
+		}
+
+		// Like the emails, webhooks only hear about bookings that look genuine
+		if booking.SuspectedSpam {
+			return nil
+		}
+		return enqueueEvent(repos, EventBookingCreated, booking)
+	})
+
+	var testDriveErr *TestDriveError
+	var apiErr *APIError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		respondError(c, notFoundError("Vehicle"))
+		return
+	case errors.Is(err, errVehicleUnavailable):
+		respondError(c, NewAPIError(http.StatusBadRequest, CodeVehicleUnavailable, "Vehicle is not available for booking"))
+		return
+	case errors.As(err, &testDriveErr):
+		respondError(c, NewAPIError(http.StatusBadRequest, CodeInvalidTestDrive, testDriveErr.Error()))
+		return
+	case errors.Is(err, errSlotTaken):
+		respondError(c, NewAPIError(http.StatusConflict, CodeSlotTaken, "The requested test drive time is already booked"))
+		return
+	case errors.As(err, &apiErr):
+		respondError(c, apiErr)
+		return
+	case err != nil:
+		respondError(c, internalError("Failed to create booking", err))
 		return
 	}
 
-	if !vehicle.Availability {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Vehicle is not available for booking"})
-		return
+	// Fetch the created booking with vehicle and brand information
+	if created, err := s.Bookings.GetByID(booking.ID); err == nil {
+		booking = created
 	}
 
-	// Set default status
-	if booking.Status == "" {
-		booking.Status = "pending"
+	// Suspected spam is kept for review but must not trigger emails, which
+	// would let bots use the form to send mail to arbitrary addresses.
+	// The booking is committed; a failed email must not fail the request.
+	if booking.SuspectedSpam {
+		log.Printf("Booking %d flagged as suspected spam (%s)", booking.ID, booking.SpamReasons)
+	} else if err := s.Notifier.BookingCreated(booking); err != nil {
+		log.Println("Failed to send booking notification:", err)
 	}
 
-	if err := database.DB.Create(&booking).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
-		return
+	c.JSON(http.StatusCreated, publicBooking{Booking: booking})
+}
+
+// checkTestDriveSlot fills in a missing end time and verifies the requested
+// test drive is within dealer hours and free. It must run inside the booking
+// transaction, after the vehicle row is locked.
+func (s *Server) checkTestDriveSlot(repos Repositories, vehicle *models.Vehicle, booking *models.Booking) error {
+	start := *booking.RequestedStart
+	if booking.RequestedEnd == nil {
+		end := start.Add(testDriveSlotLength)
+		booking.RequestedEnd = &end
 	}
+	end := *booking.RequestedEnd
 
-	// Fetch the created booking with vehicle and brand information
-	database.DB.Preload("Vehicle.Brand").First(&booking, booking.ID)
+	hours, err := s.dealerHours(dealerName(vehicle))
+	if err != nil {
+		return err
+	}
+
+	if err := validateTestDriveWindow(hours, start, end); err != nil {
+		return err
+	}
+
+	conflicts, err := repos.Bookings.ListTestDrives(vehicle.ID, start, end, testDriveBlockingStatuses)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return errSlotTaken
+	}
 
-	c.JSON(http.StatusCreated, booking)
+	return nil
 }
 
 // GetBookings handles GET /api/admin/bookings
-func GetBookings(c *gin.Context) {
-	var bookings []models.Booking
-
+func (s *Server) GetBookings(c *gin.Context) {
 	// Parse query parameters for filtering
-	status := c.Query("status")
-
-	query := database.DB.Preload("Vehicle.Brand")
-
-	if status != "" {
-		query = query.Where("status = ?", status)
+	filter := models.BookingFilter{Status: c.Query("status")}
+
+	if raw := c.Query("suspected_spam"); raw != "" {
+		spam, err := strconv.ParseBool(raw)
+		if err != nil {
+			respondError(c, badRequestError("Invalid suspected_spam value"))
+			return
+		}
+		filter.SuspectedSpam = &spam
 	}
 
-	if err := query.Order("created_at DESC").Find(&bookings).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
+	bookings, err := s.Bookings.List(filter)
+	if err != nil {
+		respondError(c, internalError("Failed to fetch bookings", err))
 		return
 	}
 
@@ -68,75 +171,291 @@ func GetBookings(c *gin.Context) {
 }
 
 // GetBookingByID handles GET /api/admin/bookings/:id
-func GetBookingByID(c *gin.Context) {
-	id := c.Param("id")
-	var booking models.Booking
+func (s *Server) GetBookingByID(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		respondError(c, notFoundError("Booking"))
+		return
+	}
 
-	if err := database.DB.Preload("Vehicle.Brand").First(&booking, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+	booking, err := s.Bookings.GetByID(id)
+	if err != nil {
+		respondError(c, notFoundError("Booking"))
 		return
 	}
 
+	setETag(c, booking.Version)
 	c.JSON(http.StatusOK, booking)
 }
 
 // UpdateBookingStatus handles PUT /api/admin/bookings/:id
-func UpdateBookingStatus(c *gin.Context) {
-	id := c.Param("id")
-	var booking models.Booking
-
-	if err := database.DB.First(&booking, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+func (s *Server) UpdateBookingStatus(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		respondError(c, notFoundError("Booking"))
 		return
 	}
 
 	var updateData struct {
 		Status string `json:"status" binding:"required"`
+		Note   string `json:"note"`
 	}
 
-	if err := c.ShouldBindJSON(&updateData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &updateData) {
 		return
 	}
 
-	// Validate status values
-	validStatuses := map[string]bool{
-		"pending":   true,
-		"contacted": true,
-		"completed": true,
-		"cancelled": true,
+	if !IsValidBookingStatus(updateData.Status) {
+		respondError(c, validationError(FieldError{Field: "status", Message: "must be one of: pending, contacted, completed, cancelled"}))
+		return
 	}
 
-	if !validStatuses[updateData.Status] {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status value"})
+	admin, _ := currentAdmin(c)
+
+	var booking *models.Booking
+	var previousStatus string
+	err = s.Tx.WithinTransaction(func(repos Repositories) error {
+		b, err := repos.Bookings.GetByIDForUpdate(id)
+		if err != nil {
+			return err
+		}
+
+		if err := checkIfMatch(c, b.Version); err != nil {
+			return err
+		}
+		if err := CheckBookingTransition(b.Status, updateData.Status); err != nil {
+			return err
+		}
+
+		entry := models.BookingStatusHistory{
+			BookingID:  b.ID,
+			FromStatus: b.Status,
+			ToStatus:   updateData.Status,
+			Actor:      admin.Email,
+			Note:       updateData.Note,
+		}
+
+		previousStatus = b.Status
+		b.Status = updateData.Status
+		if err := repos.Bookings.Update(b); err != nil {
+			return err
+		}
+
+		booking = b
+		return recordStatusChange(repos, b, &entry)
+	})
+
+	var transitionErr *TransitionError
+	var apiErr *APIError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		respondError(c, notFoundError("Booking"))
+		return
+	case errors.Is(err, ErrVersionConflict):
+		respondError(c, versionConflictError())
+		return
+	case errors.As(err, &transitionErr):
+		respondError(c, NewAPIError(http.StatusConflict, CodeInvalidTransition, transitionErr.Error()))
 		return
+	case errors.As(err, &apiErr):
+		respondError(c, apiErr)
+		return
+	case err != nil:
+		respondError(c, internalError("Failed to update booking status", err))
+		return
+	}
+
+	// Fetch the updated booking with vehicle and brand information
+	if updated, err := s.Bookings.GetByID(booking.ID); err == nil {
+		booking = updated
 	}
 
-	booking.Status = updateData.Status
+	if err := s.Notifier.BookingStatusChanged(booking, previousStatus, updateData.Note); err != nil {
+		log.Println("Failed to send booking status notification:", err)
+	}
 
-	if err := database.DB.Save(&booking).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking status"})
+	setETag(c, booking.Version)
+	c.JSON(http.StatusOK, booking)
+}
+
+// PatchBooking handles PATCH /api/admin/bookings/:id, updating only the
+// fields present in a JSON Merge Patch. A status change is checked against
+// the state machine and recorded in the history like one made with PUT; the
+// patch may carry a "note" for the history entry.
+func (s *Server) PatchBooking(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		respondError(c, notFoundError("Booking"))
+		return
+	}
+
+	patch, err := readMergePatch(c, bookingReadOnlyFields...)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	note, ok := patch["note"].(string)
+	if _, present := patch["note"]; present && !ok {
+		respondError(c, validationError(FieldError{Field: "note", Message: "must be a string"}))
+		return
+	}
+	delete(patch, "note")
+
+	admin, _ := currentAdmin(c)
+
+	var booking *models.Booking
+	var previousStatus string
+	err = s.Tx.WithinTransaction(func(repos Repositories) error {
+		b, err := repos.Bookings.GetByIDForUpdate(id)
+		if err != nil {
+			return err
+		}
+
+		if err := checkIfMatch(c, b.Version); err != nil {
+			return err
+		}
+
+		previousStatus = b.Status
+		if err := applyMergePatch(b, patch); err != nil {
+			return err
+		}
+
+		statusChanged := b.Status != previousStatus
+		if statusChanged {
+			if !IsValidBookingStatus(b.Status) {
+				return validationError(FieldError{Field: "status", Message: "must be one of: pending, contacted, completed, cancelled"})
+			}
+			if err := CheckBookingTransition(previousStatus, b.Status); err != nil {
+				return err
+			}
+		}
+
+		booking = b
+		if err := repos.Bookings.Update(b); err != nil {
+			return err
+		}
+		if err := enqueueEvent(repos, EventBookingUpdated, b); err != nil {
+			return err
+		}
+		if !statusChanged {
+			return nil
+		}
+
+		return recordStatusChange(repos, b, &models.BookingStatusHistory{
+			BookingID:  b.ID,
+			FromStatus: previousStatus,
+			ToStatus:   b.Status,
+			Actor:      admin.Email,
+			Note:       note,
+		})
+	})
+
+	var transitionErr *TransitionError
+	var apiErr *APIError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		respondError(c, notFoundError("Booking"))
+		return
+	case errors.Is(err, ErrVersionConflict):
+		respondError(c, versionConflictError())
+		return
+	case errors.As(err, &transitionErr):
+		respondError(c, NewAPIError(http.StatusConflict, CodeInvalidTransition, transitionErr.Error()))
+		return
+	case errors.As(err, &apiErr):
+		respondError(c, apiErr)
+		return
+	case err != nil:
+		respondError(c, internalError("Failed to update booking", err))
 		return
 	}
 
 	// Fetch the updated booking with vehicle and brand information
-	database.DB.Preload("Vehicle.Brand").First(&booking, booking.ID)
+	if updated, err := s.Bookings.GetByID(booking.ID); err == nil {
+		booking = updated
+	}
+
+	if booking.Status != previousStatus {
+		if err := s.Notifier.BookingStatusChanged(booking, previousStatus, note); err != nil {
+			log.Println("Failed to send booking status notification:", err)
+		}
+	}
 
+	setETag(c, booking.Version)
 	c.JSON(http.StatusOK, booking)
 }
 
-// DeleteBooking handles DELETE /api/admin/bookings/:id
-func DeleteBooking(c *gin.Context) {
-	id := c.Param("id")
-	var booking models.Booking
+// recordStatusChange adds entry to the booking's status history and enqueues
+// the status change event for b
+func recordStatusChange(repos Repositories, b *models.Booking, entry *models.BookingStatusHistory) error {
+	if err := repos.Bookings.AddStatusHistory(entry); err != nil {
+		return err
+	}
+
+	return enqueueEvent(repos, EventBookingStatusChanged, gin.H{
+		"booking":     b,
+		"from_status": entry.FromStatus,
+		"to_status":   entry.ToStatus,
+		"actor":       entry.Actor,
+		"note":        entry.Note,
+	})
+}
+
+// GetBookingHistory handles GET /api/admin/bookings/:id/history
+func (s *Server) GetBookingHistory(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		respondError(c, notFoundError("Booking"))
+		return
+	}
+
+	if _, err := s.Bookings.GetByID(id); err != nil {
+		respondError(c, notFoundError("Booking"))
+		return
+	}
 
-	if err := database.DB.First(&booking, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+	history, err := s.Bookings.StatusHistory(id)
+	if err != nil {
+		respondError(c, internalError("Failed to fetch booking history", err))
 		return
 	}
 
-	if err := database.DB.Delete(&booking).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete booking"})
+	c.JSON(http.StatusOK, gin.H{
+		"booking_id": id,
+		"history":    history,
+	})
+}
+
+// DeleteBooking handles DELETE /api/admin/bookings/:id
+func (s *Server) DeleteBooking(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		respondError(c, notFoundError("Booking"))
+		return
+	}
+
+	err = s.Tx.WithinTransaction(func(repos Repositories) error {
+		booking, err := repos.Bookings.GetByIDForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if err := checkIfMatch(c, booking.Version); err != nil {
+			return err
+		}
+		return repos.Bookings.Delete(id)
+	})
+
+	var apiErr *APIError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		respondError(c, notFoundError("Booking"))
+		return
+	case errors.As(err, &apiErr):
+		respondError(c, apiErr)
+		return
+	case err != nil:
+		respondError(c, internalError("Failed to delete booking", err))
 		return
 	}
 
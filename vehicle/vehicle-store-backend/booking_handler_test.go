@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"vehicle-store-backend/internal/models"
+)
+
+// bookConcurrently sends each booking body at the same time and returns
+// the responses in the same order
+func (ts *testServer) bookConcurrently(bodies []string) []*httptest.ResponseRecorder {
+	responses := make([]*httptest.ResponseRecorder, len(bodies))
+	start := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i, body := range bodies {
+		wg.Add(1)
+		go func(i int, body string) {
+			defer wg.Done()
+			<-start
+			responses[i] = ts.do(http.MethodPost, "/api/bookings", body)
+		}(i, body)
+	}
+	close(start)
+	wg.Wait()
+
+	return responses
+}
+
+func TestCreateBookingConcurrently(t *testing.T) {
+	const requests = 8
+
+	servers := map[string]func(t *testing.T) *testServer{
+		"memory": newTestServer,
+		"sqlite": newGormTestServer,
+	}
+
+	for name, newServer := range servers {
+		t.Run(name, func(t *testing.T) {
+			t.Run("same test drive slot", func(t *testing.T) {
+				ts := newServer(t)
+				vehicle := ts.addVehicle(t, models.Vehicle{Name: "Model 3", Year: 2024, Price: 45000})
+				monday := nextWeekday(time.Monday, 10)
+
+				var bodies []string
+				for i := 0; i < requests; i++ {
+					// Overlapping, not identical, slots
+					start := monday.Add(time.Duration(i%2) * 15 * time.Minute)
+					bodies = append(bodies, bookingJSON(vehicle.ID, fmt.Sprintf("driver%d@example.com", i), testDriveJSON(start)))
+				}
+
+				created := 0
+				for i, w := range ts.bookConcurrently(bodies) {
+					switch w.Code {
+					case http.StatusCreated:
+						created++
+					case http.StatusConflict:
+						expectError(t, w, http.StatusConflict, CodeSlotTaken)
+					default:
+						t.Errorf("booking %d = %d: %s", i, w.Code, w.Body.String())
+					}
+				}
+				if created != 1 {
+					t.Errorf("%d bookings took the slot, want 1", created)
+				}
+			})
+
+			t.Run("same customer", func(t *testing.T) {
+				ts := newServer(t)
+				vehicle := ts.addVehicle(t, models.Vehicle{Name: "Model 3", Year: 2024, Price: 45000})
+
+				bodies := make([]string, requests)
+				for i := range bodies {
+					bodies[i] = bookingJSON(vehicle.ID, "repeat@example.com")
+				}
+
+				for i, w := range ts.bookConcurrently(bodies) {
+					if w.Code != http.StatusCreated {
+						t.Fatalf("booking %d = %d: %s", i, w.Code, w.Body.String())
+					}
+				}
+
+				// Each booking sees the ones committed before it, so only the
+				// first is taken as genuine. Customers are not told, so the
+				// flags are read back from the store.
+				bookings, err := ts.Bookings.List(models.BookingFilter{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(bookings) != requests {
+					t.Fatalf("%d bookings stored, want %d", len(bookings), requests)
+				}
+				genuine := 0
+				for _, booking := range bookings {
+					if !booking.SuspectedSpam {
+						genuine++
+					} else if booking.SpamReasons != SpamReasonDuplicate {
+						t.Errorf("booking %d spam reasons = %q, want %q", booking.ID, booking.SpamReasons, SpamReasonDuplicate)
+					}
+				}
+				if genuine != 1 {
+					t.Errorf("%d bookings were not flagged as duplicates, want 1", genuine)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Booking statuses
+const (
+	BookingPending   = "pending"
+	BookingContacted = "contacted"
+	BookingCompleted = "completed"
+	BookingCancelled = "cancelled"
+)
+
+// bookingTransitions lists the statuses each status may move to.
+// Completed and cancelled bookings are final.
+var bookingTransitions = map[string][]string{
+	BookingPending:   {BookingContacted, BookingCancelled},
+	BookingContacted: {BookingCompleted, BookingCancelled},
+	BookingCompleted: {},
+	BookingCancelled: {},
+}
+
+// TransitionError reports a status change the state machine does not allow
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot change booking status from %s to %s", e.From, e.To)
+}
+
+// IsValidBookingStatus reports whether status is a known booking status
+func IsValidBookingStatus(status string) bool {
+	_, ok := bookingTransitions[status]
+	return ok
+}
+
+// CheckBookingTransition returns a *TransitionError unless from may move to to
+func CheckBookingTransition(from, to string) error {
+	for _, allowed := range bookingTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return &TransitionError{From: from, To: to}
+}
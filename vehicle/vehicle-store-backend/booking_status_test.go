@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"vehicle-store-backend/internal/models"
+)
+
+func TestCheckBookingTransition(t *testing.T) {
+	statuses := []string{BookingPending, BookingContacted, BookingCompleted, BookingCancelled}
+	allowed := map[[2]string]bool{
+		{BookingPending, BookingContacted}:   true,
+		{BookingPending, BookingCancelled}:   true,
+		{BookingContacted, BookingCompleted}: true,
+		{BookingContacted, BookingCancelled}: true,
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			err := CheckBookingTransition(from, to)
+			if want := allowed[[2]string{from, to}]; (err == nil) != want {
+				t.Errorf("CheckBookingTransition(%s, %s) = %v, want allowed %v", from, to, err, want)
+			}
+		}
+	}
+}
+
+func TestUpdateBookingStatus(t *testing.T) {
+	ts := newTestServer(t)
+	auth := ts.adminToken(t, RoleSales)
+	vehicle := ts.addVehicle(t, models.Vehicle{Name: "Civic", Year: 2024, Price: 25000})
+
+	w := ts.do(http.MethodPost, "/api/bookings", bookingJSON(vehicle.ID, "jo@example.com"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create booking: %d %s", w.Code, w.Body.String())
+	}
+	var booking models.Booking
+	decode(t, w, &booking)
+	path := fmt.Sprintf("/api/admin/bookings/%d", booking.ID)
+
+	// Completing skips contacting the customer
+	w = ts.do(http.MethodPut, path, `{"status":"completed"}`, "Authorization", auth, "If-Match", `"1"`)
+	expectError(t, w, http.StatusConflict, CodeInvalidTransition)
+
+	w = ts.do(http.MethodPut, path, `{"status":"contacted","note":"Called back"}`, "Authorization", auth, "If-Match", `"1"`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("pending to contacted: %d %s", w.Code, w.Body.String())
+	}
+	if etag := w.Header().Get("ETag"); etag != `"2"` {
+		t.Errorf("ETag = %s, want \"2\"", etag)
+	}
+
+	w = ts.do(http.MethodPut, path, `{"status":"completed"}`, "Authorization", auth, "If-Match", `"2"`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("contacted to completed: %d %s", w.Code, w.Body.String())
+	}
+
+	// Completed bookings are final
+	w = ts.do(http.MethodPut, path, `{"status":"cancelled"}`, "Authorization", auth, "If-Match", `"3"`)
+	expectError(t, w, http.StatusConflict, CodeInvalidTransition)
+
+	w = ts.do(http.MethodPut, path, `{"status":"archived"}`, "Authorization", auth, "If-Match", `"3"`)
+	expectError(t, w, http.StatusUnprocessableEntity, CodeValidationFailed)
+
+	var history struct {
+		History []models.BookingStatusHistory `json:"history"`
+	}
+	decode(t, ts.do(http.MethodGet, path+"/history", "", "Authorization", auth), &history)
+
+	want := []struct{ from, to, actor, note string }{
+		{"", BookingPending, "customer", ""},
+		{BookingPending, BookingContacted, "sales@example.com", "Called back"},
+		{BookingContacted, BookingCompleted, "sales@example.com", ""},
+	}
+	if len(history.History) != len(want) {
+		t.Fatalf("history has %d entries, want %d: %+v", len(history.History), len(want), history.History)
+	}
+	for i, w := range want {
+		got := history.History[i]
+		if got.FromStatus != w.from || got.ToStatus != w.to || got.Actor != w.actor || got.Note != w.note {
+			t.Errorf("history[%d] = %s -> %s by %s (%q), want %s -> %s by %s (%q)",
+				i, got.FromStatus, got.ToStatus, got.Actor, got.Note, w.from, w.to, w.actor, w.note)
+		}
+	}
+}
@@ -1,20 +1,26 @@
 package main
 
 import (
+	"errors"
 	"net/http"
 
-	"vehicle-store-backend/internal/database"
 	"vehicle-store-backend/internal/models"
 
 	"github.com/gin-gonic/gin"
 )
 
-// GetBrands handles GET /api/brands
-func GetBrands(c *gin.Context) {
-	var brands []models.Brand
+// brandNameTakenError is returned when another brand already has the name
+func brandNameTakenError() *APIError {
+	apiErr := NewAPIError(http.StatusConflict, CodeConflict, "A brand with this name already exists")
+	apiErr.Details = []FieldError{{Field: "name", Message: "is already taken"}}
+	return apiErr
+}
 
-	if err := database.DB.Find(&brands).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch brands"})
+// GetBrands handles GET /api/brands
+func (s *Server) GetBrands(c *gin.Context) {
+	brands, err := s.Brands.List()
+	if err != nil {
+		respondError(c, internalError("Failed to fetch brands", err))
 		return
 	}
 
@@ -22,79 +28,169 @@ func GetBrands(c *gin.Context) {
 }
 
 // GetBrandByID handles GET /api/brands/:id
-func GetBrandByID(c *gin.Context) {
-	id := c.Param("id")
-	var brand models.Brand
+func (s *Server) GetBrandByID(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		respondError(c, notFoundError("Brand"))
+		return
+	}
 
-	if err := database.DB.Preload("Vehicles").First(&brand, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Brand not found"})
+	brand, err := s.Brands.GetByID(id)
+	if err != nil {
+		respondError(c, notFoundError("Brand"))
 		return
 	}
 
+	setETag(c, brand.Version)
 	c.JSON(http.StatusOK, brand)
 }
 
 // CreateBrand handles POST /api/admin/brands
-func CreateBrand(c *gin.Context) {
+func (s *Server) CreateBrand(c *gin.Context) {
 	var brand models.Brand
 
-	if err := c.ShouldBindJSON(&brand); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &brand) {
 		return
 	}
 
-	if err := database.DB.Create(&brand).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create brand"})
+	err := s.Brands.Create(&brand)
+	switch {
+	case errors.Is(err, ErrDuplicate):
+		respondError(c, brandNameTakenError())
+		return
+	case err != nil:
+		respondError(c, internalError("Failed to create brand", err))
 		return
 	}
 
+	setETag(c, brand.Version)
 	c.JSON(http.StatusCreated, brand)
 }
 
 // UpdateBrand handles PUT /api/admin/brands/:id
-func UpdateBrand(c *gin.Context) {
-	id := c.Param("id")
-	var brand models.Brand
+func (s *Server) UpdateBrand(c *gin.Context) {
+	s.editBrand(c, func(brand *models.Brand) error {
+		// The body replaces the editable fields only. It may echo the fields
+		// it was read with, but the path names the brand and If-Match its
+		// version.
+		current := *brand
+		if err := c.ShouldBindJSON(brand); err != nil {
+			return bindError(err)
+		}
+		brand.ID, brand.Version, brand.CreatedAt = current.ID, current.Version, current.CreatedAt
+		brand.Vehicles = current.Vehicles
+		return nil
+	})
+}
+
+// PatchBrand handles PATCH /api/admin/brands/:id, updating only the fields
+// present in a JSON Merge Patch
+func (s *Server) PatchBrand(c *gin.Context) {
+	patch, err := readMergePatch(c, brandReadOnlyFields...)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
 
-	if err := database.DB.First(&brand, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Brand not found"})
+	s.editBrand(c, func(brand *models.Brand) error {
+		return applyMergePatch(brand, patch)
+	})
+}
+
+// editBrand locks the brand named by the path, checks If-Match, applies edit
+// and saves the result in one transaction, then responds with the brand
+func (s *Server) editBrand(c *gin.Context, edit func(brand *models.Brand) error) {
+	id, err := idParam(c)
+	if err != nil {
+		respondError(c, notFoundError("Brand"))
 		return
 	}
 
-	if err := c.ShouldBindJSON(&brand); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	err = s.Tx.WithinTransaction(func(repos Repositories) error {
+		brand, err := repos.Brands.GetByIDForUpdate(id)
+		if err != nil {
+			return err
+		}
+
+		if err := checkIfMatch(c, brand.Version); err != nil {
+			return err
+		}
+		if err := edit(brand); err != nil {
+			return err
+		}
+		return repos.Brands.Update(brand)
+	})
+
+	var apiErr *APIError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		respondError(c, notFoundError("Brand"))
+		return
+	case errors.Is(err, ErrVersionConflict):
+		respondError(c, versionConflictError())
+		return
+	case errors.Is(err, ErrDuplicate):
+		respondError(c, brandNameTakenError())
+		return
+	case errors.As(err, &apiErr):
+		respondError(c, apiErr)
+		return
+	case err != nil:
+		respondError(c, internalError("Failed to update brand", err))
 		return
 	}
 
-	if err := database.DB.Save(&brand).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update brand"})
+	// Fetch the updated brand with its vehicles
+	brand, err := s.Brands.GetByID(id)
+	if err != nil {
+		respondError(c, internalError("Failed to update brand", err))
 		return
 	}
 
+	setETag(c, brand.Version)
 	c.JSON(http.StatusOK, brand)
 }
 
 // DeleteBrand handles DELETE /api/admin/brands/:id
-func DeleteBrand(c *gin.Context) {
-	id := c.Param("id")
-	var brand models.Brand
-
-	if err := database.DB.First(&brand, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Brand not found"})
+func (s *Server) DeleteBrand(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		respondError(c, notFoundError("Brand"))
 		return
 	}
 
-	// Check if brand has vehicles
-	var vehicleCount int64
-	database.DB.Model(&models.Vehicle{}).Where("brand_id = ?", id).Count(&vehicleCount)
-
-	if vehicleCount > 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete brand with existing vehicles"})
+	err = s.Tx.WithinTransaction(func(repos Repositories) error {
+		brand, err := repos.Brands.GetByIDForUpdate(id)
+		if err != nil {
+			return err
+		}
+
+		if err := checkIfMatch(c, brand.Version); err != nil {
+			return err
+		}
+
+		// Check if brand has vehicles
+		vehicleCount, err := repos.Brands.CountVehicles(id)
+		if err != nil {
+			return err
+		}
+		if vehicleCount > 0 {
+			return NewAPIError(http.StatusBadRequest, CodeBrandHasVehicles, "Cannot delete brand with existing vehicles")
+		}
+
+		return repos.Brands.Delete(id)
+	})
+
+	var apiErr *APIError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		respondError(c, notFoundError("Brand"))
 		return
-	}
-
-	if err := database.DB.Delete(&brand).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete brand"})
+	case errors.As(err, &apiErr):
+		respondError(c, apiErr)
+		return
+	case err != nil:
+		respondError(c, internalError("Failed to delete brand", err))
 		return
 	}
 
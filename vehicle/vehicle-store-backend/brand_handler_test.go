@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"vehicle-store-backend/internal/models"
+)
+
+func TestBrandNameConflict(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.adminToken(t, RoleAdmin)
+
+	toyota := models.Brand{Name: "Toyota"}
+	honda := models.Brand{Name: "Honda"}
+	for _, brand := range []*models.Brand{&toyota, &honda} {
+		if err := ts.repos.Brands.Create(brand); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := fmt.Sprintf("/api/admin/brands/%d", honda.ID)
+
+	// ifMatch returns the If-Match header for Honda's current version
+	ifMatch := func() string {
+		brand, err := ts.repos.Brands.GetByID(honda.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return etag(brand.Version)
+	}
+
+	tests := []struct {
+		name, method, path, body string
+		headers                  []string
+	}{
+		{"create", http.MethodPost, "/api/admin/brands", `{"name":"Toyota"}`, nil},
+		{"rename with PUT", http.MethodPut, path, `{"name":"Toyota"}`, []string{ifMatchHeader, ifMatch()}},
+		{"rename with PATCH", http.MethodPatch, path, `{"name":"Toyota"}`, []string{ifMatchHeader, ifMatch()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := append([]string{"Authorization", token}, tt.headers...)
+			w := ts.do(tt.method, tt.path, tt.body, headers...)
+			body := expectError(t, w, http.StatusConflict, CodeConflict)
+			if len(body.Error.Details) != 1 || body.Error.Details[0].Field != "name" {
+				t.Errorf("details = %+v, want field name", body.Error.Details)
+			}
+		})
+	}
+
+	if brands, _ := ts.repos.Brands.List(); len(brands) != 2 {
+		t.Errorf("%d brands stored, want 2", len(brands))
+	}
+	if brand, _ := ts.repos.Brands.GetByID(honda.ID); brand.Name != "Honda" || brand.Version != honda.Version {
+		t.Errorf("rejected rename changed the brand: %+v", brand)
+	}
+
+	t.Run("keeping its own name", func(t *testing.T) {
+		w := ts.do(http.MethodPut, path, `{"name":"Honda","logo_url":"https://example.com/honda.png"}`,
+			"Authorization", token, ifMatchHeader, ifMatch())
+		if w.Code != http.StatusOK {
+			t.Fatalf("got %d: %s", w.Code, w.Body.String())
+		}
+	})
+}
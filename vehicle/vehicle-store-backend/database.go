@@ -1,141 +1,113 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"vehicle-store-backend/internal/models"
 
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-var DB *gorm.DB
+// Connect opens the database connection selected by DB_DRIVER and DB_DSN
+func Connect() *gorm.DB {
+	driver := GetEnv("DB_DRIVER", "sqlite")
 
-// Connect initializes the database connection
-func Connect() {
-	var err error
-
-	// Use SQLite database file
-	dbPath := getEnv("DB_PATH", "vehicle_store.db")
+	dialector, err := openDialector(driver, GetEnv("DB_DSN", ""))
+	if err != nil {
+		log.Fatal("Failed to configure database:", err)
+	}
 
-	DB, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	db, err := gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
-	log.Println("Database connected successfully")
+	log.Printf("Database connected successfully (%s)", driver)
+	return db
+}
+
+// openDialector returns the GORM dialector for driver. SQLite falls back to
+// DB_PATH when no DSN is given; PostgreSQL and MySQL require one.
+// SQLite transactions are opened with BEGIN IMMEDIATE because SQLite has no
+// row locks; taking the write lock up front serializes check-then-write work.
+func openDialector(driver, dsn string) (gorm.Dialector, error) {
+	switch driver {
+	case "sqlite":
+		if dsn == "" {
+			dsn = GetEnv("DB_PATH", "vehicle_store.db")
+		}
+		if !strings.Contains(dsn, "_txlock=") {
+			if strings.Contains(dsn, "?") {
+				dsn += "&_txlock=immediate"
+			} else {
+				dsn = "file:" + strings.TrimPrefix(dsn, "file:") + "?_txlock=immediate"
+			}
+		}
+		return sqlite.Open(dsn), nil
+	case "postgres":
+		if dsn == "" {
+			return nil, errors.New("DB_DSN is required for postgres")
+		}
+		return postgres.Open(dsn), nil
+	case "mysql":
+		if dsn == "" {
+			return nil, errors.New("DB_DSN is required for mysql")
+		}
+		return mysql.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("unsupported DB_DRIVER %q", driver)
+	}
 }
 
-// Migrate runs database migrations
-func Migrate() {
-	err := DB.AutoMigrate(
-		&models.Brand{},
-		&models.Vehicle{},
-		&models.Booking{},
-	)
+// Migrate applies all pending schema migrations
+func Migrate(db *gorm.DB) {
+	applied, err := MigrateUp(db)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
-	log.Println("Database migrated successfully")
+	log.Printf("Database migrated successfully (%d applied)", applied)
 }
 
-// SeedData populates the database with initial data
-func SeedData() {
-	// Check if brands already exist
+// SeedAdmin creates the initial admin account from ADMIN_EMAIL and ADMIN_PASSWORD
+// when no admin users exist yet
+func SeedAdmin(db *gorm.DB) {
 	var count int64
-	DB.Model(&models.Brand{}).Count(&count)
+	db.Model(&models.AdminUser{}).Count(&count)
 	if count > 0 {
-		log.Println("Database already seeded")
 		return
 	}
 
-	// Create sample brands
-	brands := []models.Brand{
-		{Name: "Toyota", LogoURL: "/images/brands/toyota.png", Description: "Quality and reliability leader"},
-		{Name: "Honda", LogoURL: "/images/brands/honda.png", Description: "Innovation and efficiency"},
-		{Name: "Ford", LogoURL: "/images/brands/ford.png", Description: "Built tough, built to last"},
-		{Name: "BMW", LogoURL: "/images/brands/bmw.png", Description: "The ultimate driving machine"},
-		{Name: "Mercedes-Benz", LogoURL: "/images/brands/mercedes.png", Description: "The best or nothing"},
-		{Name: "Audi", LogoURL: "/images/brands/audi.png", Description: "Vorsprung durch Technik"},
-		{Name: "Tesla", LogoURL: "/images/brands/tesla.png", Description: "Accelerating the world's transition to sustainable energy"},
-		{Name: "Volkswagen", LogoURL: "/images/brands/volkswagen.png", Description: "Das Auto"},
-	}
-
-	for _, brand := range brands {
-		DB.Create(&brand)
+	email := GetEnv("ADMIN_EMAIL", "")
+	password := GetEnv("ADMIN_PASSWORD", "")
+	if email == "" || password == "" {
+		log.Println("No admin users exist; set ADMIN_EMAIL and ADMIN_PASSWORD to create one")
+		return
 	}
 
-	// Create sample vehicles
-	vehicles := []models.Vehicle{
-		{
-			BrandID: 1, Name: "Camry", Model: "LE", Year: 2024, Price: 28750.00, FuelType: "Petrol",
-			ThumbnailURL: "/images/vehicles/toyota-camry-2024.jpg", Description: "Reliable midsize sedan",
-			EngineSpecs: "2.5L 4-Cylinder", Transmission: "8-Speed Automatic", Mileage: 32,
-			ExteriorColor: "Midnight Black", InteriorColor: "Black Fabric", SafetyFeatures: "Toyota Safety Sense 2.0",
-			FinancingRate: 2.9, WarrantyYears: 3, DealerInfo: "Downtown Toyota - (555) 123-4567",
-		},
-		{
-			BrandID: 1, Name: "Prius", Model: "LE", Year: 2024, Price: 27450.00, FuelType: "Hybrid",
-			ThumbnailURL: "/images/vehicles/toyota-prius-2024.jpg", Description: "Most fuel-efficient hybrid",
-			EngineSpecs: "1.8L Hybrid", Transmission: "CVT", Mileage: 58,
-			ExteriorColor: "Blue Crush", InteriorColor: "Black SofTex", SafetyFeatures: "Toyota Safety Sense 2.0",
-			FinancingRate: 2.4, WarrantyYears: 3, DealerInfo: "Downtown Toyota - (555) 123-4567",
-		},
-		{
-			BrandID: 2, Name: "Civic", Model: "LX", Year: 2024, Price: 25200.00, FuelType: "Petrol",
-			ThumbnailURL: "/images/vehicles/honda-civic-2024.jpg", Description: "Compact car with style",
-			EngineSpecs: "2.0L 4-Cylinder", Transmission: "CVT", Mileage: 35,
-			ExteriorColor: "Sonic Gray", InteriorColor: "Black Cloth", SafetyFeatures: "Honda Sensing",
-			FinancingRate: 3.1, WarrantyYears: 3, DealerInfo: "Metro Honda - (555) 234-5678",
-		},
-		{
-			BrandID: 4, Name: "3 Series", Model: "330i", Year: 2024, Price: 45950.00, FuelType: "Petrol",
-			ThumbnailURL: "/images/vehicles/bmw-3series-2024.jpg", Description: "Ultimate sport sedan",
-			EngineSpecs: "2.0L TwinPower Turbo", Transmission: "8-Speed Automatic", Mileage: 28,
-			ExteriorColor: "Alpine White", InteriorColor: "Black Sensatec", SafetyFeatures: "BMW Active Guard",
-			FinancingRate: 3.9, WarrantyYears: 4, DealerInfo: "Luxury BMW - (555) 345-6789",
-		},
-		{
-			BrandID: 7, Name: "Model 3", Model: "Long Range", Year: 2024, Price: 47740.00, FuelType: "Electric",
-			ThumbnailURL: "/images/vehicles/tesla-model3-2024.jpg", Description: "Premium electric sedan",
-			EngineSpecs: "Dual Motor AWD", Transmission: "Single-Speed", Mileage: 358,
-			ExteriorColor: "Pearl White", InteriorColor: "Black Premium", SafetyFeatures: "Autopilot Included",
-			FinancingRate: 2.99, WarrantyYears: 4, DealerInfo: "Tesla Service Center - (555) 456-7890",
-		},
-		{
-			BrandID: 3, Name: "F-150", Model: "XLT", Year: 2024, Price: 42970.00, FuelType: "Petrol",
-			ThumbnailURL: "/images/vehicles/ford-f150-2024.jpg", Description: "America's best-selling truck",
-			EngineSpecs: "3.3L V6", Transmission: "10-Speed Automatic", Mileage: 24,
-			ExteriorColor: "Oxford White", InteriorColor: "Medium Earth Gray", SafetyFeatures: "Ford Co-Pilot360",
-			FinancingRate: 3.5, WarrantyYears: 3, DealerInfo: "Ford Country - (555) 567-8901",
-		},
-		{
-			BrandID: 5, Name: "C-Class", Model: "C300", Year: 2024, Price: 47850.00, FuelType: "Petrol",
-			ThumbnailURL: "/images/vehicles/mercedes-c300-2024.jpg", Description: "Luxury redefined",
-			EngineSpecs: "2.0L Turbo", Transmission: "9G-TRONIC", Mileage: 26,
-			ExteriorColor: "Obsidian Black", InteriorColor: "Black Artico", SafetyFeatures: "Mercedes-Benz Intelligent Drive",
-			FinancingRate: 4.2, WarrantyYears: 4, DealerInfo: "Mercedes-Benz Elite - (555) 678-9012",
-		},
-		{
-			BrandID: 6, Name: "A4", Model: "Premium", Year: 2024, Price: 43800.00, FuelType: "Petrol",
-			ThumbnailURL: "/images/vehicles/audi-a4-2024.jpg", Description: "Progressive luxury sedan",
-			EngineSpecs: "2.0L TFSI", Transmission: "7-Speed S tronic", Mileage: 29,
-			ExteriorColor: "Brilliant Black", InteriorColor: "Black Fine Nappa", SafetyFeatures: "Audi pre sense",
-			FinancingRate: 3.8, WarrantyYears: 4, DealerInfo: "Audi Prestige - (555) 789-0123",
-		},
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Fatal("Failed to hash admin password:", err)
 	}
 
-	for _, vehicle := range vehicles {
-		DB.Create(&vehicle)
+	admin := models.AdminUser{Email: email, Name: "Administrator", Role: "admin", PasswordHash: string(hash)}
+	if err := db.Create(&admin).Error; err != nil {
+		log.Fatal("Failed to create admin user:", err)
 	}
 
-	log.Println("Database seeded successfully")
+	log.Println("Admin user created:", email)
 }
 
-// getEnv gets environment variable with default value
-func getEnv(key, defaultValue string) string {
+// GetEnv gets environment variable with default value
+func GetEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
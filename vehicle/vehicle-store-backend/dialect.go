@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Dialect produces the SQL fragments that differ between database engines
+type Dialect interface {
+	// Name returns the GORM dialector name (sqlite, postgres or mysql)
+	Name() string
+	// DateExpr returns an expression formatting column as a YYYY-MM-DD string
+	DateExpr(column string) string
+	// ContainsExpr returns a case-insensitive match condition for column
+	// whose single placeholder takes the value built by ContainsPattern
+	ContainsExpr(column string) string
+}
+
+// DialectFor returns the Dialect matching the driver db was opened with
+func DialectFor(db *gorm.DB) Dialect {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return postgresDialect{}
+	case "mysql":
+		return mysqlDialect{}
+	default:
+		return sqliteDialect{}
+	}
+}
+
+// VehicleSearchTable is the SQLite FTS5 index of vehicle text, created by
+// migration 0010, or 0014 on databases migrated while FTS5 was optional
+const VehicleSearchTable = "vehicle_search"
+
+// HasVehicleSearch reports whether db has the vehicle full-text index
+func HasVehicleSearch(db *gorm.DB) bool {
+	return db.Dialector.Name() == "sqlite" && db.Migrator().HasTable(VehicleSearchTable)
+}
+
+// ContainsPattern returns the LIKE pattern matching term anywhere, with
+// wildcard characters in term escaped
+func ContainsPattern(term string) string {
+	escaper := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+	return "%" + escaper.Replace(strings.ToLower(term)) + "%"
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string { return "sqlite" }
+
+func (sqliteDialect) DateExpr(column string) string {
+	return "DATE(" + column + ")"
+}
+
+func (sqliteDialect) ContainsExpr(column string) string {
+	return "LOWER(" + column + ") LIKE ? ESCAPE '!'"
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string { return "postgres" }
+
+func (postgresDialect) DateExpr(column string) string {
+	return "TO_CHAR(" + column + ", 'YYYY-MM-DD')"
+}
+
+func (postgresDialect) ContainsExpr(column string) string {
+	return column + " ILIKE ? ESCAPE '!'"
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string { return "mysql" }
+
+func (mysqlDialect) DateExpr(column string) string {
+	return "DATE_FORMAT(" + column + ", '%Y-%m-%d')"
+}
+
+func (mysqlDialect) ContainsExpr(column string) string {
+	return "LOWER(" + column + ") LIKE ? ESCAPE '!'"
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// dryRunDB returns a DB for driver that builds SQL without connecting
+func dryRunDB(t *testing.T, driver string) *gorm.DB {
+	t.Helper()
+
+	var dialector gorm.Dialector
+	switch driver {
+	case "sqlite":
+		dialector = sqlite.Open("file::memory:")
+	case "postgres":
+		dialector = postgres.Open("host=localhost dbname=vehicles")
+	case "mysql":
+		dialector = mysql.New(mysql.Config{DSN: "user@tcp(localhost)/vehicles", SkipInitializeWithVersion: true})
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("open %s: %v", driver, err)
+	}
+	return db
+}
+
+func TestDialectFor(t *testing.T) {
+	for _, driver := range []string{"sqlite", "postgres", "mysql"} {
+		if got := DialectFor(dryRunDB(t, driver)).Name(); got != driver {
+			t.Errorf("DialectFor(%s).Name() = %q", driver, got)
+		}
+	}
+}
+
+func TestDialectSQL(t *testing.T) {
+	tests := []struct {
+		dialect  Dialect
+		date     string
+		contains string
+	}{
+		{
+			dialect:  sqliteDialect{},
+			date:     "DATE(bookings.created_at)",
+			contains: "LOWER(vehicles.name) LIKE ? ESCAPE '!'",
+		},
+		{
+			dialect:  postgresDialect{},
+			date:     "TO_CHAR(bookings.created_at, 'YYYY-MM-DD')",
+			contains: "vehicles.name ILIKE ? ESCAPE '!'",
+		},
+		{
+			dialect:  mysqlDialect{},
+			date:     "DATE_FORMAT(bookings.created_at, '%Y-%m-%d')",
+			contains: "LOWER(vehicles.name) LIKE ? ESCAPE '!'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dialect.Name(), func(t *testing.T) {
+			if got := tt.dialect.DateExpr("bookings.created_at"); got != tt.date {
+				t.Errorf("DateExpr = %q, want %q", got, tt.date)
+			}
+			if got := tt.dialect.ContainsExpr("vehicles.name"); got != tt.contains {
+				t.Errorf("ContainsExpr = %q, want %q", got, tt.contains)
+			}
+		})
+	}
+}
+
+func TestContainsPattern(t *testing.T) {
+	tests := []struct {
+		term, want string
+	}{
+		{"Camry", "%camry%"},
+		{"100%", "%100!%%"},
+		{"f_150", "%f!_150%"},
+		{"wow!", "%wow!!%"},
+	}
+
+	for _, tt := range tests {
+		if got := ContainsPattern(tt.term); got != tt.want {
+			t.Errorf("ContainsPattern(%q) = %q, want %q", tt.term, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"vehicle-store-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// priceRangeLimits split vehicle prices into the ranges counted by the price
+// facet: under $20k, $20k–30k, $30k–40k, $40k–60k, $60k–80k and $80k up
+var priceRangeLimits = []float64{20000, 30000, 40000, 60000, 80000}
+
+// facetFilters lists the facets counted by GetVehicleFacets with the filter
+// each one ignores, so a facet counts what picking each of its values would
+// return rather than only the value already picked
+var facetFilters = []struct {
+	facet VehicleFacet
+	clear func(filter *models.VehicleFilter)
+}{
+	{FacetBrand, func(f *models.VehicleFilter) { f.BrandID = 0 }},
+	{FacetFuelType, func(f *models.VehicleFilter) { f.FuelType = "" }},
+	{FacetTransmission, func(f *models.VehicleFilter) { f.Transmission = "" }},
+	{FacetYear, func(f *models.VehicleFilter) { f.Year = 0 }},
+	{FacetColor, func(f *models.VehicleFilter) { f.Color = "" }},
+}
+
+// GetVehicleFacets handles GET /api/vehicles/facets. It takes the filters of
+// GET /api/vehicles and returns, for brand, fuel type, transmission, year,
+// colour and price range, how many vehicles each value would match under
+// the other filters. Values matching nothing are left out, apart from the
+// fixed price ranges.
+func (s *Server) GetVehicleFacets(c *gin.Context) {
+	filter := vehicleFilterFromQuery(c)
+	if _, err := s.expandSearch(&filter); err != nil {
+		respondError(c, internalError("Failed to fetch facets", err))
+		return
+	}
+
+	facets := make(gin.H, len(facetFilters)+1)
+	for _, f := range facetFilters {
+		facetFilter := filter
+		f.clear(&facetFilter)
+
+		counts, err := s.Vehicles.CountBy(facetFilter, f.facet)
+		if err != nil {
+			respondError(c, internalError("Failed to fetch facets", err))
+			return
+		}
+		if f.facet == FacetYear {
+			sortYearsDescending(counts)
+		}
+		facets[string(f.facet)] = counts
+	}
+
+	priceFilter := filter
+	priceFilter.MinPrice, priceFilter.MaxPrice = 0, 0
+	counts, err := s.Vehicles.CountByPrice(priceFilter, priceRangeLimits)
+	if err != nil {
+		respondError(c, internalError("Failed to fetch facets", err))
+		return
+	}
+
+	ranges := make([]models.PriceRangeCount, len(counts))
+	for i, count := range counts {
+		ranges[i].Count = count
+		if i > 0 {
+			ranges[i].Min = priceRangeLimits[i-1]
+		}
+		if i < len(priceRangeLimits) {
+			ranges[i].Max = priceRangeLimits[i]
+		}
+	}
+	facets["price"] = ranges
+
+	c.JSON(http.StatusOK, gin.H{"facets": facets})
+}
+
+// sortYearsDescending orders year counts newest first, as years are picked
+// from a list rather than by popularity
+func sortYearsDescending(counts []models.FacetCount) {
+	sort.Slice(counts, func(i, j int) bool {
+		a, _ := strconv.Atoi(counts[i].Value)
+		b, _ := strconv.Atoi(counts[j].Value)
+		return a > b
+	})
+}
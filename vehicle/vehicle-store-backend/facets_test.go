@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"vehicle-store-backend/internal/models"
+)
+
+// vehicleFacets is the body of GET /api/vehicles/facets
+type vehicleFacets struct {
+	Brand        []models.FacetCount      `json:"brand"`
+	FuelType     []models.FacetCount      `json:"fuel_type"`
+	Transmission []models.FacetCount      `json:"transmission"`
+	Year         []models.FacetCount      `json:"year"`
+	Color        []models.FacetCount      `json:"color"`
+	Price        []models.PriceRangeCount `json:"price"`
+}
+
+// facetCounts writes counts as "value=count", with the label in place of
+// the value where there is one
+func facetCounts(counts []models.FacetCount) []string {
+	out := []string{}
+	for _, c := range counts {
+		name := c.Value
+		if c.Label != "" {
+			name = c.Label
+		}
+		out = append(out, fmt.Sprintf("%s=%d", name, c.Count))
+	}
+	return out
+}
+
+// priceCounts returns the count of each price range
+func priceCounts(ranges []models.PriceRangeCount) []int64 {
+	counts := make([]int64, len(ranges))
+	for i, r := range ranges {
+		counts[i] = r.Count
+	}
+	return counts
+}
+
+func TestVehicleFacets(t *testing.T) {
+	servers := map[string]func(t *testing.T) *testServer{
+		"memory": newTestServer,
+		"sqlite": newGormTestServer,
+	}
+
+	for name, newServer := range servers {
+		t.Run(name, func(t *testing.T) {
+			ts := newServer(t)
+			toyota := ts.addBrand(t, "Toyota")
+			honda := ts.addBrand(t, "Honda")
+			for _, v := range []models.Vehicle{
+				{BrandID: toyota.ID, Name: "Camry", FuelType: "Petrol", Transmission: "Automatic", Year: 2024, ExteriorColor: "White", Price: 20000},
+				{BrandID: toyota.ID, Name: "Prius", FuelType: "Hybrid", Transmission: "Automatic", Year: 2023, ExteriorColor: "White", Price: 29999.99},
+				{BrandID: toyota.ID, Name: "Corolla", FuelType: "Petrol", Transmission: "Manual", Year: 2024, ExteriorColor: "Red", Price: 19999.99},
+				{BrandID: honda.ID, Name: "Civic", FuelType: "Petrol", Transmission: "Manual", Year: 2022, ExteriorColor: "Red", Price: 40000},
+				{BrandID: honda.ID, Name: "Accord", FuelType: "Hybrid", Transmission: "Automatic", Year: 2024, Price: 80000},
+			} {
+				ts.addVehicle(t, v)
+			}
+			sold := ts.addVehicle(t, models.Vehicle{BrandID: honda.ID, Name: "Jazz", FuelType: "Petrol",
+				Transmission: "Manual", Year: 2024, ExteriorColor: "Red", Price: 25000})
+			sold.Availability = false
+			if err := ts.Vehicles.Update(sold); err != nil {
+				t.Fatal(err)
+			}
+
+			facets := func(query string) vehicleFacets {
+				t.Helper()
+				w := ts.do(http.MethodGet, "/api/vehicles/facets"+query, "")
+				if w.Code != http.StatusOK {
+					t.Fatalf("facets%s = %d: %s", query, w.Code, w.Body.String())
+				}
+				var body struct {
+					Facets vehicleFacets `json:"facets"`
+				}
+				decode(t, w, &body)
+				return body.Facets
+			}
+
+			t.Run("unfiltered", func(t *testing.T) {
+				got := facets("")
+				if want := []string{"Toyota=3", "Honda=2"}; !reflect.DeepEqual(facetCounts(got.Brand), want) {
+					t.Errorf("brand = %v, want %v", facetCounts(got.Brand), want)
+				}
+				// Vehicles without a colour are not counted under ""
+				if want := []string{"Red=2", "White=2"}; !reflect.DeepEqual(facetCounts(got.Color), want) {
+					t.Errorf("color = %v, want %v", facetCounts(got.Color), want)
+				}
+				if want := []string{"2024=3", "2023=1", "2022=1"}; !reflect.DeepEqual(facetCounts(got.Year), want) {
+					t.Errorf("year = %v, want %v", facetCounts(got.Year), want)
+				}
+			})
+
+			t.Run("price range limits", func(t *testing.T) {
+				got := facets("")
+				// A price on a limit belongs to the range above it: 20000 is
+				// in $20k–30k, 40000 in $40k–60k and 80000 in $80k up
+				if want := []int64{1, 2, 0, 1, 0, 1}; !reflect.DeepEqual(priceCounts(got.Price), want) {
+					t.Errorf("price counts = %v, want %v", priceCounts(got.Price), want)
+				}
+				first, last := got.Price[0], got.Price[len(got.Price)-1]
+				if first.Min != 0 || first.Max != 20000 || last.Min != 80000 || last.Max != 0 {
+					t.Errorf("outer ranges = %+v and %+v", first, last)
+				}
+			})
+
+			t.Run("each facet ignores its own filter", func(t *testing.T) {
+				got := facets("?fuel_type=Petrol&transmission=Manual")
+
+				// Fuel types of manual vehicles
+				if want := []string{"Petrol=2"}; !reflect.DeepEqual(facetCounts(got.FuelType), want) {
+					t.Errorf("fuel_type = %v, want %v", facetCounts(got.FuelType), want)
+				}
+				// Transmissions of petrol vehicles
+				if want := []string{"Manual=2", "Automatic=1"}; !reflect.DeepEqual(facetCounts(got.Transmission), want) {
+					t.Errorf("transmission = %v, want %v", facetCounts(got.Transmission), want)
+				}
+				// The rest apply both filters
+				if want := []string{"Toyota=1", "Honda=1"}; !sameNames(facetCounts(got.Brand), want) {
+					t.Errorf("brand = %v, want %v", facetCounts(got.Brand), want)
+				}
+				if want := []string{"2024=1", "2022=1"}; !reflect.DeepEqual(facetCounts(got.Year), want) {
+					t.Errorf("year = %v, want %v", facetCounts(got.Year), want)
+				}
+				if want := []int64{1, 0, 0, 1, 0, 0}; !reflect.DeepEqual(priceCounts(got.Price), want) {
+					t.Errorf("price counts = %v, want %v", priceCounts(got.Price), want)
+				}
+			})
+
+			t.Run("price ignores the price filter", func(t *testing.T) {
+				got := facets(fmt.Sprintf("?min_price=20000&max_price=30000&year=2024&brand_id=%d", toyota.ID))
+
+				// Toyotas from 2024 at any price
+				if want := []int64{1, 1, 0, 0, 0, 0}; !reflect.DeepEqual(priceCounts(got.Price), want) {
+					t.Errorf("price counts = %v, want %v", priceCounts(got.Price), want)
+				}
+				// Toyotas priced $20k–30k from any year
+				if want := []string{"2024=1", "2023=1"}; !reflect.DeepEqual(facetCounts(got.Year), want) {
+					t.Errorf("year = %v, want %v", facetCounts(got.Year), want)
+				}
+				// 2024 vehicles priced $20k–30k of any brand
+				if want := []string{"Toyota=1"}; !reflect.DeepEqual(facetCounts(got.Brand), want) {
+					t.Errorf("brand = %v, want %v", facetCounts(got.Brand), want)
+				}
+				if want := []string{"White=1"}; !reflect.DeepEqual(facetCounts(got.Color), want) {
+					t.Errorf("color = %v, want %v", facetCounts(got.Color), want)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"vehicle-store-backend/internal/database"
+	"vehicle-store-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayHeader marks a response replayed from an earlier request
+	idempotentReplayHeader = "Idempotent-Replayed"
+
+	defaultIdempotencyTTL = 24 * time.Hour
+	maxIdempotencyKeyLen  = 255
+)
+
+// errIdempotencyKeyBusy means the key's first request has not finished yet
+var errIdempotencyKeyBusy = errors.New("idempotency key is in use")
+
+// idempotencyTTLFromEnv reads the replay window from IDEMPOTENCY_TTL,
+// a Go duration such as "24h" or "90m"
+func idempotencyTTLFromEnv() (time.Duration, error) {
+	raw := database.GetEnv("IDEMPOTENCY_TTL", "")
+	if raw == "" {
+		return defaultIdempotencyTTL, nil
+	}
+
+	ttl, err := time.ParseDuration(raw)
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("invalid IDEMPOTENCY_TTL %q", raw)
+	}
+	return ttl, nil
+}
+
+// Idempotent makes the handlers after it safe to retry. A request carrying an
+// Idempotency-Key header runs once; retries with the same key and body get the
+// stored response back, and reusing the key with a different body is a 409.
+// Requests without the header are passed through untouched.
+//
+// scope names the endpoint so the same key can be used on different routes.
+// Keys are also per caller so clients cannot replay each other's responses:
+// per admin on admin routes, where it must run after AuthRequired, and per
+// client IP on public ones.
+func (s *Server) Idempotent(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLen {
+			respondError(c, badRequestError("Idempotency-Key is too long"))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			respondError(c, badRequestError("Failed to read request body"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		record := &models.IdempotencyRecord{
+			Scope:       idempotencyScope(c, scope),
+			Key:         key,
+			RequestHash: requestHash(body),
+			ExpiresAt:   time.Now().Add(s.IdempotencyTTL),
+		}
+
+		existing, err := s.reserveIdempotencyKey(record)
+		switch {
+		case errors.Is(err, errIdempotencyKeyBusy):
+			respondError(c, NewAPIError(http.StatusConflict, CodeIdempotencyBusy, "A request with this Idempotency-Key is still being processed"))
+			return
+		case err != nil:
+			respondError(c, internalError("Failed to check idempotency key", err))
+			return
+		case existing != nil:
+			replayIdempotent(c, existing, record.RequestHash)
+			return
+		}
+
+		// Free the key if the handler fails or panics so the client can retry.
+		// Failures are rendered by errorMiddleware after this returns, so
+		// only responses the handler wrote itself are stored.
+		stored := false
+		defer func() {
+			if stored {
+				return
+			}
+			if err := s.Replays.Delete(record.ID); err != nil {
+				log.Println("Failed to release idempotency key:", err)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		c.Next()
+
+		if len(c.Errors) > 0 || !recorder.Written() || recorder.Status() >= http.StatusInternalServerError {
+			return
+		}
+
+		record.StatusCode = recorder.Status()
+		record.ContentType = recorder.Header().Get("Content-Type")
+		record.ResponseBody = recorder.body.Bytes()
+		if err := s.Replays.Update(record); err != nil {
+			log.Println("Failed to store idempotent response:", err)
+			return
+		}
+		stored = true
+	}
+}
+
+// idempotencyScope combines the endpoint scope with the caller: the admin if
+// one is signed in, otherwise the client IP
+func idempotencyScope(c *gin.Context, scope string) string {
+	if admin, ok := currentAdmin(c); ok {
+		return fmt.Sprintf("%s:admin:%d", scope, admin.ID)
+	}
+	return scope + ":ip:" + c.ClientIP()
+}
+
+// reserveIdempotencyKey claims record's key for a new request. If the key is
+// already held by an unexpired record, that record is returned instead.
+func (s *Server) reserveIdempotencyKey(record *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	// Two attempts: the second follows clearing an expired record
+	for attempt := 0; attempt < 2; attempt++ {
+		err := s.Replays.Reserve(record)
+		if !errors.Is(err, ErrDuplicate) {
+			return nil, err
+		}
+
+		existing, err := s.Replays.Get(record.Scope, record.Key)
+		if errors.Is(err, ErrNotFound) {
+			// Released by a failed first request in between
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if existing.ExpiresAt.After(time.Now()) {
+			return existing, nil
+		}
+		if err := s.Replays.Delete(existing.ID); err != nil {
+			return nil, err
+		}
+	}
+	return nil, errIdempotencyKeyBusy
+}
+
+// replayIdempotent answers a retried request from the stored record
+func replayIdempotent(c *gin.Context, record *models.IdempotencyRecord, requestHash string) {
+	switch {
+	case record.RequestHash != requestHash:
+		respondError(c, NewAPIError(http.StatusConflict, CodeIdempotencyMismatch, "Idempotency-Key was already used with a different request"))
+	case record.StatusCode == 0:
+		respondError(c, NewAPIError(http.StatusConflict, CodeIdempotencyBusy, "A request with this Idempotency-Key is still being processed"))
+	default:
+		c.Header(idempotentReplayHeader, "true")
+		c.Data(record.StatusCode, record.ContentType, record.ResponseBody)
+		c.Abort()
+	}
+}
+
+// requestHash fingerprints a request body. JSON bodies are normalized first so
+// retries that only differ in key order or whitespace still match.
+func requestHash(body []byte) string {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var parsed interface{}
+	if err := decoder.Decode(&parsed); err == nil && !decoder.More() {
+		if normalized, err := json.Marshal(parsed); err == nil {
+			body = normalized
+		}
+	}
+
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// responseRecorder copies everything written to the response so it can be
+// stored for replay
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// StartIdempotencySweeper deletes expired idempotency records every interval
+// until ctx is done
+func (s *Server) StartIdempotencySweeper(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.Replays.DeleteExpired(time.Now()); err != nil {
+					log.Println("Failed to delete expired idempotency keys:", err)
+				}
+			}
+		}
+	}()
+}
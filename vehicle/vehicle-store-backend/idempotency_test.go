@@ -0,0 +1,124 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"vehicle-store-backend/internal/models"
+)
+
+// bookFrom posts body to /api/bookings from remoteAddr with an Idempotency-Key
+func (ts *testServer) bookFrom(remoteAddr, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/bookings", strings.NewReader(body))
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(idempotencyKeyHeader, key)
+
+	w := httptest.NewRecorder()
+	ts.router.ServeHTTP(w, req)
+	return w
+}
+
+// bookingCount returns the number of stored bookings
+func (ts *testServer) bookingCount(t *testing.T) int {
+	t.Helper()
+
+	bookings, err := ts.repos.Bookings.List(models.BookingFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(bookings)
+}
+
+func TestIdempotentReplay(t *testing.T) {
+	ts := newTestServer(t)
+	vehicle := ts.addVehicle(t, models.Vehicle{Name: "Camry", Year: 2024, Price: 28000})
+	body := bookingJSON(vehicle.ID, "jane@example.com")
+
+	first := ts.bookFrom("203.0.113.1:1000", "key-1", body)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first request = %d: %s", first.Code, first.Body.String())
+	}
+	if first.Header().Get(idempotentReplayHeader) != "" {
+		t.Error("first response is marked as replayed")
+	}
+
+	t.Run("same body is replayed", func(t *testing.T) {
+		w := ts.bookFrom("203.0.113.1:2000", "key-1", body)
+		if w.Code != http.StatusCreated || w.Body.String() != first.Body.String() {
+			t.Fatalf("replay = %d %s, want %d %s", w.Code, w.Body.String(), first.Code, first.Body.String())
+		}
+		if w.Header().Get(idempotentReplayHeader) != "true" {
+			t.Errorf("%s header = %q", idempotentReplayHeader, w.Header().Get(idempotentReplayHeader))
+		}
+		if n := ts.bookingCount(t); n != 1 {
+			t.Errorf("%d bookings stored, want 1", n)
+		}
+	})
+
+	t.Run("different body", func(t *testing.T) {
+		w := ts.bookFrom("203.0.113.1:3000", "key-1", bookingJSON(vehicle.ID, "john@example.com"))
+		expectError(t, w, http.StatusConflict, CodeIdempotencyMismatch)
+	})
+
+	t.Run("another client", func(t *testing.T) {
+		w := ts.bookFrom("198.51.100.7:1000", "key-1", body)
+		if w.Code != http.StatusCreated || w.Header().Get(idempotentReplayHeader) != "" {
+			t.Fatalf("another client got %d replayed=%q: %s", w.Code, w.Header().Get(idempotentReplayHeader), w.Body.String())
+		}
+		if n := ts.bookingCount(t); n != 2 {
+			t.Errorf("%d bookings stored, want 2", n)
+		}
+	})
+
+	t.Run("without a key", func(t *testing.T) {
+		before := ts.bookingCount(t)
+		for i := 0; i < 2; i++ {
+			if w := ts.do(http.MethodPost, "/api/bookings", body); w.Code != http.StatusCreated {
+				t.Fatalf("request %d = %d: %s", i, w.Code, w.Body.String())
+			}
+		}
+		if n := ts.bookingCount(t); n != before+2 {
+			t.Errorf("%d bookings stored, want %d", n, before+2)
+		}
+	})
+}
+
+func TestIdempotentFailureReleasesKey(t *testing.T) {
+	ts := newTestServer(t)
+	vehicle := ts.addVehicle(t, models.Vehicle{Name: "Camry", Year: 2024, Price: 28000})
+
+	// A request that fails validation does not use up the key
+	w := ts.bookFrom("203.0.113.1:1000", "key-1", `{"vehicle_id":1}`)
+	expectError(t, w, http.StatusUnprocessableEntity, CodeValidationFailed)
+
+	w = ts.bookFrom("203.0.113.1:1000", "key-1", bookingJSON(vehicle.ID, "jane@example.com"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("retry = %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestIdempotencyScope(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.adminToken(t, RoleAdmin)
+	vehicle := ts.addVehicle(t, models.Vehicle{Name: "Camry", Year: 2024, Price: 28000})
+	body := `{"name":"Lexus"}`
+
+	first := ts.do(http.MethodPost, "/api/admin/brands", body, "Authorization", token, idempotencyKeyHeader, "key-1")
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first request = %d: %s", first.Code, first.Body.String())
+	}
+
+	// The same key on another route is a separate request
+	w := ts.do(http.MethodPost, "/api/bookings", bookingJSON(vehicle.ID, "jane@example.com"), idempotencyKeyHeader, "key-1")
+	if w.Code != http.StatusCreated || w.Header().Get(idempotentReplayHeader) != "" {
+		t.Fatalf("booking with a brand's key = %d replayed=%q: %s", w.Code, w.Header().Get(idempotentReplayHeader), w.Body.String())
+	}
+
+	w = ts.do(http.MethodPost, "/api/admin/brands", body, "Authorization", token, idempotencyKeyHeader, "key-1")
+	if w.Code != http.StatusCreated || w.Header().Get(idempotentReplayHeader) != "true" {
+		t.Fatalf("replay = %d replayed=%q: %s", w.Code, w.Header().Get(idempotentReplayHeader), w.Body.String())
+	}
+}
@@ -1,29 +1,116 @@
+// Command vehicle-store-backend serves the vehicle store API.
+//
+// Build it with -tags sqlite_fts5 (make build): vehicle search on SQLite uses
+// an FTS5 index, which go-sqlite3 only compiles in with that tag, and
+// migrations fail on SQLite without it.
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
-	"net/http"
+	"os"
+	"strconv"
+	"time"
 
-	"github.com/gin-gonic/gin"
+	"vehicle-store-backend/internal/database"
+
+	"gorm.io/gorm"
 )
 
 func main() {
-	// TODO: Import and call your database.Connect(), database.Migrate(), and database.SeedData() here
-	// TODO: Register your API routes (handlers) here
+	db := database.Connect()
+
+	// "migrate" subcommand manages the schema without starting the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrateCommand(db, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	database.Migrate(db)
+	database.SeedData(db)
+	database.SeedAdmin(db)
+
+	server := NewServer(db)
 
-	r := gin.Default()
+	notifier, err := NewNotifierFromEnv()
+	if err != nil {
+		log.Fatal("Failed to configure notifications:", err)
+	}
+	queued := NewQueuedNotifier(notifier, notificationQueueSize)
+	server.Notifier = queued
 
-	// Example CORS middleware (allow all for dev)
-	r.Use(func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Next()
-	})
+	server.IdempotencyTTL, err = idempotencyTTLFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	// TODO: Register your actual API routes here
-	r.GET("/api/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "ok"})
-	})
+	server.RateLimiter, err = NewRateLimiterFromEnv()
+	if err != nil {
+		log.Fatal("Failed to configure rate limiting:", err)
+	}
+
+	queued.Start(context.Background())
+	server.StartReservationSweeper(context.Background(), time.Minute)
+	server.StartWebhookDispatcher(context.Background(), 5*time.Second)
+	server.StartIdempotencySweeper(context.Background(), time.Hour)
+
+	r := NewRouter(server)
 
 	log.Println("Server running on :8080")
-	r.Run(":8080")
+	if err := r.Run(":8080"); err != nil {
+		log.Fatal("Failed to start server:", err)
+	}
+}
+
+// runMigrateCommand handles "migrate up", "migrate down [N]" and "migrate status"
+func runMigrateCommand(db *gorm.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up | down [N] | status")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := database.MigrateUp(db)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d migration(s)\n", applied)
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid step count %q", args[1])
+			}
+			steps = n
+		}
+
+		reverted, err := database.Rollback(db, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rolled back %d migration(s)\n", reverted)
+
+	case "status":
+		states, err := database.MigrationStatus(db)
+		if err != nil {
+			return err
+		}
+		for _, state := range states {
+			if state.Applied {
+				fmt.Printf("[x] %s_%s  (applied %s)\n", state.Version, state.Name, state.AppliedAt.Format("2006-01-02 15:04:05"))
+			} else {
+				fmt.Printf("[ ] %s_%s\n", state.Version, state.Name)
+			}
+		}
+
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+
+	return nil
 }
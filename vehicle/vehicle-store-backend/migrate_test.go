@@ -0,0 +1,141 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"vehicle-store-backend/internal/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openSQLite returns an empty SQLite database in a temporary directory,
+// skipping the test if SQLite was built without FTS5
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db") + "?_txlock=immediate"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := requireFTS5(db); err != nil {
+		t.Skip("SQLite lacks FTS5; run with -tags sqlite_fts5 (make test)")
+	}
+	return db
+}
+
+// appliedVersions returns the versions MigrationStatus reports as applied
+func appliedVersions(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+
+	states, err := MigrationStatus(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(states) != len(migrations) {
+		t.Fatalf("status lists %d migrations, want %d", len(states), len(migrations))
+	}
+
+	var applied []string
+	for _, state := range states {
+		if state.Applied != (state.AppliedAt != nil) {
+			t.Errorf("migration %s applied = %v but applied at %v", state.Version, state.Applied, state.AppliedAt)
+		}
+		if state.Applied {
+			applied = append(applied, state.Version)
+		}
+	}
+	return applied
+}
+
+func TestMigrationsAreOrdered(t *testing.T) {
+	seen := make(map[string]bool)
+	for i, m := range migrations {
+		if seen[m.Version] {
+			t.Errorf("version %s is used twice", m.Version)
+		}
+		seen[m.Version] = true
+		if i > 0 && m.Version <= migrations[i-1].Version {
+			t.Errorf("version %s follows %s", m.Version, migrations[i-1].Version)
+		}
+		if m.Name == "" || m.Up == nil || m.Down == nil {
+			t.Errorf("migration %s needs a name, Up and Down", m.Version)
+		}
+	}
+}
+
+func TestMigrateUpDownStatus(t *testing.T) {
+	db := openSQLite(t)
+	last := migrations[len(migrations)-1].Version
+
+	if applied := appliedVersions(t, db); len(applied) != 0 {
+		t.Fatalf("fresh database has %v applied", applied)
+	}
+
+	n, err := MigrateUp(db)
+	if err != nil || n != len(migrations) {
+		t.Fatalf("MigrateUp = %d, %v; want %d", n, err, len(migrations))
+	}
+	if applied := appliedVersions(t, db); len(applied) != len(migrations) {
+		t.Fatalf("%d migrations applied, want %d", len(applied), len(migrations))
+	}
+	if n, err := MigrateUp(db); err != nil || n != 0 {
+		t.Fatalf("second MigrateUp = %d, %v; want nothing to do", n, err)
+	}
+
+	// The schema fits the current models
+	brand := models.Brand{Name: "Toyota"}
+	if err := db.Create(&brand).Error; err != nil {
+		t.Fatal(err)
+	}
+	vehicle := models.Vehicle{BrandID: brand.ID, Name: "Camry", Year: 2024, Price: 28000, FuelType: "Petrol"}
+	if err := db.Create(&vehicle).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("rollback one step", func(t *testing.T) {
+		if n, err := Rollback(db, 1); err != nil || n != 1 {
+			t.Fatalf("Rollback(1) = %d, %v", n, err)
+		}
+		applied := appliedVersions(t, db)
+		if len(applied) != len(migrations)-1 || applied[len(applied)-1] >= last {
+			t.Errorf("after rolling back %s, applied = %v", last, applied)
+		}
+
+		if n, err := MigrateUp(db); err != nil || n != 1 {
+			t.Fatalf("reapplying = %d, %v; want 1", n, err)
+		}
+	})
+
+	t.Run("rollback everything", func(t *testing.T) {
+		// More steps than there are migrations stops at the first
+		n, err := Rollback(db, len(migrations)+5)
+		if err != nil || n != len(migrations) {
+			t.Fatalf("Rollback(all) = %d, %v; want %d", n, err, len(migrations))
+		}
+		if applied := appliedVersions(t, db); len(applied) != 0 {
+			t.Errorf("applied after full rollback = %v", applied)
+		}
+		for _, table := range []string{"brands", "vehicles", "bookings", "admin_users", "vehicle_search"} {
+			if db.Migrator().HasTable(table) {
+				t.Errorf("table %s survived the rollback", table)
+			}
+		}
+
+		if n, err := MigrateUp(db); err != nil || n != len(migrations) {
+			t.Fatalf("reapplying = %d, %v; want %d", n, err, len(migrations))
+		}
+		if err := db.Create(&models.Brand{Name: "Honda"}).Error; err != nil {
+			t.Errorf("schema after reapplying: %v", err)
+		}
+	})
+}
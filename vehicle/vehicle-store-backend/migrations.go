@@ -0,0 +1,605 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrations is the ordered schema history. Each step uses its own frozen
+// copies of the models so later model changes do not rewrite old steps.
+var migrations = []Migration{
+	{
+		Version: "0001",
+		Name:    "create_inventory",
+		Up: func(tx *gorm.DB) error {
+			// Databases created before migrations existed already have these
+			// tables from AutoMigrate, so only create what is missing
+			return createTablesIfMissing(tx, &brand0001{}, &vehicle0001{}, &booking0001{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&booking0001{}, &vehicle0001{}, &brand0001{})
+		},
+	},
+	{
+		Version: "0002",
+		Name:    "create_admin_users",
+		Up: func(tx *gorm.DB) error {
+			return createTablesIfMissing(tx, &adminUser0002{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&adminUser0002{})
+		},
+	},
+	{
+		Version: "0003",
+		Name:    "create_booking_status_history",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&bookingStatusHistory0003{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&bookingStatusHistory0003{})
+		},
+	},
+	{
+		Version: "0004",
+		Name:    "add_test_drive_scheduling",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if err := m.AddColumn(&booking0004{}, "RequestedStart"); err != nil {
+				return err
+			}
+			if err := m.AddColumn(&booking0004{}, "RequestedEnd"); err != nil {
+				return err
+			}
+			if err := m.CreateIndex(&booking0004{}, "RequestedStart"); err != nil {
+				return err
+			}
+			return m.CreateTable(&dealerHours0004{})
+		},
+		Down: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if err := m.DropTable(&dealerHours0004{}); err != nil {
+				return err
+			}
+			if err := dropIndexIfExists(tx, &booking0004{}, "RequestedStart"); err != nil {
+				return err
+			}
+			if err := m.DropColumn(&booking0004{}, "RequestedEnd"); err != nil {
+				return err
+			}
+			return m.DropColumn(&booking0004{}, "RequestedStart")
+		},
+	},
+	{
+		Version: "0005",
+		Name:    "create_reservations",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&reservation0005{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&reservation0005{})
+		},
+	},
+	{
+		Version: "0006",
+		Name:    "create_webhooks",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&webhookSubscription0006{}, &outboxEvent0006{}, &webhookDelivery0006{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&webhookDelivery0006{}, &outboxEvent0006{}, &webhookSubscription0006{})
+		},
+	},
+	{
+		Version: "0007",
+		Name:    "create_idempotency_records",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&idempotencyRecord0007{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&idempotencyRecord0007{})
+		},
+	},
+	{
+		Version: "0008",
+		Name:    "add_booking_spam_flags",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if err := m.AddColumn(&booking0008{}, "SuspectedSpam"); err != nil {
+				return err
+			}
+			if err := m.AddColumn(&booking0008{}, "SpamReasons"); err != nil {
+				return err
+			}
+			return m.CreateIndex(&booking0008{}, "SuspectedSpam")
+		},
+		Down: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if err := dropIndexIfExists(tx, &booking0008{}, "SuspectedSpam"); err != nil {
+				return err
+			}
+			if err := m.DropColumn(&booking0008{}, "SpamReasons"); err != nil {
+				return err
+			}
+			return m.DropColumn(&booking0008{}, "SuspectedSpam")
+		},
+	},
+	{
+		Version: "0009",
+		Name:    "add_record_versions",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, table := range []interface{}{&brand0009{}, &vehicle0009{}, &booking0009{}} {
+				if err := m.AddColumn(table, "Version"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, table := range []interface{}{&booking0009{}, &vehicle0009{}, &brand0009{}} {
+				if err := m.DropColumn(table, "Version"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: "0010",
+		Name:    "create_vehicle_search_index",
+		Up: func(tx *gorm.DB) error {
+			// Other engines keep the LIKE search
+			if tx.Dialector.Name() != "sqlite" {
+				return nil
+			}
+			if err := requireFTS5(tx); err != nil {
+				return err
+			}
+			return execAll(tx, vehicleSearch0010)
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, stmt := range []string{
+				"DROP TRIGGER IF EXISTS vehicle_search_brand_update",
+				"DROP TRIGGER IF EXISTS vehicle_search_delete",
+				"DROP TRIGGER IF EXISTS vehicle_search_update",
+				"DROP TRIGGER IF EXISTS vehicle_search_insert",
+				"DROP TABLE IF EXISTS vehicle_search",
+			} {
+				if err := tx.Exec(stmt).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: "0011",
+		Name:    "add_search_synonyms",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().CreateTable(&searchSynonym0011{}); err != nil {
+				return err
+			}
+			if err := tx.Create(defaultSynonyms0011()).Error; err != nil {
+				return err
+			}
+
+			// Index fuel type and transmission so synonyms such as "ev" can
+			// reach them. The index only exists where 0010 could create it.
+			if !tx.Migrator().HasTable("vehicle_search") {
+				return nil
+			}
+			return execAll(tx, dropVehicleSearch0011, vehicleSearch0011)
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Migrator().HasTable("vehicle_search") {
+				if err := execAll(tx, dropVehicleSearch0011, vehicleSearch0010); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().DropTable(&searchSynonym0011{})
+		},
+	},
+	{
+		Version: "0012",
+		Name:    "create_search_queries",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&searchQuery0012{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&searchQuery0012{})
+		},
+	},
+	{
+		Version: "0013",
+		Name:    "add_reservation_previous_availability",
+		Up: func(tx *gorm.DB) error {
+			// Holds could only be placed on available vehicles, so existing
+			// holds restore availability as release always did
+			return tx.Migrator().AddColumn(&reservation0013{}, "PreviousAvailability")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&reservation0013{}, "PreviousAvailability")
+		},
+	},
+	{
+		Version: "0014",
+		Name:    "create_missing_vehicle_search_index",
+		Up: func(tx *gorm.DB) error {
+			// 0010 used to skip the index when SQLite lacked FTS5, leaving
+			// search unranked; build it now that FTS5 is required
+			if tx.Dialector.Name() != "sqlite" || tx.Migrator().HasTable("vehicle_search") {
+				return nil
+			}
+			if err := requireFTS5(tx); err != nil {
+				return err
+			}
+			return execAll(tx, vehicleSearch0011)
+		},
+		Down: func(tx *gorm.DB) error {
+			// The index belongs to 0010 and 0011, which drop it
+			return nil
+		},
+	},
+}
+
+// requireFTS5 fails unless SQLite was compiled with FTS5, which vehicle
+// search is built on. go-sqlite3 only includes it with -tags sqlite_fts5.
+func requireFTS5(tx *gorm.DB) error {
+	var fts5 bool
+	if err := tx.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5).Error; err != nil {
+		return err
+	}
+	if !fts5 {
+		return errors.New("SQLite was built without FTS5, which vehicle search needs; " +
+			"build with -tags sqlite_fts5 (make build)")
+	}
+	return nil
+}
+
+// execAll runs each batch of statements in order
+func execAll(tx *gorm.DB, batches ...[]string) error {
+	for _, batch := range batches {
+		for _, stmt := range batch {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// dropIndexIfExists drops an index unless it is already gone. SQLite drops a
+// column by rebuilding its table, which loses the table's other indexes, so
+// rolling back a later migration can take an earlier one's index with it
+func dropIndexIfExists(tx *gorm.DB, table interface{}, name string) error {
+	if !tx.Migrator().HasIndex(table, name) {
+		return nil
+	}
+	return tx.Migrator().DropIndex(table, name)
+}
+
+// createTablesIfMissing creates each table that does not exist yet
+func createTablesIfMissing(tx *gorm.DB, tables ...interface{}) error {
+	for _, table := range tables {
+		if tx.Migrator().HasTable(table) {
+			continue
+		}
+		if err := tx.Migrator().CreateTable(table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type brand0001 struct {
+	ID          uint   `gorm:"primaryKey"`
+	Name        string `gorm:"not null;unique"`
+	LogoURL     string
+	Description string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (brand0001) TableName() string { return "brands" }
+
+type vehicle0001 struct {
+	ID             uint   `gorm:"primaryKey"`
+	BrandID        uint   `gorm:"not null"`
+	Name           string `gorm:"not null"`
+	Model          string
+	Year           int     `gorm:"not null"`
+	Price          float64 `gorm:"not null"`
+	FuelType       string  `gorm:"not null"`
+	ThumbnailURL   string
+	Description    string
+	EngineSpecs    string
+	Transmission   string
+	Mileage        int
+	ExteriorColor  string
+	InteriorColor  string
+	SafetyFeatures string
+	FinancingRate  float64
+	WarrantyYears  int
+	DealerInfo     string
+	Availability   bool `gorm:"default:true"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (vehicle0001) TableName() string { return "vehicles" }
+
+type booking0001 struct {
+	ID            uint   `gorm:"primaryKey"`
+	VehicleID     uint   `gorm:"not null"`
+	CustomerName  string `gorm:"not null"`
+	CustomerEmail string `gorm:"not null"`
+	CustomerPhone string
+	Message       string
+	Status        string `gorm:"default:'pending'"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (booking0001) TableName() string { return "bookings" }
+
+type adminUser0002 struct {
+	ID           uint   `gorm:"primaryKey"`
+	Email        string `gorm:"not null;unique"`
+	Name         string
+	PasswordHash string `gorm:"not null"`
+	Role         string `gorm:"not null;default:'analyst'"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (adminUser0002) TableName() string { return "admin_users" }
+
+type bookingStatusHistory0003 struct {
+	ID         uint `gorm:"primaryKey"`
+	BookingID  uint `gorm:"not null;index"`
+	FromStatus string
+	ToStatus   string `gorm:"not null"`
+	Actor      string
+	Note       string
+	CreatedAt  time.Time
+}
+
+func (bookingStatusHistory0003) TableName() string { return "booking_status_history" }
+
+type booking0004 struct {
+	RequestedStart *time.Time `gorm:"index"`
+	RequestedEnd   *time.Time
+}
+
+func (booking0004) TableName() string { return "bookings" }
+
+type dealerHours0004 struct {
+	ID       uint   `gorm:"primaryKey"`
+	Dealer   string `gorm:"not null;uniqueIndex:idx_dealer_hours_day"`
+	Weekday  int    `gorm:"not null;uniqueIndex:idx_dealer_hours_day"`
+	OpensAt  string `gorm:"not null"`
+	ClosesAt string `gorm:"not null"`
+}
+
+func (dealerHours0004) TableName() string { return "dealer_hours" }
+
+type reservation0005 struct {
+	ID            uint `gorm:"primaryKey"`
+	VehicleID     uint `gorm:"not null;index"`
+	CustomerName  string
+	CustomerEmail string
+	Note          string
+	HeldBy        string
+	ExpiresAt     time.Time `gorm:"not null;index"`
+	ReleasedAt    *time.Time
+	ReleaseReason string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (reservation0005) TableName() string { return "reservations" }
+
+type webhookSubscription0006 struct {
+	ID        uint   `gorm:"primaryKey"`
+	URL       string `gorm:"not null"`
+	Secret    string `gorm:"not null"`
+	Events    string
+	Active    bool `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (webhookSubscription0006) TableName() string { return "webhook_subscriptions" }
+
+type outboxEvent0006 struct {
+	ID          uint   `gorm:"primaryKey"`
+	EventType   string `gorm:"not null"`
+	Payload     string `gorm:"not null"`
+	CreatedAt   time.Time
+	ProcessedAt *time.Time `gorm:"index"`
+}
+
+func (outboxEvent0006) TableName() string { return "outbox_events" }
+
+type webhookDelivery0006 struct {
+	ID             uint   `gorm:"primaryKey"`
+	SubscriptionID uint   `gorm:"not null;index"`
+	EventID        uint   `gorm:"not null"`
+	EventType      string `gorm:"not null"`
+	Payload        string `gorm:"not null"`
+	OccurredAt     time.Time
+	Status         string `gorm:"not null;index"`
+	Attempts       int
+	ResponseCode   int
+	LastError      string
+	NextAttemptAt  time.Time `gorm:"index"`
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (webhookDelivery0006) TableName() string { return "webhook_deliveries" }
+
+type idempotencyRecord0007 struct {
+	ID           uint   `gorm:"primaryKey"`
+	Scope        string `gorm:"not null;uniqueIndex:idx_idempotency_scope_key"`
+	Key          string `gorm:"column:idempotency_key;not null;uniqueIndex:idx_idempotency_scope_key"`
+	RequestHash  string `gorm:"not null"`
+	StatusCode   int
+	ContentType  string
+	ResponseBody []byte
+	ExpiresAt    time.Time `gorm:"not null;index"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (idempotencyRecord0007) TableName() string { return "idempotency_records" }
+
+type booking0008 struct {
+	SuspectedSpam bool `gorm:"not null;default:false;index"`
+	SpamReasons   string
+}
+
+func (booking0008) TableName() string { return "bookings" }
+
+type brand0009 struct {
+	Version uint `gorm:"not null;default:1"`
+}
+
+func (brand0009) TableName() string { return "brands" }
+
+type vehicle0009 struct {
+	Version uint `gorm:"not null;default:1"`
+}
+
+func (vehicle0009) TableName() string { return "vehicles" }
+
+type booking0009 struct {
+	Version uint `gorm:"not null;default:1"`
+}
+
+func (booking0009) TableName() string { return "bookings" }
+
+// vehicleSearch0010 creates the FTS5 index behind vehicle search, fills it
+// and adds the triggers that keep it in step with vehicles and brand names.
+// The rowid of each entry is the vehicle ID.
+var vehicleSearch0010 = []string{
+	`CREATE VIRTUAL TABLE vehicle_search USING fts5(
+		name, model, brand, description, engine_specs, safety_features,
+		tokenize = 'unicode61 remove_diacritics 2'
+	)`,
+	`INSERT INTO vehicle_search (rowid, name, model, brand, description, engine_specs, safety_features)
+		SELECT vehicles.id, vehicles.name, vehicles.model, brands.name,
+			vehicles.description, vehicles.engine_specs, vehicles.safety_features
+		FROM vehicles LEFT JOIN brands ON brands.id = vehicles.brand_id`,
+	`CREATE TRIGGER vehicle_search_insert AFTER INSERT ON vehicles BEGIN
+		INSERT INTO vehicle_search (rowid, name, model, brand, description, engine_specs, safety_features)
+		VALUES (new.id, new.name, new.model, (SELECT name FROM brands WHERE id = new.brand_id),
+			new.description, new.engine_specs, new.safety_features);
+	END`,
+	`CREATE TRIGGER vehicle_search_update AFTER UPDATE ON vehicles BEGIN
+		DELETE FROM vehicle_search WHERE rowid = old.id;
+		INSERT INTO vehicle_search (rowid, name, model, brand, description, engine_specs, safety_features)
+		VALUES (new.id, new.name, new.model, (SELECT name FROM brands WHERE id = new.brand_id),
+			new.description, new.engine_specs, new.safety_features);
+	END`,
+	`CREATE TRIGGER vehicle_search_delete AFTER DELETE ON vehicles BEGIN
+		DELETE FROM vehicle_search WHERE rowid = old.id;
+	END`,
+	`CREATE TRIGGER vehicle_search_brand_update AFTER UPDATE OF name ON brands BEGIN
+		UPDATE vehicle_search SET brand = new.name
+		WHERE rowid IN (SELECT id FROM vehicles WHERE brand_id = new.id);
+	END`,
+}
+
+type searchSynonym0011 struct {
+	ID        uint   `gorm:"primaryKey"`
+	Term      string `gorm:"not null;uniqueIndex:idx_search_synonyms_term_synonym"`
+	Synonym   string `gorm:"not null;uniqueIndex:idx_search_synonyms_term_synonym"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (searchSynonym0011) TableName() string { return "search_synonyms" }
+
+// defaultSynonyms0011 are the synonyms new databases start with
+func defaultSynonyms0011() []searchSynonym0011 {
+	pairs := [][2]string{
+		{"ev", "Electric"},
+		{"merc", "Mercedes-Benz"},
+		{"benz", "Mercedes-Benz"},
+		{"chevy", "Chevrolet"},
+		{"vw", "Volkswagen"},
+		{"beemer", "BMW"},
+		{"auto", "Automatic"},
+		{"stick", "Manual"},
+		{"gas", "Petrol"},
+		{"gasoline", "Petrol"},
+	}
+
+	synonyms := make([]searchSynonym0011, len(pairs))
+	for i, pair := range pairs {
+		synonyms[i] = searchSynonym0011{Term: pair[0], Synonym: pair[1]}
+	}
+	return synonyms
+}
+
+// dropVehicleSearch0011 removes the vehicle search index and its triggers
+var dropVehicleSearch0011 = []string{
+	"DROP TRIGGER IF EXISTS vehicle_search_brand_update",
+	"DROP TRIGGER IF EXISTS vehicle_search_delete",
+	"DROP TRIGGER IF EXISTS vehicle_search_update",
+	"DROP TRIGGER IF EXISTS vehicle_search_insert",
+	"DROP TABLE IF EXISTS vehicle_search",
+}
+
+// vehicleSearch0011 recreates the vehicle search index of 0010 with the fuel
+// type and transmission added
+var vehicleSearch0011 = []string{
+	`CREATE VIRTUAL TABLE vehicle_search USING fts5(
+		name, model, brand, description, engine_specs, safety_features, fuel_type, transmission,
+		tokenize = 'unicode61 remove_diacritics 2'
+	)`,
+	`INSERT INTO vehicle_search (rowid, name, model, brand, description, engine_specs, safety_features, fuel_type, transmission)
+		SELECT vehicles.id, vehicles.name, vehicles.model, brands.name, vehicles.description,
+			vehicles.engine_specs, vehicles.safety_features, vehicles.fuel_type, vehicles.transmission
+		FROM vehicles LEFT JOIN brands ON brands.id = vehicles.brand_id`,
+	`CREATE TRIGGER vehicle_search_insert AFTER INSERT ON vehicles BEGIN
+		INSERT INTO vehicle_search (rowid, name, model, brand, description, engine_specs, safety_features, fuel_type, transmission)
+		VALUES (new.id, new.name, new.model, (SELECT name FROM brands WHERE id = new.brand_id), new.description,
+			new.engine_specs, new.safety_features, new.fuel_type, new.transmission);
+	END`,
+	`CREATE TRIGGER vehicle_search_update AFTER UPDATE ON vehicles BEGIN
+		DELETE FROM vehicle_search WHERE rowid = old.id;
+		INSERT INTO vehicle_search (rowid, name, model, brand, description, engine_specs, safety_features, fuel_type, transmission)
+		VALUES (new.id, new.name, new.model, (SELECT name FROM brands WHERE id = new.brand_id), new.description,
+			new.engine_specs, new.safety_features, new.fuel_type, new.transmission);
+	END`,
+	`CREATE TRIGGER vehicle_search_delete AFTER DELETE ON vehicles BEGIN
+		DELETE FROM vehicle_search WHERE rowid = old.id;
+	END`,
+	`CREATE TRIGGER vehicle_search_brand_update AFTER UPDATE OF name ON brands BEGIN
+		UPDATE vehicle_search SET brand = new.name
+		WHERE rowid IN (SELECT id FROM vehicles WHERE brand_id = new.id);
+	END`,
+}
+
+type searchQuery0012 struct {
+	ID             uint   `gorm:"primaryKey"`
+	Query          string `gorm:"not null;size:200;uniqueIndex"`
+	Searches       int64  `gorm:"not null;default:0;index"`
+	LastSearchedAt time.Time
+	CreatedAt      time.Time
+}
+
+func (searchQuery0012) TableName() string { return "search_queries" }
+
+type reservation0013 struct {
+	PreviousAvailability bool `gorm:"not null;default:true"`
+}
+
+func (reservation0013) TableName() string { return "reservations" }
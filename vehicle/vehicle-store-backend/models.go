@@ -7,9 +7,10 @@ import (
 // Brand represents a vehicle brand
 type Brand struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
-	Name        string    `json:"name" gorm:"not null;unique"`
-	LogoURL     string    `json:"logo_url"`
-	Description string    `json:"description"`
+	Name        string    `json:"name" binding:"required,max=100" gorm:"not null;unique"`
+	LogoURL     string    `json:"logo_url" binding:"max=500"`
+	Description string    `json:"description" binding:"max=2000"`
+	Version     uint      `json:"version" gorm:"not null;default:1"` // bumped on every update; sent as the ETag
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	Vehicles    []Vehicle `json:"vehicles,omitempty" gorm:"foreignKey:BrandID"`
@@ -18,54 +19,232 @@ type Brand struct {
 // Vehicle represents a vehicle in the inventory
 type Vehicle struct {
 	ID             uint      `json:"id" gorm:"primaryKey"`
-	BrandID        uint      `json:"brand_id" gorm:"not null"`
-	Brand          Brand     `json:"brand" gorm:"foreignKey:BrandID"`
-	Name           string    `json:"name" gorm:"not null"`
-	Model          string    `json:"model"`
-	Year           int       `json:"year" gorm:"not null"`
-	Price          float64   `json:"price" gorm:"not null"`
-	FuelType       string    `json:"fuel_type" gorm:"not null"` // Petrol, Diesel, Electric, Hybrid
-	ThumbnailURL   string    `json:"thumbnail_url"`
-	Description    string    `json:"description"`
-	EngineSpecs    string    `json:"engine_specs"`
-	Transmission   string    `json:"transmission"`
-	Mileage        int       `json:"mileage"`
-	ExteriorColor  string    `json:"exterior_color"`
-	InteriorColor  string    `json:"interior_color"`
-	SafetyFeatures string    `json:"safety_features"`
-	FinancingRate  float64   `json:"financing_rate"`
-	WarrantyYears  int       `json:"warranty_years"`
-	DealerInfo     string    `json:"dealer_info"`
+	BrandID        uint      `json:"brand_id" binding:"required" gorm:"not null"`
+	Brand          Brand     `json:"brand" binding:"-" gorm:"foreignKey:BrandID"`
+	Name           string    `json:"name" binding:"required,max=100" gorm:"not null"`
+	Model          string    `json:"model" binding:"max=100"`
+	Year           int       `json:"year" binding:"vehicle_year" gorm:"not null"`
+	Price          float64   `json:"price" binding:"gt=0" gorm:"not null"`
+	FuelType       string    `json:"fuel_type" binding:"required,oneof=Petrol Diesel Electric Hybrid" gorm:"not null"`
+	ThumbnailURL   string    `json:"thumbnail_url" binding:"max=500"`
+	Description    string    `json:"description" binding:"max=5000"`
+	EngineSpecs    string    `json:"engine_specs" binding:"max=255"`
+	Transmission   string    `json:"transmission" binding:"max=50"`
+	Mileage        int       `json:"mileage" binding:"gte=0"`
+	ExteriorColor  string    `json:"exterior_color" binding:"max=50"`
+	InteriorColor  string    `json:"interior_color" binding:"max=50"`
+	SafetyFeatures string    `json:"safety_features" binding:"max=2000"`
+	FinancingRate  float64   `json:"financing_rate" binding:"gte=0,lte=100"`
+	WarrantyYears  int       `json:"warranty_years" binding:"gte=0,lte=20"`
+	DealerInfo     string    `json:"dealer_info" binding:"max=255"`
 	Availability   bool      `json:"availability" gorm:"default:true"`
+	Version        uint      `json:"version" gorm:"not null;default:1"` // bumped on every update; sent as the ETag
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
 	Bookings       []Booking `json:"bookings,omitempty" gorm:"foreignKey:VehicleID"`
+	// SearchSnippet is set on search results: an excerpt of the best matching
+	// text, HTML-escaped, with the matched words wrapped in <mark></mark>. It
+	// is safe to render as HTML.
+	SearchSnippet string `json:"search_snippet,omitempty" binding:"-" gorm:"-"`
 }
 
 // Booking represents a customer booking request
 type Booking struct {
-	ID            uint      `json:"id" gorm:"primaryKey"`
-	VehicleID     uint      `json:"vehicle_id" gorm:"not null"`
-	Vehicle       Vehicle   `json:"vehicle" gorm:"foreignKey:VehicleID"`
-	CustomerName  string    `json:"customer_name" gorm:"not null"`
-	CustomerEmail string    `json:"customer_email" gorm:"not null"`
-	CustomerPhone string    `json:"customer_phone"`
-	Message       string    `json:"message"`
-	Status        string    `json:"status" gorm:"default:'pending'"` // pending, contacted, completed, cancelled
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	VehicleID      uint       `json:"vehicle_id" binding:"required" gorm:"not null"`
+	Vehicle        Vehicle    `json:"vehicle" binding:"-" gorm:"foreignKey:VehicleID"`
+	CustomerName   string     `json:"customer_name" binding:"required,max=100" gorm:"not null"`
+	CustomerEmail  string     `json:"customer_email" binding:"required,email,max=255" gorm:"not null"`
+	CustomerPhone  string     `json:"customer_phone" binding:"omitempty,phone"`
+	Message        string     `json:"message" binding:"max=2000"`
+	RequestedStart *time.Time `json:"requested_start,omitempty" gorm:"index"` // set for test drives
+	RequestedEnd   *time.Time `json:"requested_end,omitempty"`
+	Status         string     `json:"status" gorm:"default:'pending'"` // pending, contacted, completed, cancelled
+	SuspectedSpam  bool       `json:"suspected_spam" gorm:"not null;default:false;index"`
+	SpamReasons    string     `json:"spam_reasons,omitempty"`            // comma-separated: duplicate, honeypot, too_fast, no_form_time
+	Version        uint       `json:"version" gorm:"not null;default:1"` // bumped on every update; sent as the ETag
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Reservation is a temporary hold that takes a vehicle off sale until it is
+// released or expires
+type Reservation struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	VehicleID     uint       `json:"vehicle_id" gorm:"not null;index"`
+	Vehicle       Vehicle    `json:"vehicle" gorm:"foreignKey:VehicleID"`
+	CustomerName  string     `json:"customer_name"`
+	CustomerEmail string     `json:"customer_email"`
+	Note          string     `json:"note"`
+	HeldBy        string     `json:"held_by"`
+	ExpiresAt     time.Time  `json:"expires_at" gorm:"not null;index"`
+	ReleasedAt    *time.Time `json:"released_at,omitempty"`
+	ReleaseReason string     `json:"release_reason,omitempty"` // released, expired
+	// PreviousAvailability is given back to the vehicle when the hold ends:
+	// its availability before the hold, or one an admin set during it
+	PreviousAvailability bool      `json:"previous_availability" gorm:"not null"`
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// DealerHours is a dealer's opening hours on one day of the week.
+// Days without a row are closed.
+type DealerHours struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Dealer   string `json:"dealer" gorm:"not null;uniqueIndex:idx_dealer_hours_day"`
+	Weekday  int    `json:"weekday" gorm:"not null;uniqueIndex:idx_dealer_hours_day"` // 0 = Sunday
+	OpensAt  string `json:"opens_at" gorm:"not null"`                                 // HH:MM
+	ClosesAt string `json:"closes_at" gorm:"not null"`                                // HH:MM
+}
+
+// BookingStatusHistory records one booking status transition
+type BookingStatusHistory struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	BookingID  uint      `json:"booking_id" gorm:"not null;index"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status" gorm:"not null"`
+	Actor      string    `json:"actor"`
+	Note       string    `json:"note"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName keeps the history table name singular
+func (BookingStatusHistory) TableName() string {
+	return "booking_status_history"
+}
+
+// WebhookSubscription is an endpoint that receives signed event payloads
+type WebhookSubscription struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	URL       string    `json:"url" gorm:"not null"`
+	Secret    string    `json:"-" gorm:"not null"`
+	Events    string    `json:"events"` // comma-separated event types; empty or "*" for all
+	Active    bool      `json:"active" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OutboxEvent is a domain event written in the same transaction as the change
+// it describes and fanned out to webhook subscriptions afterwards
+type OutboxEvent struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	EventType   string     `json:"event_type" gorm:"not null"`
+	Payload     string     `json:"payload" gorm:"not null"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ProcessedAt *time.Time `json:"processed_at,omitempty" gorm:"index"`
+}
+
+// WebhookDelivery is one event queued for one subscription, with the outcome
+// of its latest attempt
+type WebhookDelivery struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	SubscriptionID uint       `json:"subscription_id" gorm:"not null;index"`
+	EventID        uint       `json:"event_id" gorm:"not null"`
+	EventType      string     `json:"event_type" gorm:"not null"`
+	Payload        string     `json:"-" gorm:"not null"`
+	OccurredAt     time.Time  `json:"occurred_at"`
+	Status         string     `json:"status" gorm:"not null;index"` // pending, succeeded, failed
+	Attempts       int        `json:"attempts"`
+	ResponseCode   int        `json:"response_code"`
+	LastError      string     `json:"last_error"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" gorm:"index"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// IdempotencyRecord is the stored outcome of a create request sent with an
+// Idempotency-Key header, replayed when the request is retried
+type IdempotencyRecord struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	Scope        string    `json:"scope" gorm:"not null;uniqueIndex:idx_idempotency_scope_key"` // endpoint and caller
+	Key          string    `json:"key" gorm:"column:idempotency_key;not null;uniqueIndex:idx_idempotency_scope_key"`
+	RequestHash  string    `json:"request_hash" gorm:"not null"`
+	StatusCode   int       `json:"status_code"` // 0 while the first request is in flight
+	ContentType  string    `json:"content_type"`
+	ResponseBody []byte    `json:"-"`
+	ExpiresAt    time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// SearchSynonym makes a search word also match another word or phrase,
+// e.g. "ev" also matches "Electric"
+type SearchSynonym struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Term      string    `json:"term" binding:"required,max=50" gorm:"not null;uniqueIndex:idx_search_synonyms_term_synonym"` // a single lowercase word
+	Synonym   string    `json:"synonym" binding:"required,max=100" gorm:"not null;uniqueIndex:idx_search_synonyms_term_synonym"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SearchQuery counts how often customers made a search, for suggesting
+// popular searches
+type SearchQuery struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	Query          string    `json:"query" gorm:"not null;size:200;uniqueIndex"` // lowercase words separated by single spaces
+	Searches       int64     `json:"searches" gorm:"not null;default:0;index"`
+	LastSearchedAt time.Time `json:"last_searched_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// AdminUser represents a staff account allowed to use the admin API
+type AdminUser struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	Email        string    `json:"email" gorm:"not null;unique"`
+	Name         string    `json:"name"`
+	PasswordHash string    `json:"-" gorm:"not null"`
+	Role         string    `json:"role" gorm:"not null;default:'analyst'"` // admin, inventory_manager, sales, analyst
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
 
 // VehicleFilter represents filter parameters for vehicle queries
 type VehicleFilter struct {
 	BrandID      uint    `json:"brand_id,omitempty"`
 	FuelType     string  `json:"fuel_type,omitempty"`
+	Transmission string  `json:"transmission,omitempty"`
+	Year         int     `json:"year,omitempty"`
+	Color        string  `json:"color,omitempty"` // exterior colour
 	MinPrice     float64 `json:"min_price,omitempty"`
 	MaxPrice     float64 `json:"max_price,omitempty"`
 	Search       string  `json:"search,omitempty"`
 	Limit        int     `json:"limit,omitempty"`
 	Offset       int     `json:"offset,omitempty"`
 	Availability bool    `json:"availability,omitempty"`
+	// Expansions lists other words or phrases each search word may match
+	// instead, from synonyms and typo correction
+	Expansions map[string][]string `json:"-"`
+	// Sort orders the results by these keys in turn, then by ID. Without
+	// keys, searches are ordered by relevance and other lists by ID.
+	Sort []VehicleSort `json:"sort,omitempty"`
+}
+
+// VehicleSort is one key vehicles are sorted by
+type VehicleSort struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc,omitempty"`
+}
+
+// FacetCount is how many vehicles have one value of a facet
+type FacetCount struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"` // display name where value is an ID
+	Count int64  `json:"count"`
+}
+
+// PriceRangeCount is how many vehicles are priced from Min up to Max; a
+// zero Min or Max leaves that end open
+type PriceRangeCount struct {
+	Min   float64 `json:"min,omitempty"`
+	Max   float64 `json:"max,omitempty"`
+	Count int64   `json:"count"`
+}
+
+// BookingFilter represents filter parameters for booking queries
+type BookingFilter struct {
+	Status        string `json:"status,omitempty"`
+	SuspectedSpam *bool  `json:"suspected_spam,omitempty"` // nil matches both
 }
 
 // Analytics represents basic inventory analytics
@@ -78,3 +257,33 @@ type Analytics struct {
 	AveragePrice     float64            `json:"average_price"`
 	PriceRange       map[string]float64 `json:"price_range"`
 }
+
+// PopularVehicle is a vehicle ranked by its number of bookings
+type PopularVehicle struct {
+	VehicleID    uint    `json:"vehicle_id"`
+	VehicleName  string  `json:"vehicle_name"`
+	BrandName    string  `json:"brand_name"`
+	BookingCount int64   `json:"booking_count"`
+	Price        float64 `json:"price"`
+}
+
+// BookingTrend is the number of bookings created on a single day
+type BookingTrend struct {
+	Date  string `json:"date"`
+	Count int64  `json:"count"`
+}
+
+// HighDemandVehicle is a vehicle with many open bookings
+type HighDemandVehicle struct {
+	VehicleID    uint   `json:"vehicle_id"`
+	VehicleName  string `json:"vehicle_name"`
+	BrandName    string `json:"brand_name"`
+	BookingCount int64  `json:"booking_count"`
+}
+
+// InventoryStatus summarizes vehicle availability and demand
+type InventoryStatus struct {
+	AvailableVehicles   int64               `json:"available_vehicles"`
+	UnavailableVehicles int64               `json:"unavailable_vehicles"`
+	HighDemandVehicles  []HighDemandVehicle `json:"high_demand_vehicles"`
+}
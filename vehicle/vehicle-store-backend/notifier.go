@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"vehicle-store-backend/internal/database"
+	"vehicle-store-backend/internal/models"
+)
+
+// Notifier tells customers and the dealership about booking activity
+type Notifier interface {
+	BookingCreated(booking *models.Booking) error
+	BookingStatusChanged(booking *models.Booking, from, note string) error
+}
+
+// NopNotifier discards every notification
+type NopNotifier struct{}
+
+func (NopNotifier) BookingCreated(*models.Booking) error                       { return nil }
+func (NopNotifier) BookingStatusChanged(*models.Booking, string, string) error { return nil }
+
+// notificationQueueSize is how many notifications can wait for the mail server
+const notificationQueueSize = 256
+
+// errNotificationQueueFull is returned when notifications arrive faster than
+// the mail server takes them
+var errNotificationQueueFull = errors.New("notification queue is full")
+
+// QueuedNotifier hands notifications to a background worker so requests do
+// not wait for the mail server. Failures are logged by the worker, and
+// notifications still queued when the process exits are lost.
+type QueuedNotifier struct {
+	next  Notifier
+	queue chan func() error
+}
+
+// NewQueuedNotifier returns a Notifier queueing up to size notifications for
+// next; call Start to send them
+func NewQueuedNotifier(next Notifier, size int) *QueuedNotifier {
+	return &QueuedNotifier{next: next, queue: make(chan func() error, size)}
+}
+
+func (n *QueuedNotifier) BookingCreated(booking *models.Booking) error {
+	return n.enqueue(func() error { return n.next.BookingCreated(booking) })
+}
+
+func (n *QueuedNotifier) BookingStatusChanged(booking *models.Booking, from, note string) error {
+	return n.enqueue(func() error { return n.next.BookingStatusChanged(booking, from, note) })
+}
+
+// enqueue queues send without blocking the request
+func (n *QueuedNotifier) enqueue(send func() error) error {
+	select {
+	case n.queue <- send:
+		return nil
+	default:
+		return errNotificationQueueFull
+	}
+}
+
+// Start sends queued notifications one at a time until ctx is done
+func (n *QueuedNotifier) Start(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case send := <-n.queue:
+				if err := send(); err != nil {
+					log.Println("Failed to send notification:", err)
+				}
+			}
+		}
+	}()
+}
+
+// Message is a plain-text email
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email messages
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// headerValue strips line breaks so user input cannot inject headers
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
+
+// Bytes renders msg in RFC 5322 format
+func (msg Message) Bytes() []byte {
+	to := make([]string, len(msg.To))
+	for i, addr := range msg.To {
+		to[i] = headerValue(addr)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", headerValue(msg.From))
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", headerValue(msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	buf.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return buf.Bytes()
+}
+
+// SMTPMailer sends mail through an SMTP server
+type SMTPMailer struct {
+	Addr     string // host:port
+	Username string
+	Password string
+	Timeout  time.Duration
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	host, _, err := net.SplitHostPort(m.Addr)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.DialTimeout("tcp", m.Addr, m.Timeout)
+	if err != nil {
+		return err
+	}
+	if m.Timeout > 0 {
+		if err := conn.SetDeadline(time.Now().Add(m.Timeout)); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if m.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.Username, m.Password, host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(headerValue(msg.From)); err != nil {
+		return err
+	}
+	for _, addr := range msg.To {
+		if err := client.Rcpt(headerValue(addr)); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg.Bytes()); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// WriterMailer writes each message to an io.Writer instead of sending it,
+// for development and tests
+type WriterMailer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterMailer returns a Mailer that writes messages to w
+func NewWriterMailer(w io.Writer) *WriterMailer {
+	return &WriterMailer{w: w}
+}
+
+func (m *WriterMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.w.Write(msg.Bytes()); err != nil {
+		return err
+	}
+	_, err := io.WriteString(m.w, "\r\n--------\r\n")
+	return err
+}
+
+// notificationTemplates are the email subjects and bodies, keyed by name
+var notificationTemplates = template.Must(template.New("notifications").Parse(`
+{{define "customer_created_subject"}}We received your request for the {{.Vehicle.Brand.Name}} {{.Vehicle.Name}}{{end}}
+{{define "customer_created_body"}}Hi {{.CustomerName}},
+
+Thanks for your interest in the {{.Vehicle.Year}} {{.Vehicle.Brand.Name}} {{.Vehicle.Name}} {{.Vehicle.Model}}.
+{{- if .RequestedStart}}
+Requested test drive: {{.RequestedStart.Format "Mon 2 Jan 2006 15:04"}} - {{.RequestedEnd.Format "15:04"}}
+{{- end}}
+
+Your booking reference is #{{.ID}}. A member of our team will be in touch shortly.
+
+{{.Vehicle.DealerInfo}}
+{{end}}
+
+{{define "dealer_created_subject"}}New booking #{{.ID}}: {{.Vehicle.Brand.Name}} {{.Vehicle.Name}}{{end}}
+{{define "dealer_created_body"}}A new booking has been submitted.
+
+Vehicle:  {{.Vehicle.Year}} {{.Vehicle.Brand.Name}} {{.Vehicle.Name}} {{.Vehicle.Model}} (ID {{.VehicleID}})
+Customer: {{.CustomerName}} <{{.CustomerEmail}}>
+Phone:    {{.CustomerPhone}}
+{{- if .RequestedStart}}
+Test drive: {{.RequestedStart.Format "Mon 2 Jan 2006 15:04"}} - {{.RequestedEnd.Format "15:04"}}
+{{- end}}
+
+{{.Message}}
+{{end}}
+
+{{define "customer_status_subject"}}Update on your booking #{{.Booking.ID}}{{end}}
+{{define "customer_status_body"}}Hi {{.Booking.CustomerName}},
+
+The status of your booking for the {{.Booking.Vehicle.Brand.Name}} {{.Booking.Vehicle.Name}} changed from {{.From}} to {{.Booking.Status}}.
+{{- if .Note}}
+
+{{.Note}}
+{{- end}}
+
+{{.Booking.Vehicle.DealerInfo}}
+{{end}}
+`))
+
+// EmailNotifier renders the notification templates and sends them through a
+// Mailer to the customer and the dealership inbox
+type EmailNotifier struct {
+	Mailer          Mailer
+	From            string
+	DealershipEmail string
+}
+
+// send renders the <name>_subject and <name>_body templates and mails them
+func (n *EmailNotifier) send(to, name string, data interface{}) error {
+	var subject, body bytes.Buffer
+	if err := notificationTemplates.ExecuteTemplate(&subject, name+"_subject", data); err != nil {
+		return err
+	}
+	if err := notificationTemplates.ExecuteTemplate(&body, name+"_body", data); err != nil {
+		return err
+	}
+
+	return n.Mailer.Send(Message{
+		From:    n.From,
+		To:      []string{to},
+		Subject: strings.TrimSpace(subject.String()),
+		Body:    body.String(),
+	})
+}
+
+func (n *EmailNotifier) BookingCreated(booking *models.Booking) error {
+	var errs []string
+	if booking.CustomerEmail != "" {
+		if err := n.send(booking.CustomerEmail, "customer_created", booking); err != nil {
+			errs = append(errs, "customer: "+err.Error())
+		}
+	}
+	if n.DealershipEmail != "" {
+		if err := n.send(n.DealershipEmail, "dealer_created", booking); err != nil {
+			errs = append(errs, "dealership: "+err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("booking %d notification failed: %s", booking.ID, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (n *EmailNotifier) BookingStatusChanged(booking *models.Booking, from, note string) error {
+	if booking.CustomerEmail == "" {
+		return nil
+	}
+
+	data := struct {
+		Booking *models.Booking
+		From    string
+		Note    string
+	}{booking, from, note}
+
+	return n.send(booking.CustomerEmail, "customer_status", data)
+}
+
+// NewNotifierFromEnv builds the notifier selected by MAIL_DRIVER:
+// "smtp" (SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD), "file"
+// (MAIL_FILE) or "log" (the default, writing to stdout)
+func NewNotifierFromEnv() (Notifier, error) {
+	var mailer Mailer
+
+	switch driver := database.GetEnv("MAIL_DRIVER", "log"); driver {
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required for the smtp mail driver")
+		}
+		mailer = &SMTPMailer{
+			Addr:     net.JoinHostPort(host, database.GetEnv("SMTP_PORT", "587")),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			Timeout:  10 * time.Second,
+		}
+	case "file":
+		f, err := os.OpenFile(database.GetEnv("MAIL_FILE", "mail.log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		mailer = NewWriterMailer(f)
+	case "log":
+		mailer = NewWriterMailer(log.Writer())
+	default:
+		return nil, fmt.Errorf("unsupported MAIL_DRIVER %q", driver)
+	}
+
+	return &EmailNotifier{
+		Mailer:          mailer,
+		From:            database.GetEnv("MAIL_FROM", "no-reply@vehicle-store.local"),
+		DealershipEmail: os.Getenv("DEALERSHIP_EMAIL"),
+	}, nil
+}
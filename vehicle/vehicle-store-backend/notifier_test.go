@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"vehicle-store-backend/internal/models"
+)
+
+// fakeSMTPMail is a message accepted by fakeSMTPServer
+type fakeSMTPMail struct {
+	From string
+	To   []string
+	Data string
+}
+
+// fakeSMTPServer accepts mail on a local port without TLS or auth
+type fakeSMTPServer struct {
+	listener net.Listener
+
+	mu   sync.Mutex
+	mail []fakeSMTPMail
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &fakeSMTPServer{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTPServer) Addr() string { return s.listener.Addr().String() }
+
+// Mail returns the messages accepted so far
+func (s *fakeSMTPServer) Mail() []fakeSMTPMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakeSMTPMail(nil), s.mail...)
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+
+	var mail fakeSMTPMail
+	text.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb := strings.ToUpper(strings.Fields(line + " ")[0])
+
+		switch verb {
+		case "EHLO", "HELO":
+			text.PrintfLine("250 localhost")
+		case "MAIL":
+			mail = fakeSMTPMail{From: smtpPath(line)}
+			text.PrintfLine("250 OK")
+		case "RCPT":
+			mail.To = append(mail.To, smtpPath(line))
+			text.PrintfLine("250 OK")
+		case "DATA":
+			text.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			mail.Data = string(data)
+			s.mu.Lock()
+			s.mail = append(s.mail, mail)
+			s.mu.Unlock()
+			text.PrintfLine("250 OK")
+		case "QUIT":
+			text.PrintfLine("221 Bye")
+			return
+		default:
+			text.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+// smtpPath returns the address in a MAIL FROM:<...> or RCPT TO:<...> line
+func smtpPath(line string) string {
+	start, end := strings.Index(line, "<"), strings.LastIndex(line, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start+1 : end]
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	mailer := &SMTPMailer{Addr: server.Addr(), Timeout: 5 * time.Second}
+
+	err := mailer.Send(Message{
+		From:    "store@example.com",
+		To:      []string{"jane@example.com", "sales@example.com"},
+		Subject: "Your booking\r\nBcc: victim@example.com",
+		Body:    "Hello Jane,\nSee you soon.",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mail := server.Mail()
+	if len(mail) != 1 {
+		t.Fatalf("server received %d messages, want 1", len(mail))
+	}
+	got := mail[0]
+
+	if got.From != "store@example.com" {
+		t.Errorf("MAIL FROM = %q", got.From)
+	}
+	if strings.Join(got.To, ",") != "jane@example.com,sales@example.com" {
+		t.Errorf("RCPT TO = %q", got.To)
+	}
+
+	headers, body, _ := strings.Cut(got.Data, "\n\n")
+	for _, want := range []string{"From: store@example.com", "To: jane@example.com, sales@example.com", "Subject: Your bookingBcc: victim@example.com"} {
+		if !strings.Contains(headers, want+"\n") {
+			t.Errorf("headers missing %q:\n%s", want, headers)
+		}
+	}
+	if strings.Contains(headers, "\nBcc:") {
+		t.Errorf("subject injected a header:\n%s", headers)
+	}
+	if body != "Hello Jane,\nSee you soon.\n" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestEmailNotifierBookingCreated(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	notifier := &EmailNotifier{
+		Mailer:          &SMTPMailer{Addr: server.Addr(), Timeout: 5 * time.Second},
+		From:            "store@example.com",
+		DealershipEmail: "sales@example.com",
+	}
+
+	booking := &models.Booking{
+		ID:            42,
+		CustomerName:  "Jane Smith",
+		CustomerEmail: "jane@example.com",
+		CustomerPhone: "555-0100",
+		Vehicle: models.Vehicle{
+			ID:    7,
+			Name:  "Camry",
+			Model: "XLE",
+			Year:  2024,
+			Brand: models.Brand{Name: "Toyota"},
+		},
+	}
+	if err := notifier.BookingCreated(booking); err != nil {
+		t.Fatal(err)
+	}
+
+	mail := server.Mail()
+	if len(mail) != 2 {
+		t.Fatalf("server received %d messages, want 2", len(mail))
+	}
+
+	customer, dealer := mail[0], mail[1]
+	if strings.Join(customer.To, ",") != "jane@example.com" {
+		t.Errorf("customer mail sent to %q", customer.To)
+	}
+	for _, want := range []string{"Subject: We received your request for the Toyota Camry", "Hi Jane Smith,", "booking reference is #42"} {
+		if !strings.Contains(customer.Data, want) {
+			t.Errorf("customer mail missing %q:\n%s", want, customer.Data)
+		}
+	}
+
+	if strings.Join(dealer.To, ",") != "sales@example.com" {
+		t.Errorf("dealership mail sent to %q", dealer.To)
+	}
+	for _, want := range []string{"Subject: New booking #42: Toyota Camry", "Customer: Jane Smith <jane@example.com>", "Phone:    555-0100"} {
+		if !strings.Contains(dealer.Data, want) {
+			t.Errorf("dealership mail missing %q:\n%s", want, dealer.Data)
+		}
+	}
+}
+
+// recordingNotifier records the bookings it is told about
+type recordingNotifier struct {
+	created chan uint
+}
+
+func (n *recordingNotifier) BookingCreated(booking *models.Booking) error {
+	n.created <- booking.ID
+	return nil
+}
+
+func (n *recordingNotifier) BookingStatusChanged(*models.Booking, string, string) error {
+	return nil
+}
+
+func TestQueuedNotifier(t *testing.T) {
+	next := &recordingNotifier{created: make(chan uint, 1)}
+	notifier := NewQueuedNotifier(next, 1)
+
+	if err := notifier.BookingCreated(&models.Booking{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	// The worker is not running yet, so the queue is full
+	if err := notifier.BookingCreated(&models.Booking{ID: 2}); err != errNotificationQueueFull {
+		t.Fatalf("second notification: err = %v, want errNotificationQueueFull", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	notifier.Start(ctx)
+
+	select {
+	case id := <-next.created:
+		if id != 1 {
+			t.Errorf("sent booking %d, want 1", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued notification was not sent")
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// mergePatchContentType is the media type of a JSON Merge Patch (RFC 7396)
+const mergePatchContentType = "application/merge-patch+json"
+
+// Fields no patch may change
+var (
+	vehicleReadOnlyFields = []string{"id", "brand", "bookings", "version", "created_at", "updated_at"}
+	brandReadOnlyFields   = []string{"id", "vehicles", "version", "created_at", "updated_at"}
+	// Moving a booking to another vehicle or slot would skip the availability
+	// and slot checks made when it was created
+	bookingReadOnlyFields = []string{
+		"id", "vehicle_id", "vehicle", "requested_start", "requested_end",
+		"spam_reasons", "version", "created_at", "updated_at",
+	}
+)
+
+// readMergePatch reads a JSON Merge Patch object from the request body,
+// sent as application/merge-patch+json or application/json. Patches that
+// mention a readOnly field are rejected.
+func readMergePatch(c *gin.Context, readOnly ...string) (map[string]interface{}, error) {
+	switch c.ContentType() {
+	case mergePatchContentType, binding.MIMEJSON:
+	default:
+		return nil, NewAPIError(http.StatusUnsupportedMediaType, CodeUnsupportedMediaType,
+			"Content-Type must be "+mergePatchContentType)
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, badRequestError("Request body could not be read")
+	}
+
+	var patch map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&patch); err != nil || patch == nil {
+		// A patch that is not an object would replace the whole resource
+		return nil, NewAPIError(http.StatusBadRequest, CodeInvalidJSON, "Request body must be a JSON object")
+	}
+
+	var details []FieldError
+	for _, field := range readOnly {
+		if _, ok := patch[field]; ok {
+			details = append(details, FieldError{Field: field, Message: "is read-only"})
+		}
+	}
+	if len(details) > 0 {
+		return nil, validationError(details...)
+	}
+
+	return patch, nil
+}
+
+// applyMergePatch applies patch to record, a pointer to a model, and checks
+// the result against the model's binding tags. Fields set to null are reset
+// to their zero value. record is left unchanged if the patch is invalid.
+// Fields hidden from JSON are not carried over, so models with such fields
+// cannot be patched this way.
+func applyMergePatch(record interface{}, patch map[string]interface{}) error {
+	current, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	var document interface{}
+	if err := json.Unmarshal(current, &document); err != nil {
+		return err
+	}
+
+	merged, err := json.Marshal(mergePatch(document, patch))
+	if err != nil {
+		return err
+	}
+
+	target := reflect.ValueOf(record).Elem()
+	patched := reflect.New(target.Type())
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(patched.Interface()); err != nil {
+		if field, ok := unknownField(err); ok {
+			return validationError(FieldError{Field: field, Message: "is not a known field"})
+		}
+		return bindError(err)
+	}
+
+	if err := binding.Validator.ValidateStruct(patched.Interface()); err != nil {
+		return bindError(err)
+	}
+
+	target.Set(patched.Elem())
+	return nil
+}
+
+// mergePatch merges patch into target following RFC 7396: objects are merged
+// key by key, null removes a key and any other value replaces the target
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+	return targetObject
+}
+
+// unknownField extracts the field name from the decoder's error for a field
+// the target struct does not have
+func unknownField(err error) (string, bool) {
+	const prefix = `json: unknown field "`
+	msg := err.Error()
+	if !strings.HasPrefix(msg, prefix) {
+		return "", false
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(msg, prefix), `"`), true
+}
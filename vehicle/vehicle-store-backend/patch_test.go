@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"vehicle-store-backend/internal/models"
+)
+
+func TestMergePatch(t *testing.T) {
+	// The examples from RFC 7396 appendix A
+	tests := []struct {
+		target, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		var target, patch, want interface{}
+		for _, doc := range []struct {
+			raw string
+			v   *interface{}
+		}{{tt.target, &target}, {tt.patch, &patch}, {tt.want, &want}} {
+			if err := json.Unmarshal([]byte(doc.raw), doc.v); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if got := mergePatch(target, patch); !reflect.DeepEqual(got, want) {
+			gotJSON, _ := json.Marshal(got)
+			t.Errorf("mergePatch(%s, %s) = %s, want %s", tt.target, tt.patch, gotJSON, tt.want)
+		}
+	}
+}
+
+func TestPatchVehicle(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.adminToken(t, RoleInventoryManager)
+	vehicle := ts.addVehicle(t, models.Vehicle{
+		Name:        "Camry",
+		Year:        2024,
+		Price:       28000,
+		Mileage:     1200,
+		Description: "Low mileage",
+	})
+	path := fmt.Sprintf("/api/admin/vehicles/%d", vehicle.ID)
+
+	// patch sends body with the vehicle's current ETag
+	patch := func(body string, headers ...string) *httptest.ResponseRecorder {
+		current, err := ts.repos.Vehicles.GetByID(vehicle.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		headers = append([]string{
+			"Authorization", token,
+			"Content-Type", mergePatchContentType,
+			ifMatchHeader, etag(current.Version),
+		}, headers...)
+		return ts.do(http.MethodPatch, path, body, headers...)
+	}
+
+	t.Run("changes only the given fields", func(t *testing.T) {
+		w := patch(`{"price":26500,"description":null}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("got %d: %s", w.Code, w.Body.String())
+		}
+
+		var got models.Vehicle
+		decode(t, w, &got)
+		if got.Price != 26500 || got.Description != "" {
+			t.Errorf("price = %v, description = %q; want 26500 and reset", got.Price, got.Description)
+		}
+		if got.Name != "Camry" || got.Mileage != 1200 || got.BrandID != vehicle.BrandID {
+			t.Errorf("untouched fields changed: %+v", got)
+		}
+		if got.Version != vehicle.Version+1 || w.Header().Get("ETag") != etag(got.Version) {
+			t.Errorf("version = %d, ETag = %q", got.Version, w.Header().Get("ETag"))
+		}
+	})
+
+	t.Run("plain JSON", func(t *testing.T) {
+		w := patch(`{"mileage":1500}`, "Content-Type", "application/json")
+		if w.Code != http.StatusOK {
+			t.Fatalf("got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	errorTests := []struct {
+		name    string
+		body    string
+		headers []string
+		status  int
+		code    string
+		field   string
+	}{
+		{"read-only field", `{"id":99,"name":"Corolla"}`, nil, http.StatusUnprocessableEntity, CodeValidationFailed, "id"},
+		{"unknown field", `{"colour":"red"}`, nil, http.StatusUnprocessableEntity, CodeValidationFailed, "colour"},
+		{"invalid value", `{"price":-1}`, nil, http.StatusUnprocessableEntity, CodeValidationFailed, "price"},
+		{"required field reset", `{"name":null}`, nil, http.StatusUnprocessableEntity, CodeValidationFailed, "name"},
+		{"not an object", `["price"]`, nil, http.StatusBadRequest, CodeInvalidJSON, ""},
+		{"unsupported content type", `{"price":1}`, []string{"Content-Type", "text/plain"}, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, ""},
+		{"missing If-Match", `{"price":1}`, []string{ifMatchHeader, ""}, http.StatusPreconditionRequired, CodePreconditionRequired, ""},
+		{"stale If-Match", `{"price":1}`, []string{ifMatchHeader, etag(vehicle.Version)}, http.StatusPreconditionFailed, CodeVersionConflict, ""},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, err := ts.repos.Vehicles.GetByID(vehicle.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			body := expectError(t, patch(tt.body, tt.headers...), tt.status, tt.code)
+			if tt.field != "" && (len(body.Error.Details) == 0 || body.Error.Details[0].Field != tt.field) {
+				t.Errorf("details = %+v, want field %q", body.Error.Details, tt.field)
+			}
+
+			after, err := ts.repos.Vehicles.GetByID(vehicle.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if after.Version != before.Version || after.Price != before.Price || after.Name != before.Name {
+				t.Errorf("rejected patch changed the vehicle: %+v", after)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	etagHeader    = "ETag"
+	ifMatchHeader = "If-Match"
+)
+
+// etag formats a record version as an entity tag
+func etag(version uint) string {
+	return strconv.Quote(strconv.FormatUint(uint64(version), 10))
+}
+
+// setETag sends version as the response's ETag
+func setETag(c *gin.Context, version uint) {
+	c.Header(etagHeader, etag(version))
+}
+
+// checkIfMatch guards a change to a record at version. Requests without
+// If-Match get a 428 so clients cannot skip the check by accident; requests
+// whose If-Match lists neither the current ETag nor * get a 412 carrying the
+// current ETag.
+func checkIfMatch(c *gin.Context, version uint) error {
+	header := c.GetHeader(ifMatchHeader)
+	if header == "" {
+		return NewAPIError(http.StatusPreconditionRequired, CodePreconditionRequired,
+			"If-Match header is required; send the ETag from the latest read of this record")
+	}
+
+	current := etag(version)
+	for _, tag := range splitList(header) {
+		if tag == "*" || tag == current {
+			return nil
+		}
+	}
+
+	setETag(c, version)
+	return versionConflictError()
+}
+
+// versionConflictError reports a change based on an out-of-date read
+func versionConflictError() *APIError {
+	return NewAPIError(http.StatusPreconditionFailed, CodeVersionConflict,
+		"This record was changed by someone else; reload it and try again")
+}
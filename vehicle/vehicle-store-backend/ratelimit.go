@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"vehicle-store-backend/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyHeader identifies a partner client in place of its IP address
+const apiKeyHeader = "X-API-Key"
+
+// RateLimit is a token bucket: a client may make Requests requests at once,
+// and the bucket refills at an even rate over Per
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// defaultRateLimits are the per-route limits used unless RATE_LIMITS
+// overrides them
+var defaultRateLimits = map[string]RateLimit{
+	"vehicles.list":     {Requests: 120, Per: time.Minute},
+	"vehicles.suggest":  {Requests: 600, Per: time.Minute},
+	"vehicles.facets":   {Requests: 120, Per: time.Minute},
+	"bookings.create":   {Requests: 10, Per: time.Minute},
+	"analytics.summary": {Requests: 30, Per: time.Minute},
+}
+
+// RateLimitResult is the outcome of taking a token from a bucket
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next token when the request was denied
+	RetryAfter time.Duration
+}
+
+// RateLimitStore holds the token buckets. MemoryRateLimitStore suits a single
+// instance; several instances need a shared implementation.
+type RateLimitStore interface {
+	// Take removes a token from the bucket for key, creating a full bucket
+	// for limit if there is none
+	Take(key string, limit RateLimit, now time.Time) (RateLimitResult, error)
+}
+
+// RateLimiter throttles clients per route
+type RateLimiter struct {
+	Store RateLimitStore
+	// Limits are keyed by route name; routes without an entry are unlimited
+	Limits map[string]RateLimit
+	// APIKeys may be sent in X-API-Key to get a bucket of their own instead
+	// of sharing the client IP's
+	APIKeys map[string]bool
+	// Now is the limiter's clock; nil means time.Now
+	Now func() time.Time
+}
+
+// NewRateLimiter returns a RateLimiter with the default limits and an
+// in-memory store
+func NewRateLimiter() *RateLimiter {
+	limits := make(map[string]RateLimit, len(defaultRateLimits))
+	for route, limit := range defaultRateLimits {
+		limits[route] = limit
+	}
+	return &RateLimiter{
+		Store:   NewMemoryRateLimitStore(),
+		Limits:  limits,
+		APIKeys: make(map[string]bool),
+	}
+}
+
+// NewRateLimiterFromEnv returns a RateLimiter configured by RATE_LIMITS, a
+// comma-separated list of route=requests/period entries such as
+// "vehicles.list=300/1m,bookings.create=5/10m" (0 requests disables a route's
+// limit), and RATE_LIMIT_API_KEYS, a comma-separated list of partner keys
+func NewRateLimiterFromEnv() (*RateLimiter, error) {
+	limiter := NewRateLimiter()
+
+	for _, entry := range splitList(database.GetEnv("RATE_LIMITS", "")) {
+		route, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid RATE_LIMITS entry %q", entry)
+		}
+		limit, err := parseRateLimit(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RATE_LIMITS entry %q: %w", entry, err)
+		}
+
+		route = strings.TrimSpace(route)
+		if limit.Requests == 0 {
+			delete(limiter.Limits, route)
+			continue
+		}
+		limiter.Limits[route] = limit
+	}
+
+	for _, key := range splitList(database.GetEnv("RATE_LIMIT_API_KEYS", "")) {
+		limiter.APIKeys[key] = true
+	}
+
+	return limiter, nil
+}
+
+// parseRateLimit parses "requests/period", e.g. "120/1m"
+func parseRateLimit(spec string) (RateLimit, error) {
+	count, period, ok := strings.Cut(strings.TrimSpace(spec), "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("expected requests/period")
+	}
+
+	requests, err := strconv.Atoi(count)
+	if err != nil || requests < 0 {
+		return RateLimit{}, fmt.Errorf("invalid request count %q", count)
+	}
+
+	per, err := time.ParseDuration(period)
+	if err != nil || per <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid period %q", period)
+	}
+
+	return RateLimit{Requests: requests, Per: per}, nil
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// now reads the limiter's clock
+func (l *RateLimiter) now() time.Time {
+	if l.Now != nil {
+		return l.Now()
+	}
+	return time.Now()
+}
+
+// clientKey identifies the caller: a known API key if one was sent, the
+// client IP otherwise. Unknown keys are ignored so they cannot be rotated to
+// dodge the limit.
+func (l *RateLimiter) clientKey(c *gin.Context) string {
+	if key := c.GetHeader(apiKeyHeader); key != "" && l.APIKeys[key] {
+		return "key:" + key
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// RateLimit limits each client to the configured rate for route and sets the
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers. Denied
+// requests get a 429 with Retry-After. If the store fails the request is let
+// through rather than taking the route down.
+func (s *Server) RateLimit(route string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limiter := s.RateLimiter
+		if limiter == nil {
+			c.Next()
+			return
+		}
+
+		limit, ok := limiter.Limits[route]
+		if !ok || limit.Requests <= 0 {
+			c.Next()
+			return
+		}
+
+		result, err := limiter.Store.Take(route+":"+limiter.clientKey(c), limit, limiter.now())
+		if err != nil {
+			log.Println("Rate limiter unavailable:", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, ceilSeconds(limit.Per)))
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			respondError(c, NewAPIError(http.StatusTooManyRequests, CodeRateLimited, "Too many requests, please try again later"))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ceilSeconds rounds d up to whole seconds, as the rate limit headers expect
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// tokenBucket is one client's bucket in MemoryRateLimitStore
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	// fullAt is when the bucket will have refilled completely, after which
+	// it is indistinguishable from a new one and can be dropped
+	fullAt time.Time
+}
+
+// MemoryRateLimitStore is an in-process RateLimitStore
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// NewMemoryRateLimitStore returns an empty MemoryRateLimitStore
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*tokenBucket)}
+}
+
+func (m *MemoryRateLimitStore) Take(key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+
+	capacity := float64(limit.Requests)
+	// Tokens added per second
+	rate := capacity / limit.Per.Seconds()
+
+	bucket, ok := m.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, updated: now}
+		m.buckets[key] = bucket
+	}
+
+	if elapsed := now.Sub(bucket.updated).Seconds(); elapsed > 0 {
+		bucket.tokens = math.Min(capacity, bucket.tokens+elapsed*rate)
+		bucket.updated = now
+	}
+
+	result := RateLimitResult{}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - bucket.tokens) / rate)
+	}
+
+	result.Remaining = int(bucket.tokens)
+	result.Reset = secondsToDuration((capacity - bucket.tokens) / rate)
+	bucket.fullAt = now.Add(result.Reset)
+
+	return result, nil
+}
+
+// sweep drops buckets that have refilled completely, at most once a minute;
+// callers must hold mu
+func (m *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+	m.lastSweep = now
+
+	for key, bucket := range m.buckets {
+		if !bucket.fullAt.After(now) {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// rateLimitedServer is a test server whose rate limiter runs on a clock the
+// test moves by hand
+type rateLimitedServer struct {
+	*testServer
+	clock time.Time
+}
+
+// newRateLimitedServer limits GET /api/vehicles to limit
+func newRateLimitedServer(t *testing.T, limit RateLimit) *rateLimitedServer {
+	t.Helper()
+
+	rs := &rateLimitedServer{
+		testServer: newTestServer(t),
+		clock:      time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC),
+	}
+	rs.RateLimiter = NewRateLimiter()
+	rs.RateLimiter.Limits = map[string]RateLimit{"vehicles.list": limit}
+	rs.RateLimiter.Now = func() time.Time { return rs.clock }
+	return rs
+}
+
+// list requests the vehicle list from remoteAddr, with an X-API-Key header
+// unless apiKey is empty
+func (rs *rateLimitedServer) list(remoteAddr, apiKey string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/vehicles", nil)
+	req.RemoteAddr = remoteAddr
+	if apiKey != "" {
+		req.Header.Set(apiKeyHeader, apiKey)
+	}
+
+	w := httptest.NewRecorder()
+	rs.router.ServeHTTP(w, req)
+	return w
+}
+
+// expectAllowed checks a request got through with the given headers
+func expectAllowed(t *testing.T, w *httptest.ResponseRecorder, remaining, reset string) {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("RateLimit-Remaining"); got != remaining {
+		t.Errorf("RateLimit-Remaining = %q, want %q", got, remaining)
+	}
+	if got := w.Header().Get("RateLimit-Reset"); got != reset {
+		t.Errorf("RateLimit-Reset = %q, want %q", got, reset)
+	}
+}
+
+// expectLimited checks a request was denied with the given Retry-After
+func expectLimited(t *testing.T, w *httptest.ResponseRecorder, retryAfter string) {
+	t.Helper()
+	expectError(t, w, http.StatusTooManyRequests, CodeRateLimited)
+	if got := w.Header().Get("Retry-After"); got != retryAfter {
+		t.Errorf("Retry-After = %q, want %q", got, retryAfter)
+	}
+	if got := w.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Errorf("RateLimit-Remaining = %q, want 0", got)
+	}
+}
+
+func TestRateLimitBurstAndRefill(t *testing.T) {
+	// Three requests at once, refilling one token every 20 seconds
+	rs := newRateLimitedServer(t, RateLimit{Requests: 3, Per: time.Minute})
+	const client = "203.0.113.1:1000"
+
+	w := rs.list(client, "")
+	if got := w.Header().Get("RateLimit-Policy"); got != "3;w=60" {
+		t.Errorf("RateLimit-Policy = %q, want 3;w=60", got)
+	}
+	if got := w.Header().Get("RateLimit-Limit"); got != "3" {
+		t.Errorf("RateLimit-Limit = %q, want 3", got)
+	}
+	expectAllowed(t, w, "2", "20")
+	expectAllowed(t, rs.list(client, ""), "1", "40")
+	expectAllowed(t, rs.list(client, ""), "0", "60")
+	expectLimited(t, rs.list(client, ""), "20")
+
+	// Part way to the next token
+	rs.clock = rs.clock.Add(15 * time.Second)
+	expectLimited(t, rs.list(client, ""), "5")
+
+	rs.clock = rs.clock.Add(5 * time.Second)
+	expectAllowed(t, rs.list(client, ""), "0", "60")
+	expectLimited(t, rs.list(client, ""), "20")
+
+	// An idle bucket refills to the burst size and no further
+	rs.clock = rs.clock.Add(time.Hour)
+	for _, want := range []struct{ remaining, reset string }{{"2", "20"}, {"1", "40"}, {"0", "60"}} {
+		expectAllowed(t, rs.list(client, ""), want.remaining, want.reset)
+	}
+	expectLimited(t, rs.list(client, ""), "20")
+
+	// Other clients have buckets of their own
+	expectAllowed(t, rs.list("198.51.100.7:1000", ""), "2", "20")
+}
+
+func TestRateLimitUnlimitedRoute(t *testing.T) {
+	rs := newRateLimitedServer(t, RateLimit{Requests: 1, Per: time.Minute})
+
+	for i := 0; i < 3; i++ {
+		w := rs.do(http.MethodGet, "/api/brands", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d = %d", i, w.Code)
+		}
+		if got := w.Header().Get("RateLimit-Limit"); got != "" {
+			t.Errorf("unlimited route sent RateLimit-Limit %q", got)
+		}
+	}
+}
+
+func TestRateLimitAPIKeys(t *testing.T) {
+	rs := newRateLimitedServer(t, RateLimit{Requests: 1, Per: time.Minute})
+	rs.RateLimiter.APIKeys = map[string]bool{"partner-key": true}
+	const client = "203.0.113.1:1000"
+
+	expectAllowed(t, rs.list(client, ""), "0", "60")
+	expectLimited(t, rs.list(client, ""), "60")
+
+	// A known key has its own bucket, whichever address it comes from
+	expectAllowed(t, rs.list(client, "partner-key"), "0", "60")
+	expectLimited(t, rs.list("198.51.100.7:1000", "partner-key"), "60")
+
+	// An unknown key is ignored, so it shares the address's bucket
+	expectLimited(t, rs.list(client, "made-up-key"), "60")
+}
+
+func TestNewRateLimiterFromEnv(t *testing.T) {
+	t.Setenv("RATE_LIMITS", " vehicles.list=300/1m, bookings.create=5/10m,analytics.summary=0/1m,,")
+	t.Setenv("RATE_LIMIT_API_KEYS", "key-1, key-2,")
+
+	limiter, err := NewRateLimiterFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]RateLimit{
+		"vehicles.list":    {Requests: 300, Per: time.Minute},
+		"vehicles.suggest": defaultRateLimits["vehicles.suggest"],
+		"vehicles.facets":  defaultRateLimits["vehicles.facets"],
+		"bookings.create":  {Requests: 5, Per: 10 * time.Minute},
+	}
+	if !reflect.DeepEqual(limiter.Limits, want) {
+		t.Errorf("limits = %v, want %v", limiter.Limits, want)
+	}
+	if want := map[string]bool{"key-1": true, "key-2": true}; !reflect.DeepEqual(limiter.APIKeys, want) {
+		t.Errorf("API keys = %v, want %v", limiter.APIKeys, want)
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		t.Setenv("RATE_LIMITS", "")
+		t.Setenv("RATE_LIMIT_API_KEYS", "")
+
+		limiter, err := NewRateLimiterFromEnv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(limiter.Limits, defaultRateLimits) || len(limiter.APIKeys) != 0 {
+			t.Errorf("limits = %v, API keys = %v", limiter.Limits, limiter.APIKeys)
+		}
+	})
+
+	for _, entry := range []string{
+		"vehicles.list",
+		"vehicles.list=300",
+		"vehicles.list=many/1m",
+		"vehicles.list=-1/1m",
+		"vehicles.list=300/soon",
+		"vehicles.list=300/0s",
+	} {
+		t.Run(entry, func(t *testing.T) {
+			t.Setenv("RATE_LIMITS", entry)
+			if _, err := NewRateLimiterFromEnv(); err == nil {
+				t.Errorf("RATE_LIMITS=%q was accepted", entry)
+			}
+		})
+	}
+}
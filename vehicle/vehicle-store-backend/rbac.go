@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Permission names a single admin capability
+type Permission string
+
+const (
+	PermVehiclesWrite   Permission = "vehicles:write"
+	PermVehiclesDelete  Permission = "vehicles:delete"
+	PermBrandsWrite     Permission = "brands:write"
+	PermBrandsDelete    Permission = "brands:delete"
+	PermBookingsRead    Permission = "bookings:read"
+	PermBookingsUpdate  Permission = "bookings:update"
+	PermBookingsDelete  Permission = "bookings:delete"
+	PermAnalyticsRead   Permission = "analytics:read"
+	PermInventoryReport Permission = "inventory:report"
+	PermReservations    Permission = "reservations:manage"
+	PermWebhooks        Permission = "webhooks:manage"
+	PermSearchManage    Permission = "search:manage"
+)
+
+// Roles assignable to admin users
+const (
+	RoleAdmin            = "admin"
+	RoleInventoryManager = "inventory_manager"
+	RoleSales            = "sales"
+	RoleAnalyst          = "analyst"
+)
+
+// rolePermissions is the permission table checked by RequirePermission
+var rolePermissions = map[string][]Permission{
+	RoleAdmin: {
+		PermVehiclesWrite, PermVehiclesDelete,
+		PermBrandsWrite, PermBrandsDelete,
+		PermBookingsRead, PermBookingsUpdate, PermBookingsDelete,
+		PermAnalyticsRead, PermInventoryReport,
+		PermReservations, PermWebhooks,
+		PermSearchManage,
+	},
+	RoleInventoryManager: {
+		PermVehiclesWrite, PermVehiclesDelete,
+		PermBrandsWrite, PermBrandsDelete,
+		PermInventoryReport, PermReservations,
+		PermSearchManage,
+	},
+	RoleSales: {
+		PermBookingsRead, PermBookingsUpdate,
+		PermReservations,
+	},
+	RoleAnalyst: {
+		PermAnalyticsRead,
+	},
+}
+
+// HasPermission reports whether role grants perm
+func HasPermission(role string, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission rejects requests from admins whose role lacks perm.
+// It must run after AuthRequired.
+func RequirePermission(perm Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		admin, ok := currentAdmin(c)
+		if !ok {
+			respondError(c, NewAPIError(http.StatusUnauthorized, CodeUnauthorized, "Authorization required"))
+			return
+		}
+
+		if !HasPermission(admin.Role, perm) {
+			respondError(c, NewAPIError(http.StatusForbidden, CodeForbidden, "Insufficient permissions"))
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"vehicle-store-backend/internal/models"
+)
+
+// ErrNotFound is returned by repositories when a record does not exist
+var ErrNotFound = errors.New("record not found")
+
+// ErrDuplicate is returned by repositories when a record with the same unique
+// key already exists
+var ErrDuplicate = errors.New("record already exists")
+
+// ErrVersionConflict is returned by repositories when a record being updated
+// has been changed since it was read
+var ErrVersionConflict = errors.New("record has been modified")
+
+// VehicleRepository stores vehicles
+type VehicleRepository interface {
+	// List returns available vehicles matching filter and the total match count
+	List(filter models.VehicleFilter) ([]models.Vehicle, int64, error)
+	// CountBy returns how many available vehicles matching filter have each
+	// value of facet, most common first. Empty values are left out.
+	CountBy(filter models.VehicleFilter, facet VehicleFacet) ([]models.FacetCount, error)
+	// CountByPrice returns how many available vehicles matching filter fall
+	// in each price range split at limits, which must be ascending: below
+	// limits[0], from each limit up to the next, and from the last limit up
+	CountByPrice(filter models.VehicleFilter, limits []float64) ([]int64, error)
+	// GetByID returns a vehicle with its brand loaded
+	GetByID(id uint) (*models.Vehicle, error)
+	// GetByIDForUpdate returns a vehicle and locks its row until the
+	// surrounding transaction ends
+	GetByIDForUpdate(id uint) (*models.Vehicle, error)
+	Create(vehicle *models.Vehicle) error
+	// Update saves vehicle and bumps its version, returning ErrVersionConflict
+	// if the stored vehicle is no longer at the version it was read at
+	Update(vehicle *models.Vehicle) error
+	Delete(id uint) error
+	// ListNames returns the distinct brand, name and model combinations of
+	// available vehicles with how many vehicles share each, ordered by brand,
+	// name and model
+	ListNames() ([]VehicleName, error)
+}
+
+// VehicleFacet names a vehicle field results can be counted by
+type VehicleFacet string
+
+// Facets supported by VehicleRepository.CountBy
+const (
+	FacetBrand        VehicleFacet = "brand"
+	FacetFuelType     VehicleFacet = "fuel_type"
+	FacetTransmission VehicleFacet = "transmission"
+	FacetYear         VehicleFacet = "year"
+	FacetColor        VehicleFacet = "color"
+)
+
+// Fields vehicles can be sorted by
+const (
+	SortPrice      = "price"
+	SortYear       = "year"
+	SortMileage    = "mileage"
+	SortCreatedAt  = "created_at"
+	SortName       = "name"
+	SortPopularity = "popularity" // bookings not flagged as spam
+)
+
+// vehicleSortFields lists the accepted sort fields in the order they are
+// documented
+var vehicleSortFields = []string{SortPrice, SortYear, SortMileage, SortCreatedAt, SortName, SortPopularity}
+
+// VehicleName is a brand, name and model combination in the inventory
+type VehicleName struct {
+	Brand string
+	Name  string
+	Model string
+	Count int64
+}
+
+// BrandRepository stores brands
+type BrandRepository interface {
+	List() ([]models.Brand, error)
+	// GetByID returns a brand with its vehicles loaded
+	GetByID(id uint) (*models.Brand, error)
+	// GetByIDForUpdate returns a brand without its vehicles and locks its row
+	// until the surrounding transaction ends
+	GetByIDForUpdate(id uint) (*models.Brand, error)
+	// Create inserts brand, returning ErrDuplicate if its name is taken
+	Create(brand *models.Brand) error
+	// Update saves brand and bumps its version, returning ErrVersionConflict
+	// if the stored brand is no longer at the version it was read at and
+	// ErrDuplicate if another brand has its name
+	Update(brand *models.Brand) error
+	Delete(id uint) error
+	// CountVehicles returns the number of vehicles belonging to the brand
+	CountVehicles(id uint) (int64, error)
+}
+
+// BookingRepository stores bookings
+type BookingRepository interface {
+	// List returns bookings matching filter, newest first
+	List(filter models.BookingFilter) ([]models.Booking, error)
+	// GetByID returns a booking with its vehicle and brand loaded
+	GetByID(id uint) (*models.Booking, error)
+	// GetByIDForUpdate returns a booking and locks its row until the
+	// surrounding transaction ends
+	GetByIDForUpdate(id uint) (*models.Booking, error)
+	Create(booking *models.Booking) error
+	// Update saves booking and bumps its version, returning ErrVersionConflict
+	// if the stored booking is no longer at the version it was read at
+	Update(booking *models.Booking) error
+	// Delete removes a booking and its status history
+	Delete(id uint) error
+	// AddStatusHistory records a status transition
+	AddStatusHistory(entry *models.BookingStatusHistory) error
+	// StatusHistory returns a booking's transitions oldest first
+	StatusHistory(bookingID uint) ([]models.BookingStatusHistory, error)
+	// ListTestDrives returns the vehicle's test drives with one of statuses
+	// that overlap the half-open interval [from, to), leaving out suspected
+	// spam so it cannot block real customers
+	ListTestDrives(vehicleID uint, from, to time.Time, statuses []string) ([]models.Booking, error)
+	// ListRecentForVehicle returns the vehicle's bookings created at or after
+	// since, newest first
+	ListRecentForVehicle(vehicleID uint, since time.Time) ([]models.Booking, error)
+}
+
+// AnalyticsRepository computes inventory and booking statistics
+type AnalyticsRepository interface {
+	Summary() (*models.Analytics, error)
+	// PopularVehicles ranks vehicles by bookings, leaving out suspected spam
+	// like the popularity sort does
+	PopularVehicles(limit int) ([]models.PopularVehicle, error)
+	BookingTrends(days int) ([]models.BookingTrend, error)
+	InventoryStatus() (*models.InventoryStatus, error)
+}
+
+// ReservationRepository stores vehicle holds
+type ReservationRepository interface {
+	GetByID(id uint) (*models.Reservation, error)
+	// GetByIDForUpdate returns a reservation and locks its row until the
+	// surrounding transaction ends
+	GetByIDForUpdate(id uint) (*models.Reservation, error)
+	// ListActive returns unreleased holds that expire after now, soonest first
+	ListActive(now time.Time) ([]models.Reservation, error)
+	// ListExpired returns unreleased holds that expired at or before now
+	ListExpired(now time.Time) ([]models.Reservation, error)
+	// ActiveForVehicle returns the vehicle's unreleased hold expiring after now
+	ActiveForVehicle(vehicleID uint, now time.Time) (*models.Reservation, error)
+	Create(reservation *models.Reservation) error
+	Update(reservation *models.Reservation) error
+}
+
+// OutboxRepository stores events awaiting webhook fan-out
+type OutboxRepository interface {
+	// Enqueue records an event; call it inside the transaction making the change
+	Enqueue(event *models.OutboxEvent) error
+	// ListUnprocessed returns up to limit events not yet fanned out, oldest first
+	ListUnprocessed(limit int) ([]models.OutboxEvent, error)
+	MarkProcessed(id uint, at time.Time) error
+}
+
+// WebhookRepository stores webhook subscriptions and their delivery log
+type WebhookRepository interface {
+	List() ([]models.WebhookSubscription, error)
+	ListActive() ([]models.WebhookSubscription, error)
+	GetByID(id uint) (*models.WebhookSubscription, error)
+	Create(sub *models.WebhookSubscription) error
+	Update(sub *models.WebhookSubscription) error
+	// Delete removes a subscription and its delivery log
+	Delete(id uint) error
+	CreateDelivery(delivery *models.WebhookDelivery) error
+	UpdateDelivery(delivery *models.WebhookDelivery) error
+	// ListDueDeliveries returns up to limit pending deliveries whose next
+	// attempt is at or before now, oldest first
+	ListDueDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error)
+	// ListDeliveries returns a subscription's latest deliveries, newest first
+	ListDeliveries(subscriptionID uint, limit int) ([]models.WebhookDelivery, error)
+}
+
+// DealerHoursRepository stores dealer opening hours
+type DealerHoursRepository interface {
+	// Get returns the dealer's hours ordered by weekday
+	Get(dealer string) ([]models.DealerHours, error)
+	// Replace swaps the dealer's whole weekly schedule for hours
+	Replace(dealer string, hours []models.DealerHours) error
+}
+
+// IdempotencyRepository stores responses replayed for retried requests
+type IdempotencyRepository interface {
+	// Get returns the record stored under key within scope
+	Get(scope, key string) (*models.IdempotencyRecord, error)
+	// Reserve inserts record, returning ErrDuplicate if its scope and key
+	// are already taken
+	Reserve(record *models.IdempotencyRecord) error
+	Update(record *models.IdempotencyRecord) error
+	Delete(id uint) error
+	// DeleteExpired removes records that expired at or before now and
+	// returns how many were removed
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+// SearchQueryRepository counts the searches customers make
+type SearchQueryRepository interface {
+	// Record counts one more search for query, made at at
+	Record(query string, at time.Time) error
+	// ListPopular returns up to limit queries searched at least minSearches
+	// times, most searched first
+	ListPopular(minSearches int64, limit int) ([]models.SearchQuery, error)
+}
+
+// SynonymRepository stores search synonyms
+type SynonymRepository interface {
+	// List returns all synonyms ordered by term
+	List() ([]models.SearchSynonym, error)
+	GetByID(id uint) (*models.SearchSynonym, error)
+	// Create inserts synonym, returning ErrDuplicate if its term already has
+	// the same synonym
+	Create(synonym *models.SearchSynonym) error
+	// Update saves synonym, returning ErrDuplicate if another record already
+	// pairs its term and synonym
+	Update(synonym *models.SearchSynonym) error
+	Delete(id uint) error
+}
+
+// AdminUserRepository stores admin accounts
+type AdminUserRepository interface {
+	GetByID(id uint) (*models.AdminUser, error)
+	GetByEmail(email string) (*models.AdminUser, error)
+}
+
+// Repositories is the set of repositories bound to one transaction
+type Repositories struct {
+	Vehicles     VehicleRepository
+	Brands       BrandRepository
+	Bookings     BookingRepository
+	Reservations ReservationRepository
+	Events       OutboxRepository
+	Webhooks     WebhookRepository
+}
+
+// Transactor runs work atomically
+type Transactor interface {
+	// WithinTransaction calls fn with repositories bound to a single
+	// transaction, committing if fn returns nil and rolling back otherwise
+	WithinTransaction(fn func(repos Repositories) error) error
+}
@@ -0,0 +1,1106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"vehicle-store-backend/internal/database"
+	"vehicle-store-backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// translateError maps GORM errors onto repository errors
+func translateError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// saveVersioned saves every column of record, like Save, but only if its row
+// is still at *version, which it then increments. A row at another version
+// gives ErrVersionConflict and leaves *version unchanged.
+func saveVersioned(db *gorm.DB, record interface{}, version *uint) error {
+	expected := *version
+	*version = expected + 1
+
+	result := db.Model(record).Where("version = ?", expected).
+		Select("*").Omit(clause.Associations).Updates(record)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrVersionConflict
+	}
+	if result.Error != nil {
+		*version = expected
+	}
+	return result.Error
+}
+
+// gormTransactor is the GORM-backed Transactor
+type gormTransactor struct {
+	db *gorm.DB
+}
+
+// NewGormTransactor returns a Transactor running transactions on db
+func NewGormTransactor(db *gorm.DB) Transactor {
+	return &gormTransactor{db: db}
+}
+
+func (t *gormTransactor) WithinTransaction(fn func(repos Repositories) error) error {
+	return t.db.Transaction(func(tx *gorm.DB) error {
+		return fn(Repositories{
+			Vehicles:     NewGormVehicleRepository(tx),
+			Brands:       NewGormBrandRepository(tx),
+			Bookings:     NewGormBookingRepository(tx),
+			Reservations: NewGormReservationRepository(tx),
+			Events:       NewGormOutboxRepository(tx),
+			Webhooks:     NewGormWebhookRepository(tx),
+		})
+	})
+}
+
+// gormVehicleRepository is the GORM-backed VehicleRepository
+type gormVehicleRepository struct {
+	db      *gorm.DB
+	dialect database.Dialect
+
+	fullTextOnce sync.Once
+	fullText     bool
+}
+
+// NewGormVehicleRepository returns a VehicleRepository backed by db
+func NewGormVehicleRepository(db *gorm.DB) VehicleRepository {
+	return &gormVehicleRepository{db: db, dialect: database.DialectFor(db)}
+}
+
+// hasFullText reports whether searches can use the full-text index. It is
+// checked on first use so transactions that never search do not pay for it.
+func (r *gormVehicleRepository) hasFullText() bool {
+	r.fullTextOnce.Do(func() {
+		r.fullText = database.HasVehicleSearch(r.db)
+	})
+	return r.fullText
+}
+
+// applyFilter adds the WHERE clauses for filter to query
+func (r *gormVehicleRepository) applyFilter(query *gorm.DB, filter models.VehicleFilter) *gorm.DB {
+	if filter.BrandID > 0 {
+		query = query.Where("vehicles.brand_id = ?", filter.BrandID)
+	}
+
+	if filter.FuelType != "" {
+		query = query.Where("vehicles.fuel_type = ?", filter.FuelType)
+	}
+
+	if filter.Transmission != "" {
+		query = query.Where("vehicles.transmission = ?", filter.Transmission)
+	}
+
+	if filter.Year > 0 {
+		query = query.Where("vehicles.year = ?", filter.Year)
+	}
+
+	if filter.Color != "" {
+		query = query.Where("vehicles.exterior_color = ?", filter.Color)
+	}
+
+	if filter.MinPrice > 0 {
+		query = query.Where("vehicles.price >= ?", filter.MinPrice)
+	}
+
+	if filter.MaxPrice > 0 {
+		query = query.Where("vehicles.price <= ?", filter.MaxPrice)
+	}
+
+	if groups := searchGroups(filter); len(groups) > 0 {
+		query = r.applySearch(query, groups)
+	}
+
+	// Only show available vehicles
+	return query.Where("vehicles.availability = ?", true)
+}
+
+// applySearch restricts query to vehicles matching every group. The
+// full-text index matches word prefixes; without it each word of one of a
+// group's phrases must appear somewhere in the indexed columns.
+func (r *gormVehicleRepository) applySearch(query *gorm.DB, groups []searchGroup) *gorm.DB {
+	if r.hasFullText() {
+		table := database.VehicleSearchTable
+		return query.Joins("JOIN "+table+" ON "+table+".rowid = vehicles.id").
+			Where(table+" MATCH ?", ftsQuery(groups))
+	}
+
+	columns := []string{
+		"vehicles.name", "vehicles.model", "brands.name",
+		"vehicles.description", "vehicles.engine_specs", "vehicles.safety_features",
+		"vehicles.fuel_type", "vehicles.transmission",
+	}
+	query = query.Joins("JOIN brands ON brands.id = vehicles.brand_id")
+	for _, group := range groups {
+		var phrases []string
+		var args []interface{}
+		for _, phrase := range group {
+			words := make([]string, len(phrase))
+			for i, word := range phrase {
+				conditions := make([]string, len(columns))
+				for j, column := range columns {
+					conditions[j] = r.dialect.ContainsExpr(column)
+					args = append(args, database.ContainsPattern(word))
+				}
+				words[i] = "(" + strings.Join(conditions, " OR ") + ")"
+			}
+			phrases = append(phrases, "("+strings.Join(words, " AND ")+")")
+		}
+		query = query.Where("("+strings.Join(phrases, " OR ")+")", args...)
+	}
+	return query
+}
+
+// List returns the matching vehicles. Searches are ordered by relevance when
+// the full-text index is available, and each result carries a snippet.
+func (r *gormVehicleRepository) List(filter models.VehicleFilter) ([]models.Vehicle, int64, error) {
+	groups := searchGroups(filter)
+	fullText := len(groups) > 0 && r.hasFullText()
+
+	query := r.applyFilter(r.db.Preload("Brand"), filter)
+	switch {
+	case len(filter.Sort) > 0:
+		for _, key := range filter.Sort {
+			column, ok := sortColumns[key.Field]
+			if !ok {
+				return nil, 0, fmt.Errorf("unknown sort field %q", key.Field)
+			}
+			if key.Desc {
+				column += " DESC"
+			}
+			query = query.Order(column)
+		}
+	case fullText:
+		query = query.Order(fmt.Sprintf("bm25(%s, %s)", database.VehicleSearchTable, joinWeights(vehicleSearchWeights)))
+	}
+	// Ties are broken by ID so pages do not overlap or skip vehicles
+	query = query.Order("vehicles.id")
+
+	var vehicles []models.Vehicle
+	if err := query.Limit(filter.Limit).Offset(filter.Offset).Find(&vehicles).Error; err != nil {
+		return nil, 0, err
+	}
+
+	switch {
+	case fullText:
+		if err := r.setSnippets(vehicles, groups); err != nil {
+			return nil, 0, err
+		}
+	case len(groups) > 0:
+		setSearchSnippets(vehicles, groups)
+	}
+
+	var total int64
+	countQuery := r.applyFilter(r.db.Model(&models.Vehicle{}), filter)
+	if err := countQuery.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return vehicles, total, nil
+}
+
+// sortColumns are the expressions each sort field orders by. Only these are
+// ever put into ORDER BY, never text from the request.
+var sortColumns = map[string]string{
+	SortPrice:     "vehicles.price",
+	SortYear:      "vehicles.year",
+	SortMileage:   "vehicles.mileage",
+	SortCreatedAt: "vehicles.created_at",
+	SortName:      "vehicles.name",
+	SortPopularity: "(SELECT COUNT(*) FROM bookings" +
+		" WHERE bookings.vehicle_id = vehicles.id AND NOT bookings.suspected_spam)",
+}
+
+// facetColumns are the columns each facet groups by
+var facetColumns = map[VehicleFacet]string{
+	FacetBrand:        "vehicles.brand_id",
+	FacetFuelType:     "vehicles.fuel_type",
+	FacetTransmission: "vehicles.transmission",
+	FacetYear:         "vehicles.year",
+	FacetColor:        "vehicles.exterior_color",
+}
+
+func (r *gormVehicleRepository) CountBy(filter models.VehicleFilter, facet VehicleFacet) ([]models.FacetCount, error) {
+	column, ok := facetColumns[facet]
+	if !ok {
+		return nil, fmt.Errorf("unknown facet %q", facet)
+	}
+
+	query := r.applyFilter(r.db.Model(&models.Vehicle{}), filter)
+	switch facet {
+	case FacetBrand:
+		// Aliased as a text search may already have joined brands
+		query = query.Joins("JOIN brands AS facet_brands ON facet_brands.id = vehicles.brand_id").
+			Select(column + " AS facet_value, facet_brands.name AS facet_label, COUNT(*) AS facet_count").
+			Group(column + ", facet_brands.name")
+	case FacetYear:
+		query = query.Select(column + " AS facet_value, COUNT(*) AS facet_count").Group(column)
+	default:
+		query = query.Where(column+" <> ?", "").
+			Select(column + " AS facet_value, COUNT(*) AS facet_count").
+			Group(column)
+	}
+
+	var rows []struct {
+		FacetValue string
+		FacetLabel string
+		FacetCount int64
+	}
+	if err := query.Order("facet_count DESC").Order("facet_value ASC").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make([]models.FacetCount, len(rows))
+	for i, row := range rows {
+		counts[i] = models.FacetCount{Value: row.FacetValue, Label: row.FacetLabel, Count: row.FacetCount}
+	}
+	return counts, nil
+}
+
+func (r *gormVehicleRepository) CountByPrice(filter models.VehicleFilter, limits []float64) ([]int64, error) {
+	// Number each vehicle's range and count per number
+	var bucket strings.Builder
+	args := make([]interface{}, len(limits))
+	bucket.WriteString("CASE")
+	for i, limit := range limits {
+		fmt.Fprintf(&bucket, " WHEN vehicles.price < ? THEN %d", i)
+		args[i] = limit
+	}
+	fmt.Fprintf(&bucket, " ELSE %d END", len(limits))
+
+	var rows []struct {
+		PriceBucket int
+		PriceCount  int64
+	}
+	err := r.applyFilter(r.db.Model(&models.Vehicle{}), filter).
+		Select(bucket.String()+" AS price_bucket, COUNT(*) AS price_count", args...).
+		Group("price_bucket").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]int64, len(limits)+1)
+	for _, row := range rows {
+		counts[row.PriceBucket] = row.PriceCount
+	}
+	return counts, nil
+}
+
+// setSnippets fills in each vehicle's snippet from the full-text index
+func (r *gormVehicleRepository) setSnippets(vehicles []models.Vehicle, groups []searchGroup) error {
+	if len(vehicles) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(vehicles))
+	for i, v := range vehicles {
+		ids[i] = v.ID
+	}
+
+	var rows []struct {
+		ID      uint
+		Snippet string
+	}
+	table := database.VehicleSearchTable
+	err := r.db.Table(table).
+		Select(fmt.Sprintf("rowid AS id, snippet(%s, -1, ?, ?, ?, %d) AS snippet", table, snippetWords),
+			snippetOpenPlaceholder, snippetClosePlaceholder, snippetGap).
+		Where(table+" MATCH ? AND rowid IN ?", ftsQuery(groups), ids).
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	snippets := make(map[uint]string, len(rows))
+	for _, row := range rows {
+		snippets[row.ID] = markSnippet(row.Snippet)
+	}
+	for i := range vehicles {
+		vehicles[i].SearchSnippet = snippets[vehicles[i].ID]
+	}
+	return nil
+}
+
+func (r *gormVehicleRepository) GetByID(id uint) (*models.Vehicle, error) {
+	var vehicle models.Vehicle
+	if err := r.db.Preload("Brand").First(&vehicle, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &vehicle, nil
+}
+
+func (r *gormVehicleRepository) GetByIDForUpdate(id uint) (*models.Vehicle, error) {
+	var vehicle models.Vehicle
+	// The SQLite driver drops the locking clause; its transactions are opened
+	// with BEGIN IMMEDIATE instead (see openDialector)
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&vehicle, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &vehicle, nil
+}
+
+func (r *gormVehicleRepository) Create(vehicle *models.Vehicle) error {
+	vehicle.Version = 1
+	return r.db.Create(vehicle).Error
+}
+
+func (r *gormVehicleRepository) Update(vehicle *models.Vehicle) error {
+	return saveVersioned(r.db, vehicle, &vehicle.Version)
+}
+
+func (r *gormVehicleRepository) Delete(id uint) error {
+	result := r.db.Delete(&models.Vehicle{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormVehicleRepository) ListNames() ([]VehicleName, error) {
+	var names []VehicleName
+	err := r.db.Model(&models.Vehicle{}).
+		Select("brands.name AS brand, vehicles.name AS name, vehicles.model AS model, COUNT(*) AS count").
+		Joins("JOIN brands ON brands.id = vehicles.brand_id").
+		Where("vehicles.availability = ?", true).
+		Group("brands.name, vehicles.name, vehicles.model").
+		Order("brands.name, vehicles.name, vehicles.model").
+		Scan(&names).Error
+	return names, err
+}
+
+// gormBrandRepository is the GORM-backed BrandRepository
+type gormBrandRepository struct {
+	db *gorm.DB
+}
+
+// NewGormBrandRepository returns a BrandRepository backed by db
+func NewGormBrandRepository(db *gorm.DB) BrandRepository {
+	return &gormBrandRepository{db: db}
+}
+
+func (r *gormBrandRepository) List() ([]models.Brand, error) {
+	var brands []models.Brand
+	if err := r.db.Find(&brands).Error; err != nil {
+		return nil, err
+	}
+	return brands, nil
+}
+
+func (r *gormBrandRepository) GetByID(id uint) (*models.Brand, error) {
+	var brand models.Brand
+	if err := r.db.Preload("Vehicles").First(&brand, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &brand, nil
+}
+
+func (r *gormBrandRepository) GetByIDForUpdate(id uint) (*models.Brand, error) {
+	var brand models.Brand
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&brand, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &brand, nil
+}
+
+func (r *gormBrandRepository) Create(brand *models.Brand) error {
+	brand.Version = 1
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(brand)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDuplicate
+	}
+	return nil
+}
+
+func (r *gormBrandRepository) Update(brand *models.Brand) error {
+	var count int64
+	err := r.db.Model(&models.Brand{}).
+		Where("name = ? AND id <> ?", brand.Name, brand.ID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicate
+	}
+	return saveVersioned(r.db, brand, &brand.Version)
+}
+
+func (r *gormBrandRepository) Delete(id uint) error {
+	result := r.db.Delete(&models.Brand{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormBrandRepository) CountVehicles(id uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Vehicle{}).Where("brand_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+// gormBookingRepository is the GORM-backed BookingRepository
+type gormBookingRepository struct {
+	db *gorm.DB
+}
+
+// NewGormBookingRepository returns a BookingRepository backed by db
+func NewGormBookingRepository(db *gorm.DB) BookingRepository {
+	return &gormBookingRepository{db: db}
+}
+
+func (r *gormBookingRepository) List(filter models.BookingFilter) ([]models.Booking, error) {
+	var bookings []models.Booking
+	query := r.db.Preload("Vehicle.Brand")
+
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	if filter.SuspectedSpam != nil {
+		query = query.Where("suspected_spam = ?", *filter.SuspectedSpam)
+	}
+
+	if err := query.Order("created_at DESC").Find(&bookings).Error; err != nil {
+		return nil, err
+	}
+	return bookings, nil
+}
+
+func (r *gormBookingRepository) GetByID(id uint) (*models.Booking, error) {
+	var booking models.Booking
+	if err := r.db.Preload("Vehicle.Brand").First(&booking, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &booking, nil
+}
+
+func (r *gormBookingRepository) GetByIDForUpdate(id uint) (*models.Booking, error) {
+	var booking models.Booking
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &booking, nil
+}
+
+func (r *gormBookingRepository) Create(booking *models.Booking) error {
+	booking.Version = 1
+	return r.db.Create(booking).Error
+}
+
+func (r *gormBookingRepository) Update(booking *models.Booking) error {
+	return saveVersioned(r.db, booking, &booking.Version)
+}
+
+func (r *gormBookingRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("booking_id = ?", id).Delete(&models.BookingStatusHistory{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Delete(&models.Booking{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r *gormBookingRepository) AddStatusHistory(entry *models.BookingStatusHistory) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *gormBookingRepository) StatusHistory(bookingID uint) ([]models.BookingStatusHistory, error) {
+	var history []models.BookingStatusHistory
+	if err := r.db.Where("booking_id = ?", bookingID).Order("created_at ASC, id ASC").Find(&history).Error; err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func (r *gormBookingRepository) ListTestDrives(vehicleID uint, from, to time.Time, statuses []string) ([]models.Booking, error) {
+	var bookings []models.Booking
+	err := r.db.
+		Where("vehicle_id = ? AND status IN ? AND NOT suspected_spam", vehicleID, statuses).
+		Where("requested_start < ? AND requested_end > ?", to, from).
+		Order("requested_start ASC").
+		Find(&bookings).Error
+	if err != nil {
+		return nil, err
+	}
+	return bookings, nil
+}
+
+func (r *gormBookingRepository) ListRecentForVehicle(vehicleID uint, since time.Time) ([]models.Booking, error) {
+	var bookings []models.Booking
+	err := r.db.
+		Where("vehicle_id = ? AND created_at >= ?", vehicleID, since).
+		Order("created_at DESC").
+		Find(&bookings).Error
+	if err != nil {
+		return nil, err
+	}
+	return bookings, nil
+}
+
+// gormReservationRepository is the GORM-backed ReservationRepository
+type gormReservationRepository struct {
+	db *gorm.DB
+}
+
+// NewGormReservationRepository returns a ReservationRepository backed by db
+func NewGormReservationRepository(db *gorm.DB) ReservationRepository {
+	return &gormReservationRepository{db: db}
+}
+
+func (r *gormReservationRepository) GetByID(id uint) (*models.Reservation, error) {
+	var reservation models.Reservation
+	if err := r.db.First(&reservation, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &reservation, nil
+}
+
+func (r *gormReservationRepository) GetByIDForUpdate(id uint) (*models.Reservation, error) {
+	var reservation models.Reservation
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reservation, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &reservation, nil
+}
+
+func (r *gormReservationRepository) ListActive(now time.Time) ([]models.Reservation, error) {
+	var reservations []models.Reservation
+	err := r.db.Preload("Vehicle.Brand").
+		Where("released_at IS NULL AND expires_at > ?", now).
+		Order("expires_at ASC").
+		Find(&reservations).Error
+	if err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+func (r *gormReservationRepository) ListExpired(now time.Time) ([]models.Reservation, error) {
+	var reservations []models.Reservation
+	err := r.db.Where("released_at IS NULL AND expires_at <= ?", now).
+		Order("expires_at ASC").
+		Find(&reservations).Error
+	if err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+func (r *gormReservationRepository) ActiveForVehicle(vehicleID uint, now time.Time) (*models.Reservation, error) {
+	var reservation models.Reservation
+	err := r.db.Where("vehicle_id = ? AND released_at IS NULL AND expires_at > ?", vehicleID, now).
+		First(&reservation).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &reservation, nil
+}
+
+func (r *gormReservationRepository) Create(reservation *models.Reservation) error {
+	return r.db.Omit(clause.Associations).Create(reservation).Error
+}
+
+func (r *gormReservationRepository) Update(reservation *models.Reservation) error {
+	return r.db.Omit(clause.Associations).Save(reservation).Error
+}
+
+// gormOutboxRepository is the GORM-backed OutboxRepository
+type gormOutboxRepository struct {
+	db *gorm.DB
+}
+
+// NewGormOutboxRepository returns an OutboxRepository backed by db
+func NewGormOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &gormOutboxRepository{db: db}
+}
+
+func (r *gormOutboxRepository) Enqueue(event *models.OutboxEvent) error {
+	return r.db.Create(event).Error
+}
+
+func (r *gormOutboxRepository) ListUnprocessed(limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := r.db.Where("processed_at IS NULL").Order("id ASC").Limit(limit).Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *gormOutboxRepository) MarkProcessed(id uint, at time.Time) error {
+	return r.db.Model(&models.OutboxEvent{}).Where("id = ?", id).Update("processed_at", at).Error
+}
+
+// gormWebhookRepository is the GORM-backed WebhookRepository
+type gormWebhookRepository struct {
+	db *gorm.DB
+}
+
+// NewGormWebhookRepository returns a WebhookRepository backed by db
+func NewGormWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &gormWebhookRepository{db: db}
+}
+
+func (r *gormWebhookRepository) List() ([]models.WebhookSubscription, error) {
+	var subs []models.WebhookSubscription
+	if err := r.db.Order("id ASC").Find(&subs).Error; err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+func (r *gormWebhookRepository) ListActive() ([]models.WebhookSubscription, error) {
+	var subs []models.WebhookSubscription
+	if err := r.db.Where("active = ?", true).Order("id ASC").Find(&subs).Error; err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+func (r *gormWebhookRepository) GetByID(id uint) (*models.WebhookSubscription, error) {
+	var sub models.WebhookSubscription
+	if err := r.db.First(&sub, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &sub, nil
+}
+
+func (r *gormWebhookRepository) Create(sub *models.WebhookSubscription) error {
+	return r.db.Create(sub).Error
+}
+
+func (r *gormWebhookRepository) Update(sub *models.WebhookSubscription) error {
+	return r.db.Save(sub).Error
+}
+
+func (r *gormWebhookRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subscription_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Delete(&models.WebhookSubscription{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r *gormWebhookRepository) CreateDelivery(delivery *models.WebhookDelivery) error {
+	return r.db.Create(delivery).Error
+}
+
+func (r *gormWebhookRepository) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	return r.db.Save(delivery).Error
+}
+
+func (r *gormWebhookRepository) ListDueDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.Where("status = ? AND next_attempt_at <= ?", "pending", now).
+		Order("next_attempt_at ASC, id ASC").
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *gormWebhookRepository) ListDeliveries(subscriptionID uint, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.Where("subscription_id = ?", subscriptionID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// gormDealerHoursRepository is the GORM-backed DealerHoursRepository
+type gormDealerHoursRepository struct {
+	db *gorm.DB
+}
+
+// NewGormDealerHoursRepository returns a DealerHoursRepository backed by db
+func NewGormDealerHoursRepository(db *gorm.DB) DealerHoursRepository {
+	return &gormDealerHoursRepository{db: db}
+}
+
+func (r *gormDealerHoursRepository) Get(dealer string) ([]models.DealerHours, error) {
+	var hours []models.DealerHours
+	if err := r.db.Where("dealer = ?", dealer).Order("weekday ASC").Find(&hours).Error; err != nil {
+		return nil, err
+	}
+	return hours, nil
+}
+
+func (r *gormDealerHoursRepository) Replace(dealer string, hours []models.DealerHours) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("dealer = ?", dealer).Delete(&models.DealerHours{}).Error; err != nil {
+			return err
+		}
+		if len(hours) == 0 {
+			return nil
+		}
+		for i := range hours {
+			hours[i].ID = 0
+			hours[i].Dealer = dealer
+		}
+		return tx.Create(&hours).Error
+	})
+}
+
+// gormAnalyticsRepository is the GORM-backed AnalyticsRepository
+type gormAnalyticsRepository struct {
+	db      *gorm.DB
+	dialect database.Dialect
+}
+
+// NewGormAnalyticsRepository returns an AnalyticsRepository backed by db
+func NewGormAnalyticsRepository(db *gorm.DB) AnalyticsRepository {
+	return &gormAnalyticsRepository{db: db, dialect: database.DialectFor(db)}
+}
+
+func (r *gormAnalyticsRepository) Summary() (*models.Analytics, error) {
+	var analytics models.Analytics
+
+	// Get total vehicles count
+	if err := r.db.Model(&models.Vehicle{}).Count(&analytics.TotalVehicles).Error; err != nil {
+		return nil, err
+	}
+
+	// Get vehicles by brand
+	analytics.VehiclesByBrand = make(map[string]int64)
+	var brandStats []struct {
+		BrandName string
+		Count     int64
+	}
+
+	if err := r.db.Model(&models.Vehicle{}).
+		Select("brands.name as brand_name, COUNT(vehicles.id) as count").
+		Joins("JOIN brands ON brands.id = vehicles.brand_id").
+		Group("brands.name").
+		Scan(&brandStats).Error; err != nil {
+		return nil, err
+	}
+
+	for _, stat := range brandStats {
+		analytics.VehiclesByBrand[stat.BrandName] = stat.Count
+	}
+
+	// Get vehicles by fuel type
+	analytics.VehiclesByFuel = make(map[string]int64)
+	var fuelStats []struct {
+		FuelType string
+		Count    int64
+	}
+
+	if err := r.db.Model(&models.Vehicle{}).
+		Select("fuel_type, COUNT(*) as count").
+		Group("fuel_type").
+		Scan(&fuelStats).Error; err != nil {
+		return nil, err
+	}
+
+	for _, stat := range fuelStats {
+		analytics.VehiclesByFuel[stat.FuelType] = stat.Count
+	}
+
+	// Get total bookings count
+	if err := r.db.Model(&models.Booking{}).Count(&analytics.TotalBookings).Error; err != nil {
+		return nil, err
+	}
+
+	// Get bookings by status
+	analytics.BookingsByStatus = make(map[string]int64)
+	var bookingStats []struct {
+		Status string
+		Count  int64
+	}
+
+	if err := r.db.Model(&models.Booking{}).
+		Select("status, COUNT(*) as count").
+		Group("status").
+		Scan(&bookingStats).Error; err != nil {
+		return nil, err
+	}
+
+	for _, stat := range bookingStats {
+		analytics.BookingsByStatus[stat.Status] = stat.Count
+	}
+
+	// Get average price and price range
+	var prices struct {
+		Average  float64
+		MinPrice float64
+		MaxPrice float64
+	}
+	if err := r.db.Model(&models.Vehicle{}).
+		Select("COALESCE(AVG(price), 0) as average, COALESCE(MIN(price), 0) as min_price, COALESCE(MAX(price), 0) as max_price").
+		Scan(&prices).Error; err != nil {
+		return nil, err
+	}
+
+	analytics.AveragePrice = prices.Average
+	analytics.PriceRange = map[string]float64{
+		"min": prices.MinPrice,
+		"max": prices.MaxPrice,
+	}
+
+	return &analytics, nil
+}
+
+func (r *gormAnalyticsRepository) PopularVehicles(limit int) ([]models.PopularVehicle, error) {
+	var popularVehicles []models.PopularVehicle
+
+	err := r.db.Model(&models.Booking{}).
+		Select("vehicles.id as vehicle_id, vehicles.name as vehicle_name, brands.name as brand_name, COUNT(bookings.id) as booking_count, vehicles.price").
+		Joins("JOIN vehicles ON vehicles.id = bookings.vehicle_id").
+		Joins("JOIN brands ON brands.id = vehicles.brand_id").
+		Where("NOT bookings.suspected_spam").
+		Group("vehicles.id, vehicles.name, brands.name, vehicles.price").
+		Order("booking_count DESC").
+		Limit(limit).
+		Scan(&popularVehicles).Error
+
+	return popularVehicles, err
+}
+
+func (r *gormAnalyticsRepository) BookingTrends(days int) ([]models.BookingTrend, error) {
+	var trends []models.BookingTrend
+	date := r.dialect.DateExpr("created_at")
+
+	err := r.db.Model(&models.Booking{}).
+		Select(date + " as date, COUNT(*) as count").
+		Group(date).
+		Order("date DESC").
+		Limit(days).
+		Scan(&trends).Error
+
+	return trends, err
+}
+
+func (r *gormAnalyticsRepository) InventoryStatus() (*models.InventoryStatus, error) {
+	var status models.InventoryStatus
+
+	if err := r.db.Model(&models.Vehicle{}).Where("availability = ?", true).Count(&status.AvailableVehicles).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Model(&models.Vehicle{}).Where("availability = ?", false).Count(&status.UnavailableVehicles).Error; err != nil {
+		return nil, err
+	}
+
+	// Get low stock alerts (vehicles with high booking demand)
+	if err := r.db.Model(&models.Booking{}).
+		Select("vehicles.id as vehicle_id, vehicles.name as vehicle_name, brands.name as brand_name, COUNT(bookings.id) as booking_count").
+		Joins("JOIN vehicles ON vehicles.id = bookings.vehicle_id").
+		Joins("JOIN brands ON brands.id = vehicles.brand_id").
+		Where("bookings.status IN (?)", []string{"pending", "contacted"}).
+		Group("vehicles.id, vehicles.name, brands.name").
+		Having("COUNT(bookings.id) > 2").
+		Order("booking_count DESC").
+		Scan(&status.HighDemandVehicles).Error; err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// gormIdempotencyRepository is the GORM-backed IdempotencyRepository
+type gormIdempotencyRepository struct {
+	db *gorm.DB
+}
+
+// NewGormIdempotencyRepository returns an IdempotencyRepository backed by db
+func NewGormIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &gormIdempotencyRepository{db: db}
+}
+
+func (r *gormIdempotencyRepository) Get(scope, key string) (*models.IdempotencyRecord, error) {
+	var record models.IdempotencyRecord
+	if err := r.db.Where(&models.IdempotencyRecord{Scope: scope, Key: key}).First(&record).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &record, nil
+}
+
+func (r *gormIdempotencyRepository) Reserve(record *models.IdempotencyRecord) error {
+	// The unique index on scope and key makes concurrent reservations race
+	// safely: exactly one insert goes through
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDuplicate
+	}
+	return nil
+}
+
+func (r *gormIdempotencyRepository) Update(record *models.IdempotencyRecord) error {
+	return r.db.Save(record).Error
+}
+
+func (r *gormIdempotencyRepository) Delete(id uint) error {
+	return r.db.Delete(&models.IdempotencyRecord{}, id).Error
+}
+
+func (r *gormIdempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= ?", now).Delete(&models.IdempotencyRecord{})
+	return result.RowsAffected, result.Error
+}
+
+// gormSearchQueryRepository is the GORM-backed SearchQueryRepository
+type gormSearchQueryRepository struct {
+	db *gorm.DB
+}
+
+// NewGormSearchQueryRepository returns a SearchQueryRepository backed by db
+func NewGormSearchQueryRepository(db *gorm.DB) SearchQueryRepository {
+	return &gormSearchQueryRepository{db: db}
+}
+
+func (r *gormSearchQueryRepository) Record(query string, at time.Time) error {
+	// The unique index on query turns a repeated search into an increment
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "query"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"searches":         gorm.Expr("search_queries.searches + 1"),
+			"last_searched_at": at,
+		}),
+	}).Create(&models.SearchQuery{Query: query, Searches: 1, LastSearchedAt: at}).Error
+}
+
+func (r *gormSearchQueryRepository) ListPopular(minSearches int64, limit int) ([]models.SearchQuery, error) {
+	var queries []models.SearchQuery
+	err := r.db.Where("searches >= ?", minSearches).
+		Order("searches DESC").Order("query ASC").
+		Limit(limit).
+		Find(&queries).Error
+	if err != nil {
+		return nil, err
+	}
+	return queries, nil
+}
+
+// gormSynonymRepository is the GORM-backed SynonymRepository
+type gormSynonymRepository struct {
+	db *gorm.DB
+}
+
+// NewGormSynonymRepository returns a SynonymRepository backed by db
+func NewGormSynonymRepository(db *gorm.DB) SynonymRepository {
+	return &gormSynonymRepository{db: db}
+}
+
+func (r *gormSynonymRepository) List() ([]models.SearchSynonym, error) {
+	var synonyms []models.SearchSynonym
+	if err := r.db.Order("term ASC").Order("synonym ASC").Find(&synonyms).Error; err != nil {
+		return nil, err
+	}
+	return synonyms, nil
+}
+
+func (r *gormSynonymRepository) GetByID(id uint) (*models.SearchSynonym, error) {
+	var synonym models.SearchSynonym
+	if err := r.db.First(&synonym, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &synonym, nil
+}
+
+func (r *gormSynonymRepository) Create(synonym *models.SearchSynonym) error {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(synonym)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDuplicate
+	}
+	return nil
+}
+
+func (r *gormSynonymRepository) Update(synonym *models.SearchSynonym) error {
+	var count int64
+	err := r.db.Model(&models.SearchSynonym{}).
+		Where("term = ? AND synonym = ? AND id <> ?", synonym.Term, synonym.Synonym, synonym.ID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicate
+	}
+	return r.db.Save(synonym).Error
+}
+
+func (r *gormSynonymRepository) Delete(id uint) error {
+	result := r.db.Delete(&models.SearchSynonym{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// gormAdminUserRepository is the GORM-backed AdminUserRepository
+type gormAdminUserRepository struct {
+	db *gorm.DB
+}
+
+// NewGormAdminUserRepository returns an AdminUserRepository backed by db
+func NewGormAdminUserRepository(db *gorm.DB) AdminUserRepository {
+	return &gormAdminUserRepository{db: db}
+}
+
+func (r *gormAdminUserRepository) GetByID(id uint) (*models.AdminUser, error) {
+	var admin models.AdminUser
+	if err := r.db.First(&admin, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &admin, nil
+}
+
+func (r *gormAdminUserRepository) GetByEmail(email string) (*models.AdminUser, error) {
+	var admin models.AdminUser
+	if err := r.db.Where("email = ?", email).First(&admin).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &admin, nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"vehicle-store-backend/internal/database"
+	"vehicle-store-backend/internal/models"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB returns a SQLite database in a temporary directory with every
+// migration applied. The migrations need FTS5, so without -tags sqlite_fts5
+// the test is skipped.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db") + "?_txlock=immediate"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	var fts5 bool
+	if err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !fts5 {
+		t.Skip("SQLite lacks FTS5; run with -tags sqlite_fts5 (make test)")
+	}
+
+	if _, err := database.MigrateUp(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// listSQL returns the query List builds for filter on driver, without
+// connecting to a database
+func listSQL(t *testing.T, driver string, filter models.VehicleFilter) (string, error) {
+	t.Helper()
+
+	var dialector gorm.Dialector
+	switch driver {
+	case "sqlite":
+		dialector = sqlite.Open("file::memory:")
+	case "postgres":
+		dialector = postgres.Open("host=localhost dbname=vehicles")
+	case "mysql":
+		dialector = mysql.New(mysql.Config{DSN: "user@tcp(localhost)/vehicles", SkipInitializeWithVersion: true})
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("open %s: %v", driver, err)
+	}
+
+	// List runs the page query first, then the count
+	var queries []string
+	err = db.Callback().Query().After("gorm:query").Register("test:record_sql", func(tx *gorm.DB) {
+		queries = append(queries, tx.Statement.SQL.String())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := NewGormVehicleRepository(db).List(filter); err != nil {
+		return "", err
+	}
+	if len(queries) == 0 {
+		t.Fatal("List ran no query")
+	}
+	return queries[0], nil
+}
+
+const popularitySQL = "(SELECT COUNT(*) FROM bookings WHERE bookings.vehicle_id = vehicles.id AND NOT bookings.suspected_spam)"
+
+func TestVehicleListSQL(t *testing.T) {
+	filter := models.VehicleFilter{
+		Limit:  12,
+		Offset: 24,
+		Sort:   []models.VehicleSort{{Field: SortPrice, Desc: true}, {Field: SortPopularity}},
+	}
+	order := " ORDER BY vehicles.price DESC," + popularitySQL + ",vehicles.id"
+
+	tests := []struct {
+		driver string
+		want   string
+	}{
+		{"sqlite", "SELECT * FROM `vehicles` WHERE vehicles.availability = ?" + order + " LIMIT 12 OFFSET 24"},
+		{"postgres", `SELECT * FROM "vehicles" WHERE vehicles.availability = $1` + order + " LIMIT $2 OFFSET $3"},
+		{"mysql", "SELECT * FROM `vehicles` WHERE vehicles.availability = ?" + order + " LIMIT ? OFFSET ?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			got, err := listSQL(t, tt.driver, filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("SQL =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVehicleListOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		sort []models.VehicleSort
+		want string
+	}{
+		{"default", nil, "ORDER BY vehicles.id"},
+		{"price", []models.VehicleSort{{Field: SortPrice}}, "ORDER BY vehicles.price,vehicles.id"},
+		{"year", []models.VehicleSort{{Field: SortYear, Desc: true}}, "ORDER BY vehicles.year DESC,vehicles.id"},
+		{"mileage", []models.VehicleSort{{Field: SortMileage}}, "ORDER BY vehicles.mileage,vehicles.id"},
+		{"created_at", []models.VehicleSort{{Field: SortCreatedAt, Desc: true}}, "ORDER BY vehicles.created_at DESC,vehicles.id"},
+		{"name", []models.VehicleSort{{Field: SortName}}, "ORDER BY vehicles.name,vehicles.id"},
+		{"popularity", []models.VehicleSort{{Field: SortPopularity, Desc: true}}, "ORDER BY " + popularitySQL + " DESC,vehicles.id"},
+		{
+			"several keys",
+			[]models.VehicleSort{{Field: SortYear, Desc: true}, {Field: SortPrice}, {Field: SortName}},
+			"ORDER BY vehicles.year DESC,vehicles.price,vehicles.name,vehicles.id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := listSQL(t, "sqlite", models.VehicleFilter{Limit: 12, Sort: tt.sort})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := " " + tt.want + " LIMIT 12"; !strings.HasSuffix(got, want) {
+				t.Errorf("SQL %q does not end with %q", got, want)
+			}
+		})
+	}
+}
+
+func TestVehicleListRejectsUnknownSort(t *testing.T) {
+	filter := models.VehicleFilter{Sort: []models.VehicleSort{{Field: "price; DROP TABLE vehicles"}}}
+	if _, err := listSQL(t, "sqlite", filter); err == nil {
+		t.Error("List accepted an unknown sort field")
+	}
+}
@@ -0,0 +1,1417 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"vehicle-store-backend/internal/models"
+)
+
+// memoryStore holds the records shared by the in-memory repositories.
+// It is intended for tests and local experiments, not production use.
+type memoryStore struct {
+	// txMu serializes transactions; mu guards the maps for each operation
+	txMu     sync.Mutex
+	mu       sync.RWMutex
+	nextID   uint
+	brands   map[uint]models.Brand
+	vehicles map[uint]models.Vehicle
+	bookings map[uint]models.Booking
+	history  map[uint]models.BookingStatusHistory
+	holds    map[uint]models.Reservation
+	events   map[uint]models.OutboxEvent
+	webhooks map[uint]models.WebhookSubscription
+	sends    map[uint]models.WebhookDelivery
+	hours    map[string][]models.DealerHours
+	admins   map[uint]models.AdminUser
+	replays  map[uint]models.IdempotencyRecord
+	synonyms map[uint]models.SearchSynonym
+	queries  map[string]models.SearchQuery
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		brands:   make(map[uint]models.Brand),
+		vehicles: make(map[uint]models.Vehicle),
+		bookings: make(map[uint]models.Booking),
+		history:  make(map[uint]models.BookingStatusHistory),
+		holds:    make(map[uint]models.Reservation),
+		events:   make(map[uint]models.OutboxEvent),
+		webhooks: make(map[uint]models.WebhookSubscription),
+		sends:    make(map[uint]models.WebhookDelivery),
+		hours:    make(map[string][]models.DealerHours),
+		admins:   make(map[uint]models.AdminUser),
+		replays:  make(map[uint]models.IdempotencyRecord),
+		synonyms: make(map[uint]models.SearchSynonym),
+		queries:  make(map[string]models.SearchQuery),
+	}
+}
+
+// newID returns the next record ID; callers must hold the write lock
+func (s *memoryStore) newID() uint {
+	s.nextID++
+	return s.nextID
+}
+
+// vehicleWithBrand returns v with its Brand populated; callers must hold a lock
+func (s *memoryStore) vehicleWithBrand(v models.Vehicle) models.Vehicle {
+	v.Brand = s.brands[v.BrandID]
+	v.Bookings = nil
+	return v
+}
+
+// bookingWithVehicle returns b with its Vehicle and Brand populated; callers must hold a lock
+func (s *memoryStore) bookingWithVehicle(b models.Booking) models.Booking {
+	b.Vehicle = s.vehicleWithBrand(s.vehicles[b.VehicleID])
+	return b
+}
+
+// sortedIDs returns the keys of m in ascending order
+func sortedIDs[T any](m map[uint]T) []uint {
+	ids := make([]uint, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// cloneMap returns a shallow copy of m
+func cloneMap[T any](m map[uint]T) map[uint]T {
+	clone := make(map[uint]T, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+	return clone
+}
+
+// MemoryRepositories bundles in-memory repositories sharing one store
+type MemoryRepositories struct {
+	Vehicles  VehicleRepository
+	Brands    BrandRepository
+	Bookings  BookingRepository
+	Analytics AnalyticsRepository
+	Admins    *MemoryAdminUserRepository
+	Holds     ReservationRepository
+	Events    OutboxRepository
+	Webhooks  WebhookRepository
+	Hours     DealerHoursRepository
+	Replays   IdempotencyRepository
+	Synonyms  SynonymRepository
+	Queries   SearchQueryRepository
+	Tx        Transactor
+}
+
+// NewMemoryRepositories returns empty in-memory repositories
+func NewMemoryRepositories() *MemoryRepositories {
+	store := newMemoryStore()
+	return &MemoryRepositories{
+		Vehicles:  &memoryVehicleRepository{store: store},
+		Brands:    &memoryBrandRepository{store: store},
+		Bookings:  &memoryBookingRepository{store: store},
+		Analytics: &memoryAnalyticsRepository{store: store},
+		Admins:    &MemoryAdminUserRepository{store: store},
+		Holds:     &memoryReservationRepository{store: store},
+		Events:    &memoryOutboxRepository{store: store},
+		Webhooks:  &memoryWebhookRepository{store: store},
+		Hours:     &memoryDealerHoursRepository{store: store},
+		Replays:   &memoryIdempotencyRepository{store: store},
+		Synonyms:  &memorySynonymRepository{store: store},
+		Queries:   &memorySearchQueryRepository{store: store},
+		Tx:        &memoryTransactor{store: store},
+	}
+}
+
+// memoryTransactor is the in-memory Transactor. Transactions run one at a
+// time and a failed transaction restores the records it started with.
+type memoryTransactor struct {
+	store *memoryStore
+}
+
+func (t *memoryTransactor) WithinTransaction(fn func(repos Repositories) error) error {
+	t.store.txMu.Lock()
+	defer t.store.txMu.Unlock()
+
+	t.store.mu.RLock()
+	brands, vehicles, bookings := cloneMap(t.store.brands), cloneMap(t.store.vehicles), cloneMap(t.store.bookings)
+	history, holds := cloneMap(t.store.history), cloneMap(t.store.holds)
+	events, webhooks, sends := cloneMap(t.store.events), cloneMap(t.store.webhooks), cloneMap(t.store.sends)
+	t.store.mu.RUnlock()
+
+	err := fn(Repositories{
+		Vehicles:     &memoryVehicleRepository{store: t.store},
+		Brands:       &memoryBrandRepository{store: t.store},
+		Bookings:     &memoryBookingRepository{store: t.store},
+		Reservations: &memoryReservationRepository{store: t.store},
+		Events:       &memoryOutboxRepository{store: t.store},
+		Webhooks:     &memoryWebhookRepository{store: t.store},
+	})
+	if err != nil {
+		t.store.mu.Lock()
+		t.store.brands, t.store.vehicles, t.store.bookings = brands, vehicles, bookings
+		t.store.history, t.store.holds = history, holds
+		t.store.events, t.store.webhooks, t.store.sends = events, webhooks, sends
+		t.store.mu.Unlock()
+	}
+	return err
+}
+
+// memoryVehicleRepository is the in-memory VehicleRepository
+type memoryVehicleRepository struct {
+	store *memoryStore
+}
+
+// matchesVehicleFilter mirrors applyFilter apart from the search, which List
+// handles; callers must hold a lock
+func (s *memoryStore) matchesVehicleFilter(v models.Vehicle, filter models.VehicleFilter) bool {
+	if !v.Availability {
+		return false
+	}
+	if filter.BrandID > 0 && v.BrandID != filter.BrandID {
+		return false
+	}
+	if filter.FuelType != "" && v.FuelType != filter.FuelType {
+		return false
+	}
+	if filter.Transmission != "" && v.Transmission != filter.Transmission {
+		return false
+	}
+	if filter.Year > 0 && v.Year != filter.Year {
+		return false
+	}
+	if filter.Color != "" && v.ExteriorColor != filter.Color {
+		return false
+	}
+	if filter.MinPrice > 0 && v.Price < filter.MinPrice {
+		return false
+	}
+	if filter.MaxPrice > 0 && v.Price > filter.MaxPrice {
+		return false
+	}
+	return true
+}
+
+// searchVehicles returns the vehicles matching filter, with their brands,
+// snippets and search scores, in ID order; callers must hold a lock. It
+// mirrors the full-text search: every search word, or one of its
+// alternatives, must start a word in the indexed fields, and matches are
+// scored with the same field weights.
+func (s *memoryStore) searchVehicles(filter models.VehicleFilter) ([]models.Vehicle, map[uint]float64) {
+	groups := searchGroups(filter)
+	words := searchGroupWords(groups)
+	var matched []models.Vehicle
+	scores := make(map[uint]float64)
+	for _, id := range sortedIDs(s.vehicles) {
+		v := s.vehicles[id]
+		if !s.matchesVehicleFilter(v, filter) {
+			continue
+		}
+
+		v = s.vehicleWithBrand(v)
+		if len(groups) > 0 {
+			fields := vehicleSearchFields(v)
+			score, best, ok := scoreVehicleSearch(fields, groups)
+			if !ok {
+				continue
+			}
+			scores[v.ID] = score
+			v.SearchSnippet = searchSnippet(fields[best], words)
+		}
+		matched = append(matched, v)
+	}
+	return matched, scores
+}
+
+// compareVehicles orders a and b by field, like sortColumns; callers must
+// hold a lock
+func (s *memoryStore) compareVehicles(a, b models.Vehicle, field string, bookings map[uint]int) (int, error) {
+	switch field {
+	case SortPrice:
+		return cmp.Compare(a.Price, b.Price), nil
+	case SortYear:
+		return cmp.Compare(a.Year, b.Year), nil
+	case SortMileage:
+		return cmp.Compare(a.Mileage, b.Mileage), nil
+	case SortCreatedAt:
+		return a.CreatedAt.Compare(b.CreatedAt), nil
+	case SortName:
+		return strings.Compare(a.Name, b.Name), nil
+	case SortPopularity:
+		return cmp.Compare(bookings[a.ID], bookings[b.ID]), nil
+	}
+	return 0, fmt.Errorf("unknown sort field %q", field)
+}
+
+// List ranks searches by score like the full-text index does, unless sort
+// keys are given. Matches come in ID order, so ties stay in ID order.
+func (r *memoryVehicleRepository) List(filter models.VehicleFilter) ([]models.Vehicle, int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	matched, scores := r.store.searchVehicles(filter)
+	switch {
+	case len(filter.Sort) > 0:
+		bookings := make(map[uint]int)
+		for _, b := range r.store.bookings {
+			if !b.SuspectedSpam {
+				bookings[b.VehicleID]++
+			}
+		}
+
+		var sortErr error
+		sort.SliceStable(matched, func(i, j int) bool {
+			for _, key := range filter.Sort {
+				c, err := r.store.compareVehicles(matched[i], matched[j], key.Field, bookings)
+				if err != nil {
+					sortErr = err
+					return false
+				}
+				if key.Desc {
+					c = -c
+				}
+				if c != 0 {
+					return c < 0
+				}
+			}
+			return false
+		})
+		if sortErr != nil {
+			return nil, 0, sortErr
+		}
+	case filter.Search != "":
+		sort.SliceStable(matched, func(i, j int) bool {
+			return scores[matched[i].ID] > scores[matched[j].ID]
+		})
+	}
+
+	total := int64(len(matched))
+	start := min(max(filter.Offset, 0), len(matched))
+	end := len(matched)
+	if filter.Limit > 0 {
+		end = min(start+filter.Limit, len(matched))
+	}
+
+	return matched[start:end], total, nil
+}
+
+func (r *memoryVehicleRepository) CountBy(filter models.VehicleFilter, facet VehicleFacet) ([]models.FacetCount, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	matched, _ := r.store.searchVehicles(filter)
+	var counts []models.FacetCount
+	index := make(map[string]int)
+	for _, v := range matched {
+		var value, label string
+		switch facet {
+		case FacetBrand:
+			value, label = strconv.FormatUint(uint64(v.BrandID), 10), v.Brand.Name
+		case FacetFuelType:
+			value = v.FuelType
+		case FacetTransmission:
+			value = v.Transmission
+		case FacetYear:
+			value = strconv.Itoa(v.Year)
+		case FacetColor:
+			value = v.ExteriorColor
+		default:
+			return nil, fmt.Errorf("unknown facet %q", facet)
+		}
+		if value == "" {
+			continue
+		}
+
+		if i, ok := index[value]; ok {
+			counts[i].Count++
+			continue
+		}
+		index[value] = len(counts)
+		counts = append(counts, models.FacetCount{Value: value, Label: label, Count: 1})
+	}
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Value < counts[j].Value
+	})
+	return counts, nil
+}
+
+func (r *memoryVehicleRepository) CountByPrice(filter models.VehicleFilter, limits []float64) ([]int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	matched, _ := r.store.searchVehicles(filter)
+	counts := make([]int64, len(limits)+1)
+	for _, v := range matched {
+		// The first limit above the price numbers its range
+		counts[sort.Search(len(limits), func(i int) bool { return v.Price < limits[i] })]++
+	}
+	return counts, nil
+}
+
+func (r *memoryVehicleRepository) GetByID(id uint) (*models.Vehicle, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	v, ok := r.store.vehicles[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	v = r.store.vehicleWithBrand(v)
+	return &v, nil
+}
+
+// GetByIDForUpdate needs no row lock because memory transactions are serialized
+func (r *memoryVehicleRepository) GetByIDForUpdate(id uint) (*models.Vehicle, error) {
+	return r.GetByID(id)
+}
+
+func (r *memoryVehicleRepository) Create(vehicle *models.Vehicle) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	vehicle.ID = r.store.newID()
+	vehicle.Version = 1
+	vehicle.CreatedAt = now
+	vehicle.UpdatedAt = now
+	r.store.vehicles[vehicle.ID] = *vehicle
+	return nil
+}
+
+func (r *memoryVehicleRepository) Update(vehicle *models.Vehicle) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.vehicles[vehicle.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if stored.Version != vehicle.Version {
+		return ErrVersionConflict
+	}
+	vehicle.Version++
+	vehicle.UpdatedAt = time.Now()
+	r.store.vehicles[vehicle.ID] = *vehicle
+	return nil
+}
+
+func (r *memoryVehicleRepository) Delete(id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.vehicles[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.store.vehicles, id)
+	return nil
+}
+
+func (r *memoryVehicleRepository) ListNames() ([]VehicleName, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	counts := make(map[[3]string]int64)
+	for _, v := range r.store.vehicles {
+		if v.Availability {
+			counts[[3]string{r.store.brands[v.BrandID].Name, v.Name, v.Model}]++
+		}
+	}
+
+	names := make([]VehicleName, 0, len(counts))
+	for key, count := range counts {
+		names = append(names, VehicleName{Brand: key[0], Name: key[1], Model: key[2], Count: count})
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := names[i], names[j]
+		if a.Brand != b.Brand {
+			return a.Brand < b.Brand
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Model < b.Model
+	})
+	return names, nil
+}
+
+// memoryBrandRepository is the in-memory BrandRepository
+type memoryBrandRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryBrandRepository) List() ([]models.Brand, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	brands := make([]models.Brand, 0, len(r.store.brands))
+	for _, id := range sortedIDs(r.store.brands) {
+		brands = append(brands, r.store.brands[id])
+	}
+	return brands, nil
+}
+
+func (r *memoryBrandRepository) GetByID(id uint) (*models.Brand, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	brand, ok := r.store.brands[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	brand.Vehicles = nil
+	for _, vid := range sortedIDs(r.store.vehicles) {
+		if v := r.store.vehicles[vid]; v.BrandID == id {
+			brand.Vehicles = append(brand.Vehicles, v)
+		}
+	}
+	return &brand, nil
+}
+
+func (r *memoryBrandRepository) GetByIDForUpdate(id uint) (*models.Brand, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	brand, ok := r.store.brands[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	brand.Vehicles = nil
+	return &brand, nil
+}
+
+func (r *memoryBrandRepository) Create(brand *models.Brand) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.store.brandNameTaken(brand.Name, 0) {
+		return ErrDuplicate
+	}
+
+	now := time.Now()
+	brand.ID = r.store.newID()
+	brand.Version = 1
+	brand.CreatedAt = now
+	brand.UpdatedAt = now
+	r.store.brands[brand.ID] = *brand
+	return nil
+}
+
+func (r *memoryBrandRepository) Update(brand *models.Brand) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.brands[brand.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if stored.Version != brand.Version {
+		return ErrVersionConflict
+	}
+	if r.store.brandNameTaken(brand.Name, brand.ID) {
+		return ErrDuplicate
+	}
+	brand.Version++
+	brand.UpdatedAt = time.Now()
+	r.store.brands[brand.ID] = *brand
+	return nil
+}
+
+// brandNameTaken reports whether a brand other than except is called name,
+// like the unique index on brands.name; callers must hold a lock
+func (s *memoryStore) brandNameTaken(name string, except uint) bool {
+	for id, b := range s.brands {
+		if id != except && b.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryBrandRepository) Delete(id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.brands[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.store.brands, id)
+	return nil
+}
+
+func (r *memoryBrandRepository) CountVehicles(id uint) (int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var count int64
+	for _, v := range r.store.vehicles {
+		if v.BrandID == id {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// memoryBookingRepository is the in-memory BookingRepository
+type memoryBookingRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryBookingRepository) List(filter models.BookingFilter) ([]models.Booking, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var bookings []models.Booking
+	for _, id := range sortedIDs(r.store.bookings) {
+		b := r.store.bookings[id]
+		if filter.Status != "" && b.Status != filter.Status {
+			continue
+		}
+		if filter.SuspectedSpam != nil && b.SuspectedSpam != *filter.SuspectedSpam {
+			continue
+		}
+		bookings = append(bookings, r.store.bookingWithVehicle(b))
+	}
+
+	// Newest first
+	sort.SliceStable(bookings, func(i, j int) bool {
+		return bookings[i].CreatedAt.After(bookings[j].CreatedAt)
+	})
+	return bookings, nil
+}
+
+func (r *memoryBookingRepository) GetByID(id uint) (*models.Booking, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	b, ok := r.store.bookings[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	b = r.store.bookingWithVehicle(b)
+	return &b, nil
+}
+
+func (r *memoryBookingRepository) Create(booking *models.Booking) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	booking.ID = r.store.newID()
+	booking.Version = 1
+	booking.CreatedAt = now
+	booking.UpdatedAt = now
+	if booking.Status == "" {
+		booking.Status = "pending"
+	}
+	r.store.bookings[booking.ID] = *booking
+	return nil
+}
+
+func (r *memoryBookingRepository) Update(booking *models.Booking) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.bookings[booking.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if stored.Version != booking.Version {
+		return ErrVersionConflict
+	}
+	booking.Version++
+	booking.UpdatedAt = time.Now()
+	r.store.bookings[booking.ID] = *booking
+	return nil
+}
+
+// GetByIDForUpdate needs no row lock because memory transactions are serialized
+func (r *memoryBookingRepository) GetByIDForUpdate(id uint) (*models.Booking, error) {
+	return r.GetByID(id)
+}
+
+func (r *memoryBookingRepository) Delete(id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.bookings[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.store.bookings, id)
+
+	for hid, entry := range r.store.history {
+		if entry.BookingID == id {
+			delete(r.store.history, hid)
+		}
+	}
+	return nil
+}
+
+func (r *memoryBookingRepository) AddStatusHistory(entry *models.BookingStatusHistory) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	entry.ID = r.store.newID()
+	entry.CreatedAt = time.Now()
+	r.store.history[entry.ID] = *entry
+	return nil
+}
+
+func (r *memoryBookingRepository) StatusHistory(bookingID uint) ([]models.BookingStatusHistory, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var history []models.BookingStatusHistory
+	for _, id := range sortedIDs(r.store.history) {
+		if entry := r.store.history[id]; entry.BookingID == bookingID {
+			history = append(history, entry)
+		}
+	}
+	return history, nil
+}
+
+func (r *memoryBookingRepository) ListTestDrives(vehicleID uint, from, to time.Time, statuses []string) ([]models.Booking, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var bookings []models.Booking
+	for _, id := range sortedIDs(r.store.bookings) {
+		b := r.store.bookings[id]
+		if b.VehicleID != vehicleID || b.SuspectedSpam || b.RequestedStart == nil || b.RequestedEnd == nil {
+			continue
+		}
+		if !slices.Contains(statuses, b.Status) {
+			continue
+		}
+		if b.RequestedStart.Before(to) && b.RequestedEnd.After(from) {
+			bookings = append(bookings, b)
+		}
+	}
+
+	sort.SliceStable(bookings, func(i, j int) bool {
+		return bookings[i].RequestedStart.Before(*bookings[j].RequestedStart)
+	})
+	return bookings, nil
+}
+
+func (r *memoryBookingRepository) ListRecentForVehicle(vehicleID uint, since time.Time) ([]models.Booking, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var bookings []models.Booking
+	for _, id := range sortedIDs(r.store.bookings) {
+		if b := r.store.bookings[id]; b.VehicleID == vehicleID && !b.CreatedAt.Before(since) {
+			bookings = append(bookings, b)
+		}
+	}
+
+	sort.SliceStable(bookings, func(i, j int) bool {
+		return bookings[i].CreatedAt.After(bookings[j].CreatedAt)
+	})
+	return bookings, nil
+}
+
+// memoryReservationRepository is the in-memory ReservationRepository
+type memoryReservationRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryReservationRepository) GetByID(id uint) (*models.Reservation, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	reservation, ok := r.store.holds[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &reservation, nil
+}
+
+// GetByIDForUpdate needs no row lock because memory transactions are serialized
+func (r *memoryReservationRepository) GetByIDForUpdate(id uint) (*models.Reservation, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	reservation, ok := r.store.holds[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &reservation, nil
+}
+
+// filterHolds returns unreleased holds accepted by keep, soonest expiry
+// first; callers must hold a lock
+func (s *memoryStore) filterHolds(keep func(models.Reservation) bool) []models.Reservation {
+	var reservations []models.Reservation
+	for _, id := range sortedIDs(s.holds) {
+		if h := s.holds[id]; h.ReleasedAt == nil && keep(h) {
+			h.Vehicle = s.vehicleWithBrand(s.vehicles[h.VehicleID])
+			reservations = append(reservations, h)
+		}
+	}
+	sort.SliceStable(reservations, func(i, j int) bool {
+		return reservations[i].ExpiresAt.Before(reservations[j].ExpiresAt)
+	})
+	return reservations
+}
+
+func (r *memoryReservationRepository) ListActive(now time.Time) ([]models.Reservation, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.store.filterHolds(func(h models.Reservation) bool { return h.ExpiresAt.After(now) }), nil
+}
+
+func (r *memoryReservationRepository) ListExpired(now time.Time) ([]models.Reservation, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.store.filterHolds(func(h models.Reservation) bool { return !h.ExpiresAt.After(now) }), nil
+}
+
+func (r *memoryReservationRepository) ActiveForVehicle(vehicleID uint, now time.Time) (*models.Reservation, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	active := r.store.filterHolds(func(h models.Reservation) bool {
+		return h.VehicleID == vehicleID && h.ExpiresAt.After(now)
+	})
+	if len(active) == 0 {
+		return nil, ErrNotFound
+	}
+	return &active[0], nil
+}
+
+func (r *memoryReservationRepository) Create(reservation *models.Reservation) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	reservation.ID = r.store.newID()
+	reservation.CreatedAt = now
+	reservation.UpdatedAt = now
+	r.store.holds[reservation.ID] = *reservation
+	return nil
+}
+
+func (r *memoryReservationRepository) Update(reservation *models.Reservation) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.holds[reservation.ID]; !ok {
+		return ErrNotFound
+	}
+	reservation.UpdatedAt = time.Now()
+	r.store.holds[reservation.ID] = *reservation
+	return nil
+}
+
+// memoryOutboxRepository is the in-memory OutboxRepository
+type memoryOutboxRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryOutboxRepository) Enqueue(event *models.OutboxEvent) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	event.ID = r.store.newID()
+	event.CreatedAt = time.Now()
+	r.store.events[event.ID] = *event
+	return nil
+}
+
+func (r *memoryOutboxRepository) ListUnprocessed(limit int) ([]models.OutboxEvent, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var events []models.OutboxEvent
+	for _, id := range sortedIDs(r.store.events) {
+		if limit > 0 && len(events) == limit {
+			break
+		}
+		if e := r.store.events[id]; e.ProcessedAt == nil {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (r *memoryOutboxRepository) MarkProcessed(id uint, at time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	e, ok := r.store.events[id]
+	if !ok {
+		return ErrNotFound
+	}
+	e.ProcessedAt = &at
+	r.store.events[id] = e
+	return nil
+}
+
+// memoryWebhookRepository is the in-memory WebhookRepository
+type memoryWebhookRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryWebhookRepository) List() ([]models.WebhookSubscription, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var subs []models.WebhookSubscription
+	for _, id := range sortedIDs(r.store.webhooks) {
+		subs = append(subs, r.store.webhooks[id])
+	}
+	return subs, nil
+}
+
+func (r *memoryWebhookRepository) ListActive() ([]models.WebhookSubscription, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var subs []models.WebhookSubscription
+	for _, id := range sortedIDs(r.store.webhooks) {
+		if sub := r.store.webhooks[id]; sub.Active {
+			subs = append(subs, sub)
+		}
+	}
+	return subs, nil
+}
+
+func (r *memoryWebhookRepository) GetByID(id uint) (*models.WebhookSubscription, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	sub, ok := r.store.webhooks[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &sub, nil
+}
+
+func (r *memoryWebhookRepository) Create(sub *models.WebhookSubscription) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	sub.ID = r.store.newID()
+	sub.CreatedAt = now
+	sub.UpdatedAt = now
+	r.store.webhooks[sub.ID] = *sub
+	return nil
+}
+
+func (r *memoryWebhookRepository) Update(sub *models.WebhookSubscription) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.webhooks[sub.ID]; !ok {
+		return ErrNotFound
+	}
+	sub.UpdatedAt = time.Now()
+	r.store.webhooks[sub.ID] = *sub
+	return nil
+}
+
+func (r *memoryWebhookRepository) Delete(id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.webhooks[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.store.webhooks, id)
+
+	for did, d := range r.store.sends {
+		if d.SubscriptionID == id {
+			delete(r.store.sends, did)
+		}
+	}
+	return nil
+}
+
+func (r *memoryWebhookRepository) CreateDelivery(delivery *models.WebhookDelivery) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	delivery.ID = r.store.newID()
+	delivery.CreatedAt = now
+	delivery.UpdatedAt = now
+	r.store.sends[delivery.ID] = *delivery
+	return nil
+}
+
+func (r *memoryWebhookRepository) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.sends[delivery.ID]; !ok {
+		return ErrNotFound
+	}
+	delivery.UpdatedAt = time.Now()
+	r.store.sends[delivery.ID] = *delivery
+	return nil
+}
+
+func (r *memoryWebhookRepository) ListDueDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var deliveries []models.WebhookDelivery
+	for _, id := range sortedIDs(r.store.sends) {
+		if d := r.store.sends[id]; d.Status == "pending" && !d.NextAttemptAt.After(now) {
+			deliveries = append(deliveries, d)
+		}
+	}
+
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		return deliveries[i].NextAttemptAt.Before(deliveries[j].NextAttemptAt)
+	})
+	if limit > 0 && len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+func (r *memoryWebhookRepository) ListDeliveries(subscriptionID uint, limit int) ([]models.WebhookDelivery, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	ids := sortedIDs(r.store.sends)
+	var deliveries []models.WebhookDelivery
+	for i := len(ids) - 1; i >= 0; i-- {
+		if limit > 0 && len(deliveries) == limit {
+			break
+		}
+		if d := r.store.sends[ids[i]]; d.SubscriptionID == subscriptionID {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries, nil
+}
+
+// memoryDealerHoursRepository is the in-memory DealerHoursRepository
+type memoryDealerHoursRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryDealerHoursRepository) Get(dealer string) ([]models.DealerHours, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return slices.Clone(r.store.hours[dealer]), nil
+}
+
+func (r *memoryDealerHoursRepository) Replace(dealer string, hours []models.DealerHours) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	replaced := make([]models.DealerHours, len(hours))
+	for i, h := range hours {
+		h.ID = r.store.newID()
+		h.Dealer = dealer
+		replaced[i] = h
+	}
+	sort.Slice(replaced, func(i, j int) bool { return replaced[i].Weekday < replaced[j].Weekday })
+	r.store.hours[dealer] = replaced
+	return nil
+}
+
+// memoryAnalyticsRepository is the in-memory AnalyticsRepository
+type memoryAnalyticsRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryAnalyticsRepository) Summary() (*models.Analytics, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	analytics := models.Analytics{
+		VehiclesByBrand:  make(map[string]int64),
+		VehiclesByFuel:   make(map[string]int64),
+		BookingsByStatus: make(map[string]int64),
+		PriceRange:       map[string]float64{"min": 0, "max": 0},
+	}
+
+	var sum float64
+	for _, v := range r.store.vehicles {
+		analytics.TotalVehicles++
+		if brand, ok := r.store.brands[v.BrandID]; ok {
+			analytics.VehiclesByBrand[brand.Name]++
+		}
+		analytics.VehiclesByFuel[v.FuelType]++
+
+		sum += v.Price
+		if analytics.TotalVehicles == 1 || v.Price < analytics.PriceRange["min"] {
+			analytics.PriceRange["min"] = v.Price
+		}
+		if v.Price > analytics.PriceRange["max"] {
+			analytics.PriceRange["max"] = v.Price
+		}
+	}
+	if analytics.TotalVehicles > 0 {
+		analytics.AveragePrice = sum / float64(analytics.TotalVehicles)
+	}
+
+	for _, b := range r.store.bookings {
+		analytics.TotalBookings++
+		analytics.BookingsByStatus[b.Status]++
+	}
+
+	return &analytics, nil
+}
+
+// bookingCounts returns the number of bookings per vehicle accepted by
+// include; callers must hold a lock
+func (s *memoryStore) bookingCounts(include func(b models.Booking) bool) map[uint]int64 {
+	counts := make(map[uint]int64)
+	for _, b := range s.bookings {
+		if _, ok := s.vehicles[b.VehicleID]; ok && include(b) {
+			counts[b.VehicleID]++
+		}
+	}
+	return counts
+}
+
+func (r *memoryAnalyticsRepository) PopularVehicles(limit int) ([]models.PopularVehicle, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var popular []models.PopularVehicle
+	notSpam := func(b models.Booking) bool { return !b.SuspectedSpam }
+	for id, count := range r.store.bookingCounts(notSpam) {
+		v := r.store.vehicleWithBrand(r.store.vehicles[id])
+		popular = append(popular, models.PopularVehicle{
+			VehicleID:    id,
+			VehicleName:  v.Name,
+			BrandName:    v.Brand.Name,
+			BookingCount: count,
+			Price:        v.Price,
+		})
+	}
+
+	sort.Slice(popular, func(i, j int) bool {
+		if popular[i].BookingCount != popular[j].BookingCount {
+			return popular[i].BookingCount > popular[j].BookingCount
+		}
+		return popular[i].VehicleID < popular[j].VehicleID
+	})
+	if limit > 0 && len(popular) > limit {
+		popular = popular[:limit]
+	}
+	return popular, nil
+}
+
+func (r *memoryAnalyticsRepository) BookingTrends(days int) ([]models.BookingTrend, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	counts := make(map[string]int64)
+	for _, b := range r.store.bookings {
+		counts[b.CreatedAt.Format("2006-01-02")]++
+	}
+
+	trends := make([]models.BookingTrend, 0, len(counts))
+	for date, count := range counts {
+		trends = append(trends, models.BookingTrend{Date: date, Count: count})
+	}
+
+	sort.Slice(trends, func(i, j int) bool { return trends[i].Date > trends[j].Date })
+	if days > 0 && len(trends) > days {
+		trends = trends[:days]
+	}
+	return trends, nil
+}
+
+func (r *memoryAnalyticsRepository) InventoryStatus() (*models.InventoryStatus, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var status models.InventoryStatus
+	for _, v := range r.store.vehicles {
+		if v.Availability {
+			status.AvailableVehicles++
+		} else {
+			status.UnavailableVehicles++
+		}
+	}
+
+	open := func(b models.Booking) bool { return b.Status == "pending" || b.Status == "contacted" }
+	for id, count := range r.store.bookingCounts(open) {
+		if count <= 2 {
+			continue
+		}
+		v := r.store.vehicleWithBrand(r.store.vehicles[id])
+		status.HighDemandVehicles = append(status.HighDemandVehicles, models.HighDemandVehicle{
+			VehicleID:    id,
+			VehicleName:  v.Name,
+			BrandName:    v.Brand.Name,
+			BookingCount: count,
+		})
+	}
+
+	sort.Slice(status.HighDemandVehicles, func(i, j int) bool {
+		a, b := status.HighDemandVehicles[i], status.HighDemandVehicles[j]
+		if a.BookingCount != b.BookingCount {
+			return a.BookingCount > b.BookingCount
+		}
+		return a.VehicleID < b.VehicleID
+	})
+	return &status, nil
+}
+
+// memoryIdempotencyRepository is the in-memory IdempotencyRepository
+type memoryIdempotencyRepository struct {
+	store *memoryStore
+}
+
+// findReplay returns the ID of the record stored under scope and key;
+// callers must hold a lock
+func (s *memoryStore) findReplay(scope, key string) (uint, bool) {
+	for id, record := range s.replays {
+		if record.Scope == scope && record.Key == key {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+func (r *memoryIdempotencyRepository) Get(scope, key string) (*models.IdempotencyRecord, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	id, ok := r.store.findReplay(scope, key)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	record := r.store.replays[id]
+	return &record, nil
+}
+
+func (r *memoryIdempotencyRepository) Reserve(record *models.IdempotencyRecord) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.findReplay(record.Scope, record.Key); ok {
+		return ErrDuplicate
+	}
+
+	now := time.Now()
+	record.ID = r.store.newID()
+	record.CreatedAt, record.UpdatedAt = now, now
+	r.store.replays[record.ID] = *record
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) Update(record *models.IdempotencyRecord) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.replays[record.ID]; !ok {
+		return ErrNotFound
+	}
+	record.UpdatedAt = time.Now()
+	r.store.replays[record.ID] = *record
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) Delete(id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.replays, id)
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var removed int64
+	for id, record := range r.store.replays {
+		if !record.ExpiresAt.After(now) {
+			delete(r.store.replays, id)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// MemoryAdminUserRepository is the in-memory AdminUserRepository. Unlike the
+// GORM implementation it exposes Create so tests can register accounts.
+type MemoryAdminUserRepository struct {
+	store *memoryStore
+}
+
+// Create stores admin and assigns its ID
+func (r *MemoryAdminUserRepository) Create(admin *models.AdminUser) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	admin.ID = r.store.newID()
+	admin.CreatedAt = now
+	admin.UpdatedAt = now
+	r.store.admins[admin.ID] = *admin
+	return nil
+}
+
+func (r *MemoryAdminUserRepository) GetByID(id uint) (*models.AdminUser, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	admin, ok := r.store.admins[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &admin, nil
+}
+
+func (r *MemoryAdminUserRepository) GetByEmail(email string) (*models.AdminUser, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, admin := range r.store.admins {
+		if admin.Email == email {
+			return &admin, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// memorySearchQueryRepository is the in-memory SearchQueryRepository
+type memorySearchQueryRepository struct {
+	store *memoryStore
+}
+
+func (r *memorySearchQueryRepository) Record(query string, at time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	record, ok := r.store.queries[query]
+	if !ok {
+		record = models.SearchQuery{ID: r.store.newID(), Query: query, CreatedAt: at}
+	}
+	record.Searches++
+	record.LastSearchedAt = at
+	r.store.queries[query] = record
+	return nil
+}
+
+func (r *memorySearchQueryRepository) ListPopular(minSearches int64, limit int) ([]models.SearchQuery, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var queries []models.SearchQuery
+	for _, record := range r.store.queries {
+		if record.Searches >= minSearches {
+			queries = append(queries, record)
+		}
+	}
+	sort.Slice(queries, func(i, j int) bool {
+		if queries[i].Searches != queries[j].Searches {
+			return queries[i].Searches > queries[j].Searches
+		}
+		return queries[i].Query < queries[j].Query
+	})
+	if len(queries) > limit {
+		queries = queries[:limit]
+	}
+	return queries, nil
+}
+
+// memorySynonymRepository is the in-memory SynonymRepository
+type memorySynonymRepository struct {
+	store *memoryStore
+}
+
+// findSynonym returns the ID of the record pairing term and synonym;
+// callers must hold a lock
+func (s *memoryStore) findSynonym(term, synonym string) (uint, bool) {
+	for id, record := range s.synonyms {
+		if record.Term == term && record.Synonym == synonym {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+func (r *memorySynonymRepository) List() ([]models.SearchSynonym, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	synonyms := make([]models.SearchSynonym, 0, len(r.store.synonyms))
+	for _, id := range sortedIDs(r.store.synonyms) {
+		synonyms = append(synonyms, r.store.synonyms[id])
+	}
+	sort.SliceStable(synonyms, func(i, j int) bool {
+		if synonyms[i].Term != synonyms[j].Term {
+			return synonyms[i].Term < synonyms[j].Term
+		}
+		return synonyms[i].Synonym < synonyms[j].Synonym
+	})
+	return synonyms, nil
+}
+
+func (r *memorySynonymRepository) GetByID(id uint) (*models.SearchSynonym, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	synonym, ok := r.store.synonyms[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &synonym, nil
+}
+
+func (r *memorySynonymRepository) Create(synonym *models.SearchSynonym) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.findSynonym(synonym.Term, synonym.Synonym); ok {
+		return ErrDuplicate
+	}
+
+	now := time.Now()
+	synonym.ID = r.store.newID()
+	synonym.CreatedAt, synonym.UpdatedAt = now, now
+	r.store.synonyms[synonym.ID] = *synonym
+	return nil
+}
+
+func (r *memorySynonymRepository) Update(synonym *models.SearchSynonym) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.synonyms[synonym.ID]; !ok {
+		return ErrNotFound
+	}
+	if id, ok := r.store.findSynonym(synonym.Term, synonym.Synonym); ok && id != synonym.ID {
+		return ErrDuplicate
+	}
+	synonym.UpdatedAt = time.Now()
+	r.store.synonyms[synonym.ID] = *synonym
+	return nil
+}
+
+func (r *memorySynonymRepository) Delete(id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.synonyms[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.store.synonyms, id)
+	return nil
+}
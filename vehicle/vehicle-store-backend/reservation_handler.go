@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"vehicle-store-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultHoldDuration applies when a hold request gives no duration
+	defaultHoldDuration = 48 * time.Hour
+	// maxHoldDuration caps how long a vehicle can be held
+	maxHoldDuration = 14 * 24 * time.Hour
+)
+
+var (
+	errAlreadyHeld = errors.New("vehicle already has an active hold")
+	errNotHeld     = errors.New("reservation is no longer active")
+)
+
+// HoldVehicle handles POST /api/admin/vehicles/:id/hold
+func (s *Server) HoldVehicle(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		respondError(c, notFoundError("Vehicle"))
+		return
+	}
+
+	var body struct {
+		Hours         int    `json:"hours"`
+		CustomerName  string `json:"customer_name"`
+		CustomerEmail string `json:"customer_email"`
+		Note          string `json:"note"`
+	}
+
+	if !bindJSON(c, &body) {
+		return
+	}
+
+	duration := defaultHoldDuration
+	if body.Hours != 0 {
+		duration = time.Duration(body.Hours) * time.Hour
+	}
+	if duration <= 0 || duration > maxHoldDuration {
+		respondError(c, validationError(FieldError{Field: "hours", Message: "must be between 1 and 336"}))
+		return
+	}
+
+	admin, _ := currentAdmin(c)
+	now := time.Now()
+	reservation := models.Reservation{
+		VehicleID:     id,
+		CustomerName:  body.CustomerName,
+		CustomerEmail: body.CustomerEmail,
+		Note:          body.Note,
+		HeldBy:        admin.Email,
+		ExpiresAt:     now.Add(duration),
+	}
+
+	err = s.Tx.WithinTransaction(func(repos Repositories) error {
+		vehicle, err := repos.Vehicles.GetByIDForUpdate(id)
+		if err != nil {
+			return err
+		}
+
+		if _, err := repos.Reservations.ActiveForVehicle(id, now); err == nil {
+			return errAlreadyHeld
+		} else if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+
+		if !vehicle.Availability {
+			return errVehicleUnavailable
+		}
+
+		reservation.PreviousAvailability = vehicle.Availability
+		vehicle.Availability = false
+		if err := repos.Vehicles.Update(vehicle); err != nil {
+			return err
+		}
+
+		if err := repos.Reservations.Create(&reservation); err != nil {
+			return err
+		}
+		return enqueueEvent(repos, EventVehicleUpdated, vehicle)
+	})
+
+	switch {
+	case errors.Is(err, ErrNotFound):
+		respondError(c, notFoundError("Vehicle"))
+		return
+	case errors.Is(err, errAlreadyHeld):
+		respondError(c, NewAPIError(http.StatusConflict, CodeAlreadyHeld, "Vehicle is already on hold"))
+		return
+	case errors.Is(err, errVehicleUnavailable):
+		respondError(c, NewAPIError(http.StatusConflict, CodeVehicleUnavailable, "Vehicle is not available"))
+		return
+	case err != nil:
+		respondError(c, internalError("Failed to hold vehicle", err))
+		return
+	}
+
+	// Include the held vehicle with brand information
+	if vehicle, err := s.Vehicles.GetByID(reservation.VehicleID); err == nil {
+		reservation.Vehicle = *vehicle
+	}
+
+	c.JSON(http.StatusCreated, reservation)
+}
+
+// ReleaseReservation handles POST /api/admin/reservations/:id/release
+func (s *Server) ReleaseReservation(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		respondError(c, notFoundError("Reservation"))
+		return
+	}
+
+	reservation, err := s.releaseReservation(id, "released")
+	switch {
+	case errors.Is(err, ErrNotFound):
+		respondError(c, notFoundError("Reservation"))
+		return
+	case errors.Is(err, errNotHeld):
+		respondError(c, NewAPIError(http.StatusConflict, CodeAlreadyReleased, "Reservation has already been released"))
+		return
+	case err != nil:
+		respondError(c, internalError("Failed to release reservation", err))
+		return
+	}
+
+	// Include the released vehicle with brand information
+	if vehicle, err := s.Vehicles.GetByID(reservation.VehicleID); err == nil {
+		reservation.Vehicle = *vehicle
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+// GetReservations handles GET /api/admin/reservations
+func (s *Server) GetReservations(c *gin.Context) {
+	reservations, err := s.Holds.ListActive(time.Now())
+	if err != nil {
+		respondError(c, internalError("Failed to fetch reservations", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, reservations)
+}
+
+// releaseReservation ends a hold and gives the vehicle back the availability
+// it had before the hold, or the one an admin set by hand during it
+func (s *Server) releaseReservation(id uint, reason string) (*models.Reservation, error) {
+	var reservation *models.Reservation
+	err := s.Tx.WithinTransaction(func(repos Repositories) error {
+		held, err := repos.Reservations.GetByID(id)
+		if err != nil {
+			return err
+		}
+
+		// Lock the vehicle before the hold, in the same order as holding
+		// and editing the vehicle do
+		vehicle, err := repos.Vehicles.GetByIDForUpdate(held.VehicleID)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+
+		r, err := repos.Reservations.GetByIDForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if r.ReleasedAt != nil {
+			return errNotHeld
+		}
+
+		now := time.Now()
+		r.ReleasedAt = &now
+		r.ReleaseReason = reason
+		if err := repos.Reservations.Update(r); err != nil {
+			return err
+		}
+
+		if vehicle == nil {
+			// The vehicle was deleted while held; nothing to restore
+			reservation = r
+			return nil
+		}
+
+		vehicle.Availability = r.PreviousAvailability
+		if err := repos.Vehicles.Update(vehicle); err != nil {
+			return err
+		}
+
+		reservation = r
+		return enqueueEvent(repos, EventVehicleUpdated, vehicle)
+	})
+	return reservation, err
+}
+
+// keepHandSetAvailability makes availability an admin set by hand on a held
+// vehicle outlast the hold: releasing or expiring the hold then leaves the
+// vehicle as the admin set it instead of restoring the availability it had
+// before. Call it with the vehicle locked.
+func keepHandSetAvailability(repos Repositories, vehicle *models.Vehicle) error {
+	// The zero time also finds holds that expired but are not swept yet
+	hold, err := repos.Reservations.ActiveForVehicle(vehicle.ID, time.Time{})
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if hold.PreviousAvailability == vehicle.Availability {
+		return nil
+	}
+	hold.PreviousAvailability = vehicle.Availability
+	return repos.Reservations.Update(hold)
+}
+
+// SweepExpiredReservations releases every hold whose expiry has passed and
+// returns how many were released
+func (s *Server) SweepExpiredReservations() (int, error) {
+	expired, err := s.Holds.ListExpired(time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, r := range expired {
+		_, err := s.releaseReservation(r.ID, "expired")
+		if errors.Is(err, errNotHeld) {
+			// Released concurrently by an admin
+			continue
+		}
+		if err != nil {
+			return released, err
+		}
+		released++
+	}
+	return released, nil
+}
+
+// StartReservationSweeper releases expired holds now and then every interval
+// until ctx is done. Holds are persisted, so sweeping at startup also covers
+// holds that lapsed while the server was down.
+func (s *Server) StartReservationSweeper(ctx context.Context, interval time.Duration) {
+	sweep := func() {
+		released, err := s.SweepExpiredReservations()
+		if err != nil {
+			log.Println("Failed to sweep expired reservations:", err)
+		}
+		if released > 0 {
+			log.Printf("Released %d expired reservation(s)", released)
+			s.Suggestions.Invalidate()
+		}
+	}
+
+	sweep()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sweep()
+			}
+		}
+	}()
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"vehicle-store-backend/internal/models"
+)
+
+// vehicleEvents returns the availability in each unprocessed vehicle.updated
+// event for vehicleID, oldest first
+func (ts *testServer) vehicleEvents(t *testing.T, vehicleID uint) []bool {
+	t.Helper()
+
+	events, err := ts.repos.Events.ListUnprocessed(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var availability []bool
+	for _, event := range events {
+		var vehicle models.Vehicle
+		if err := json.Unmarshal([]byte(event.Payload), &vehicle); err != nil {
+			t.Fatal(err)
+		}
+		if event.EventType == EventVehicleUpdated && vehicle.ID == vehicleID {
+			availability = append(availability, vehicle.Availability)
+		}
+	}
+	return availability
+}
+
+func TestHoldAndRelease(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.adminToken(t, RoleInventoryManager)
+	vehicle := ts.addVehicle(t, models.Vehicle{Name: "Camry", Year: 2024, Price: 28000})
+
+	w := ts.do(http.MethodPost, fmt.Sprintf("/api/admin/vehicles/%d/hold", vehicle.ID), `{"hours":2,"customer_name":"Jane"}`, "Authorization", token)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("hold = %d: %s", w.Code, w.Body.String())
+	}
+	var reservation models.Reservation
+	decode(t, w, &reservation)
+	if reservation.Vehicle.Availability || !reservation.PreviousAvailability {
+		t.Errorf("held vehicle availability = %v, previous = %v", reservation.Vehicle.Availability, reservation.PreviousAvailability)
+	}
+	if got := ts.vehicleEvents(t, vehicle.ID); len(got) != 1 || got[0] {
+		t.Errorf("events after hold = %v, want one unavailable update", got)
+	}
+
+	w = ts.do(http.MethodPost, fmt.Sprintf("/api/admin/vehicles/%d/hold", vehicle.ID), `{}`, "Authorization", token)
+	expectError(t, w, http.StatusConflict, CodeAlreadyHeld)
+
+	path := fmt.Sprintf("/api/admin/reservations/%d/release", reservation.ID)
+	w = ts.do(http.MethodPost, path, "", "Authorization", token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("release = %d: %s", w.Code, w.Body.String())
+	}
+	decode(t, w, &reservation)
+	if !reservation.Vehicle.Availability || reservation.ReleaseReason != "released" {
+		t.Errorf("released vehicle availability = %v, reason = %q", reservation.Vehicle.Availability, reservation.ReleaseReason)
+	}
+	if got := ts.vehicleEvents(t, vehicle.ID); len(got) != 2 || !got[1] {
+		t.Errorf("events after release = %v, want a second, available update", got)
+	}
+
+	w = ts.do(http.MethodPost, path, "", "Authorization", token)
+	expectError(t, w, http.StatusConflict, CodeAlreadyReleased)
+}
+
+// hold puts vehicleID on hold through the API and returns the reservation
+func (ts *testServer) hold(t *testing.T, token string, vehicleID uint) models.Reservation {
+	t.Helper()
+
+	w := ts.do(http.MethodPost, fmt.Sprintf("/api/admin/vehicles/%d/hold", vehicleID), `{"hours":2}`, "Authorization", token)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("hold = %d: %s", w.Code, w.Body.String())
+	}
+	var reservation models.Reservation
+	decode(t, w, &reservation)
+	return reservation
+}
+
+func TestReleaseKeepsHandSetAvailability(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		want   bool
+	}{
+		// Sold while held: the release must not put it back on sale
+		{"patched off sale", http.MethodPatch, `{"availability":false}`, false},
+		// A PUT echoing the held state only edits the price
+		{"edited", http.MethodPut, `{"brand_id":%d,"name":"Camry","year":2024,"price":27000,"fuel_type":"Petrol","availability":false}`, true},
+	}
+
+	for _, tt := range tests {
+		for _, sweep := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s, swept=%v", tt.name, sweep), func(t *testing.T) {
+				ts := newTestServer(t)
+				token := ts.adminToken(t, RoleInventoryManager)
+				vehicle := ts.addVehicle(t, models.Vehicle{Name: "Camry", Year: 2024, Price: 28000})
+				reservation := ts.hold(t, token, vehicle.ID)
+
+				current, err := ts.repos.Vehicles.GetByID(vehicle.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body := tt.body
+				if tt.method == http.MethodPut {
+					body = fmt.Sprintf(body, vehicle.BrandID)
+				}
+				w := ts.do(tt.method, fmt.Sprintf("/api/admin/vehicles/%d", vehicle.ID), body,
+					"Authorization", token, ifMatchHeader, etag(current.Version))
+				if w.Code != http.StatusOK {
+					t.Fatalf("%s = %d: %s", tt.method, w.Code, w.Body.String())
+				}
+
+				if sweep {
+					hold, err := ts.repos.Holds.GetByID(reservation.ID)
+					if err != nil {
+						t.Fatal(err)
+					}
+					hold.ExpiresAt = time.Now().Add(-time.Minute)
+					if err := ts.repos.Holds.Update(hold); err != nil {
+						t.Fatal(err)
+					}
+					if released, err := ts.SweepExpiredReservations(); err != nil || released != 1 {
+						t.Fatalf("SweepExpiredReservations = %d, %v; want 1", released, err)
+					}
+				} else {
+					w = ts.do(http.MethodPost, fmt.Sprintf("/api/admin/reservations/%d/release", reservation.ID), "", "Authorization", token)
+					if w.Code != http.StatusOK {
+						t.Fatalf("release = %d: %s", w.Code, w.Body.String())
+					}
+				}
+
+				got, err := ts.repos.Vehicles.GetByID(vehicle.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.Availability != tt.want {
+					t.Errorf("availability after release = %v, want %v", got.Availability, tt.want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"vehicle-store-backend/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NewRouter builds the gin engine with every API route registered against s.
+// It does not start the server, so tests can drive it with httptest.
+func NewRouter(s *Server) *gin.Engine {
+	if err := registerValidators(); err != nil {
+		log.Fatal("Failed to register validators:", err)
+	}
+
+	// Handler errors, including recovered panics, are rendered by
+	// errorMiddleware as the JSON error envelope
+	r := gin.New()
+	r.Use(gin.Logger(), requestIDMiddleware(), errorMiddleware(), gin.CustomRecovery(recoverPanic))
+
+	// Only take the client IP from X-Forwarded-For when the request came
+	// through a listed proxy; otherwise anyone could pick their own IP and
+	// sidestep rate limiting
+	if err := r.SetTrustedProxies(splitList(database.GetEnv("TRUSTED_PROXIES", ""))); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+
+	r.Use(corsMiddleware())
+
+	// Versioned API
+	registerRoutes(r.Group("/api/v1"), s)
+
+	// Unversioned alias kept for existing clients
+	registerRoutes(r.Group("/api"), s)
+
+	r.NoRoute(func(c *gin.Context) {
+		respondError(c, notFoundError("Route"))
+	})
+
+	return r
+}
+
+// registerRoutes mounts the public, admin and analytics routes on rg
+func registerRoutes(rg *gin.RouterGroup, s *Server) {
+	rg.GET("/health", HealthCheck)
+
+	// Public routes
+	rg.GET("/vehicles", s.RateLimit("vehicles.list"), s.GetVehicles)
+	rg.GET("/vehicles/suggest", s.RateLimit("vehicles.suggest"), s.SuggestVehicles)
+	rg.GET("/vehicles/facets", s.RateLimit("vehicles.facets"), s.GetVehicleFacets)
+	rg.GET("/vehicles/:id", s.GetVehicleByID)
+	rg.GET("/vehicles/:id/slots", s.GetVehicleSlots)
+	rg.GET("/brands", s.GetBrands)
+	rg.GET("/brands/:id", s.GetBrandByID)
+	rg.POST("/bookings", s.RateLimit("bookings.create"), s.Idempotent("bookings.create"), s.CreateBooking)
+
+	// Analytics routes
+	analytics := rg.Group("/analytics")
+	{
+		analytics.GET("/summary", s.RateLimit("analytics.summary"), s.GetAnalytics)
+	}
+
+	// Admin session routes (unauthenticated)
+	rg.POST("/admin/login", s.Login)
+	rg.POST("/admin/refresh", s.RefreshToken)
+
+	// Admin routes
+	admin := rg.Group("/admin", s.AuthRequired())
+	{
+		admin.POST("/vehicles", RequirePermission(PermVehiclesWrite), s.RefreshSuggestions(), s.Idempotent("vehicles.create"), s.CreateVehicle)
+		admin.PUT("/vehicles/:id", RequirePermission(PermVehiclesWrite), s.RefreshSuggestions(), s.UpdateVehicle)
+		admin.PATCH("/vehicles/:id", RequirePermission(PermVehiclesWrite), s.RefreshSuggestions(), s.PatchVehicle)
+		admin.DELETE("/vehicles/:id", RequirePermission(PermVehiclesDelete), s.RefreshSuggestions(), s.DeleteVehicle)
+
+		admin.POST("/vehicles/:id/hold", RequirePermission(PermReservations), s.RefreshSuggestions(), s.HoldVehicle)
+		admin.GET("/reservations", RequirePermission(PermReservations), s.GetReservations)
+		admin.POST("/reservations/:id/release", RequirePermission(PermReservations), s.RefreshSuggestions(), s.ReleaseReservation)
+
+		admin.GET("/dealer-hours/:dealer", RequirePermission(PermVehiclesWrite), s.GetDealerHours)
+		admin.PUT("/dealer-hours/:dealer", RequirePermission(PermVehiclesWrite), s.UpdateDealerHours)
+
+		admin.POST("/brands", RequirePermission(PermBrandsWrite), s.RefreshSuggestions(), s.Idempotent("brands.create"), s.CreateBrand)
+		admin.PUT("/brands/:id", RequirePermission(PermBrandsWrite), s.RefreshSuggestions(), s.UpdateBrand)
+		admin.PATCH("/brands/:id", RequirePermission(PermBrandsWrite), s.RefreshSuggestions(), s.PatchBrand)
+		admin.DELETE("/brands/:id", RequirePermission(PermBrandsDelete), s.RefreshSuggestions(), s.DeleteBrand)
+
+		admin.GET("/bookings", RequirePermission(PermBookingsRead), s.GetBookings)
+		admin.GET("/bookings/:id", RequirePermission(PermBookingsRead), s.GetBookingByID)
+		admin.PUT("/bookings/:id", RequirePermission(PermBookingsUpdate), s.UpdateBookingStatus)
+		admin.PATCH("/bookings/:id", RequirePermission(PermBookingsUpdate), s.PatchBooking)
+		admin.GET("/bookings/:id/history", RequirePermission(PermBookingsRead), s.GetBookingHistory)
+		admin.DELETE("/bookings/:id", RequirePermission(PermBookingsDelete), s.DeleteBooking)
+
+		admin.GET("/webhooks", RequirePermission(PermWebhooks), s.GetWebhooks)
+		admin.POST("/webhooks", RequirePermission(PermWebhooks), s.CreateWebhook)
+		admin.PUT("/webhooks/:id", RequirePermission(PermWebhooks), s.UpdateWebhook)
+		admin.DELETE("/webhooks/:id", RequirePermission(PermWebhooks), s.DeleteWebhook)
+		admin.GET("/webhooks/:id/deliveries", RequirePermission(PermWebhooks), s.GetWebhookDeliveries)
+
+		admin.GET("/search-synonyms", RequirePermission(PermSearchManage), s.GetSearchSynonyms)
+		admin.POST("/search-synonyms", RequirePermission(PermSearchManage), s.CreateSearchSynonym)
+		admin.PUT("/search-synonyms/:id", RequirePermission(PermSearchManage), s.UpdateSearchSynonym)
+		admin.DELETE("/search-synonyms/:id", RequirePermission(PermSearchManage), s.DeleteSearchSynonym)
+
+		admin.GET("/analytics/summary", RequirePermission(PermAnalyticsRead), s.GetAnalytics)
+		admin.GET("/analytics/booking-trends", RequirePermission(PermAnalyticsRead), s.GetBookingTrends)
+		admin.GET("/analytics/popular-vehicles", RequirePermission(PermInventoryReport), s.GetPopularVehicles)
+		admin.GET("/analytics/inventory-status", RequirePermission(PermInventoryReport), s.GetInventoryStatus)
+	}
+}
+
+// HealthCheck handles GET /api/health
+func HealthCheck(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// corsMiddleware allows cross-origin requests from the frontend (allow all for dev)
+func corsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key, If-Match, X-API-Key, X-Request-ID")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, ETag, Idempotent-Replayed, RateLimit-Policy, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After")
+
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
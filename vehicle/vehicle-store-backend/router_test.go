@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"vehicle-store-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+	os.Exit(m.Run())
+}
+
+// testServer is a router over in-memory repositories, or over a SQLite
+// database when made by newGormTestServer. repos is nil for SQLite.
+type testServer struct {
+	*Server
+	repos  *MemoryRepositories
+	router *gin.Engine
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	s, repos := NewMemoryServer()
+	return &testServer{Server: s, repos: repos, router: NewRouter(s)}
+}
+
+// newGormTestServer returns a router over a migrated SQLite database, without
+// rate limits like the in-memory server
+func newGormTestServer(t *testing.T) *testServer {
+	t.Helper()
+	s := NewServer(openTestDB(t))
+	s.RateLimiter = nil
+	return &testServer{Server: s, router: NewRouter(s)}
+}
+
+// do sends a request with an optional JSON body and headers given as
+// name, value pairs
+func (ts *testServer) do(method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	w := httptest.NewRecorder()
+	ts.router.ServeHTTP(w, req)
+	return w
+}
+
+// adminToken creates an admin user with role and returns an access token
+func (ts *testServer) adminToken(t *testing.T, role string) string {
+	t.Helper()
+
+	admin := models.AdminUser{Email: role + "@example.com", Name: role, Role: role}
+	if err := ts.repos.Admins.Create(&admin); err != nil {
+		t.Fatal(err)
+	}
+	token, err := issueToken(admin, tokenTypeAccess, accessTokenTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + token
+}
+
+// refreshToken returns a refresh token for a new admin, which must not be
+// accepted in place of an access token
+func (ts *testServer) refreshToken(t *testing.T) string {
+	t.Helper()
+
+	admin := models.AdminUser{Email: "refresh@example.com", Role: RoleAdmin}
+	if err := ts.repos.Admins.Create(&admin); err != nil {
+		t.Fatal(err)
+	}
+	token, err := issueToken(admin, tokenTypeRefresh, refreshTokenTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// addVehicle stores an available vehicle of a new brand
+func (ts *testServer) addVehicle(t *testing.T, vehicle models.Vehicle) *models.Vehicle {
+	t.Helper()
+
+	if vehicle.BrandID == 0 {
+		brand := models.Brand{Name: "Brand " + vehicle.Name}
+		if err := ts.Brands.Create(&brand); err != nil {
+			t.Fatal(err)
+		}
+		vehicle.BrandID = brand.ID
+	}
+	vehicle.Availability = true
+	if vehicle.FuelType == "" {
+		vehicle.FuelType = "Petrol"
+	}
+	if err := ts.Vehicles.Create(&vehicle); err != nil {
+		t.Fatal(err)
+	}
+	return &vehicle
+}
+
+// bookingJSON returns a booking request for vehicleID that passes the spam
+// checks, followed by any extra fields such as `"message":"Hi"`
+func bookingJSON(vehicleID uint, email string, extra ...string) string {
+	fields := []string{
+		fmt.Sprintf(`"vehicle_id":%d`, vehicleID),
+		`"customer_name":"Test Customer"`,
+		fmt.Sprintf(`"customer_email":%q`, email),
+		fmt.Sprintf(`"form_started_at":%q`, time.Now().Add(-time.Minute).Format(time.RFC3339)),
+	}
+	return "{" + strings.Join(append(fields, extra...), ",") + "}"
+}
+
+// decode unmarshals a JSON response body into v
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode %q: %v", w.Body.String(), err)
+	}
+}
+
+// errorBody is the JSON error envelope
+type errorBody struct {
+	Error struct {
+		Code      string       `json:"code"`
+		Message   string       `json:"message"`
+		Details   []FieldError `json:"details"`
+		RequestID string       `json:"request_id"`
+	} `json:"error"`
+}
+
+// expectError checks w is an error envelope with status and code
+func expectError(t *testing.T, w *httptest.ResponseRecorder, status int, code string) errorBody {
+	t.Helper()
+
+	var body errorBody
+	decode(t, w, &body)
+	if w.Code != status || body.Error.Code != code {
+		t.Fatalf("got %d %q, want %d %q: %s", w.Code, body.Error.Code, status, code, w.Body.String())
+	}
+	return body
+}
+
+func TestHealth(t *testing.T) {
+	ts := newTestServer(t)
+
+	for _, path := range []string{"/api/health", "/api/v1/health"} {
+		w := ts.do(http.MethodGet, path, "")
+		if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `{"status":"ok"}` {
+			t.Errorf("GET %s = %d %s", path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	ts := newTestServer(t)
+
+	t.Run("unknown route", func(t *testing.T) {
+		w := ts.do(http.MethodGet, "/api/nothing-here", "")
+		body := expectError(t, w, http.StatusNotFound, CodeNotFound)
+		if body.Error.RequestID == "" || body.Error.RequestID != w.Header().Get("X-Request-ID") {
+			t.Errorf("request_id %q does not match X-Request-ID %q", body.Error.RequestID, w.Header().Get("X-Request-ID"))
+		}
+	})
+
+	t.Run("caller's request ID", func(t *testing.T) {
+		w := ts.do(http.MethodGet, "/api/vehicles/999", "", "X-Request-ID", "trace-123")
+		body := expectError(t, w, http.StatusNotFound, CodeNotFound)
+		if body.Error.RequestID != "trace-123" {
+			t.Errorf("request_id = %q, want trace-123", body.Error.RequestID)
+		}
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		w := ts.do(http.MethodPost, "/api/bookings", "{not json")
+		expectError(t, w, http.StatusBadRequest, CodeInvalidJSON)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		w := ts.do(http.MethodPost, "/api/bookings", `{"vehicle_id":1}`)
+		body := expectError(t, w, http.StatusUnprocessableEntity, CodeValidationFailed)
+		if len(body.Error.Details) == 0 {
+			t.Error("validation error has no field details")
+		}
+	})
+}
+
+func TestAdminAuth(t *testing.T) {
+	ts := newTestServer(t)
+	vehicle := ts.addVehicle(t, models.Vehicle{Name: "Camry", Year: 2024, Price: 28000})
+	body := fmt.Sprintf(`{"brand_id":%d,"name":"Corolla","year":2024,"price":22000,"fuel_type":"Petrol"}`, vehicle.BrandID)
+
+	tests := []struct {
+		name   string
+		auth   string
+		status int
+		code   string
+	}{
+		{"no token", "", http.StatusUnauthorized, CodeUnauthorized},
+		{"malformed token", "Bearer not-a-jwt", http.StatusUnauthorized, CodeUnauthorized},
+		{"refresh token", "Bearer " + ts.refreshToken(t), http.StatusUnauthorized, CodeUnauthorized},
+		{"role without permission", ts.adminToken(t, RoleAnalyst), http.StatusForbidden, CodeForbidden},
+		{"permitted role", ts.adminToken(t, RoleInventoryManager), http.StatusCreated, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := ts.do(http.MethodPost, "/api/admin/vehicles", body, "Authorization", tt.auth)
+			if tt.code == "" {
+				if w.Code != tt.status {
+					t.Fatalf("got %d, want %d: %s", w.Code, tt.status, w.Body.String())
+				}
+				return
+			}
+			expectError(t, w, tt.status, tt.code)
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"vehicle-store-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testDriveSlotLength is the length of a bookable test drive slot and the
+// default duration when a request gives no end time
+const testDriveSlotLength = time.Hour
+
+// maxTestDriveLength is the longest test drive a customer may request, so one
+// booking cannot take a vehicle off the slot list for the whole day
+const maxTestDriveLength = 2 * testDriveSlotLength
+
+// testDriveBlockingStatuses are the booking statuses that hold a test drive
+// slot. Pending requests count so two customers cannot claim the same slot.
+var testDriveBlockingStatuses = []string{BookingPending, BookingContacted, BookingCompleted}
+
+// defaultDealerHours applies to dealers without configured hours:
+// Monday to Saturday, 09:00 to 18:00
+var defaultDealerHours = []models.DealerHours{
+	{Weekday: 1, OpensAt: "09:00", ClosesAt: "18:00"},
+	{Weekday: 2, OpensAt: "09:00", ClosesAt: "18:00"},
+	{Weekday: 3, OpensAt: "09:00", ClosesAt: "18:00"},
+	{Weekday: 4, OpensAt: "09:00", ClosesAt: "18:00"},
+	{Weekday: 5, OpensAt: "09:00", ClosesAt: "18:00"},
+	{Weekday: 6, OpensAt: "09:00", ClosesAt: "18:00"},
+}
+
+// errSlotTaken aborts a booking transaction whose test drive overlaps another
+var errSlotTaken = errors.New("test drive slot is already booked")
+
+// TestDriveError reports a test drive request outside the allowed times
+type TestDriveError struct {
+	Reason string
+}
+
+func (e *TestDriveError) Error() string {
+	return e.Reason
+}
+
+// dealerName returns the dealer part of a vehicle's DealerInfo, which has the
+// form "Dealer Name - (555) 123-4567"
+func dealerName(vehicle *models.Vehicle) string {
+	name, _, _ := strings.Cut(vehicle.DealerInfo, " - ")
+	return strings.TrimSpace(name)
+}
+
+// dealerHours returns the dealer's configured hours or the default schedule
+func (s *Server) dealerHours(dealer string) ([]models.DealerHours, error) {
+	hours, err := s.Hours.Get(dealer)
+	if err != nil {
+		return nil, err
+	}
+	if len(hours) == 0 {
+		return defaultDealerHours, nil
+	}
+	return hours, nil
+}
+
+// parseClock parses an HH:MM time of day
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// openingWindow returns when the dealer opens and closes on day, in day's
+// location, or ok=false when the dealer is closed that day
+func openingWindow(hours []models.DealerHours, day time.Time) (opens, closes time.Time, ok bool) {
+	midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+
+	for _, h := range hours {
+		if h.Weekday != int(day.Weekday()) {
+			continue
+		}
+
+		openOffset, err := parseClock(h.OpensAt)
+		if err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		closeOffset, err := parseClock(h.ClosesAt)
+		if err != nil || closeOffset <= openOffset {
+			return time.Time{}, time.Time{}, false
+		}
+
+		return midnight.Add(openOffset), midnight.Add(closeOffset), true
+	}
+
+	return time.Time{}, time.Time{}, false
+}
+
+// validateTestDriveWindow checks that [start, end) lies in the future, within
+// the dealer's opening hours on a single day, and is no longer than
+// maxTestDriveLength, which is reported as a validation error on requested_end
+func validateTestDriveWindow(hours []models.DealerHours, start, end time.Time) error {
+	if !end.After(start) {
+		return &TestDriveError{Reason: "Requested end must be after requested start"}
+	}
+
+	if end.Sub(start) > maxTestDriveLength {
+		return validationError(FieldError{
+			Field:   "requested_end",
+			Message: fmt.Sprintf("must be at most %d minutes after requested_start", int(maxTestDriveLength.Minutes())),
+		})
+	}
+
+	if !start.After(time.Now()) {
+		return &TestDriveError{Reason: "Requested start must be in the future"}
+	}
+
+	local := start.In(time.Local)
+	opens, closes, ok := openingWindow(hours, local)
+	if !ok {
+		return &TestDriveError{Reason: "Dealer is closed on the requested day"}
+	}
+
+	if start.Before(opens) || end.After(closes) {
+		return &TestDriveError{Reason: fmt.Sprintf("Test drives must be between %s and %s",
+			opens.Format("15:04"), closes.Format("15:04"))}
+	}
+
+	return nil
+}
+
+// GetVehicleSlots handles GET /api/vehicles/:id/slots?date=YYYY-MM-DD
+func (s *Server) GetVehicleSlots(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		respondError(c, notFoundError("Vehicle"))
+		return
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", c.Query("date"), time.Local)
+	if err != nil {
+		respondError(c, badRequestError("date must be formatted as YYYY-MM-DD"))
+		return
+	}
+
+	vehicle, err := s.Vehicles.GetByID(id)
+	if err != nil {
+		respondError(c, notFoundError("Vehicle"))
+		return
+	}
+
+	hours, err := s.dealerHours(dealerName(vehicle))
+	if err != nil {
+		respondError(c, internalError("Failed to fetch dealer hours", err))
+		return
+	}
+
+	type slot struct {
+		Start     time.Time `json:"start"`
+		End       time.Time `json:"end"`
+		Available bool      `json:"available"`
+	}
+	slots := []slot{}
+
+	opens, closes, open := openingWindow(hours, day)
+	if open {
+		booked, err := s.Bookings.ListTestDrives(vehicle.ID, opens, closes, testDriveBlockingStatuses)
+		if err != nil {
+			respondError(c, internalError("Failed to fetch test drives", err))
+			return
+		}
+
+		now := time.Now()
+		for start := opens; !start.Add(testDriveSlotLength).After(closes); start = start.Add(testDriveSlotLength) {
+			end := start.Add(testDriveSlotLength)
+			available := vehicle.Availability && start.After(now)
+			for _, b := range booked {
+				if b.RequestedStart.Before(end) && b.RequestedEnd.After(start) {
+					available = false
+					break
+				}
+			}
+			slots = append(slots, slot{Start: start, End: end, Available: available})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"vehicle_id": vehicle.ID,
+		"date":       day.Format("2006-01-02"),
+		"open":       open,
+		"slots":      slots,
+	})
+}
+
+// GetDealerHours handles GET /api/admin/dealer-hours/:dealer
+func (s *Server) GetDealerHours(c *gin.Context) {
+	dealer := c.Param("dealer")
+
+	hours, err := s.Hours.Get(dealer)
+	if err != nil {
+		respondError(c, internalError("Failed to fetch dealer hours", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dealer":     dealer,
+		"configured": len(hours) > 0,
+		"hours":      hours,
+	})
+}
+
+// UpdateDealerHours handles PUT /api/admin/dealer-hours/:dealer
+func (s *Server) UpdateDealerHours(c *gin.Context) {
+	dealer := c.Param("dealer")
+
+	var body struct {
+		Hours []models.DealerHours `json:"hours"`
+	}
+
+	if !bindJSON(c, &body) {
+		return
+	}
+
+	seen := make(map[int]bool)
+	for _, h := range body.Hours {
+		if h.Weekday < 0 || h.Weekday > 6 || seen[h.Weekday] {
+			respondError(c, validationError(FieldError{Field: "hours", Message: "may list each weekday (0-6) at most once"}))
+			return
+		}
+		seen[h.Weekday] = true
+
+		opens, err1 := parseClock(h.OpensAt)
+		closes, err2 := parseClock(h.ClosesAt)
+		if err1 != nil || err2 != nil || closes <= opens {
+			respondError(c, validationError(FieldError{Field: "hours", Message: "opens_at and closes_at must be HH:MM with opens_at before closes_at"}))
+			return
+		}
+	}
+
+	if err := s.Hours.Replace(dealer, body.Hours); err != nil {
+		respondError(c, internalError("Failed to update dealer hours", err))
+		return
+	}
+
+	s.GetDealerHours(c)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"vehicle-store-backend/internal/models"
+)
+
+// nextWeekday returns the first day after today that falls on weekday, at
+// hour:00 local time
+func nextWeekday(weekday time.Weekday, hour int) time.Time {
+	now := time.Now()
+	day := time.Date(now.Year(), now.Month(), now.Day()+1, hour, 0, 0, 0, time.Local)
+	for day.Weekday() != weekday {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// testDriveJSON returns extra booking fields requesting a test drive at start
+func testDriveJSON(start time.Time) string {
+	return fmt.Sprintf(`"requested_start":%q`, start.Format(time.RFC3339))
+}
+
+func TestTestDriveSlots(t *testing.T) {
+	ts := newTestServer(t)
+	auth := ts.adminToken(t, RoleSales)
+	vehicle := ts.addVehicle(t, models.Vehicle{Name: "Model 3", Year: 2024, Price: 45000})
+	monday := nextWeekday(time.Monday, 10)
+
+	book := func(email string, start time.Time) *models.Booking {
+		t.Helper()
+		w := ts.do(http.MethodPost, "/api/bookings", bookingJSON(vehicle.ID, email, testDriveJSON(start)))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("book %s at %s: %d %s", email, start, w.Code, w.Body.String())
+		}
+		var booking models.Booking
+		decode(t, w, &booking)
+		return &booking
+	}
+	expectRejected := func(email string, start time.Time, status int, code string) {
+		t.Helper()
+		w := ts.do(http.MethodPost, "/api/bookings", bookingJSON(vehicle.ID, email, testDriveJSON(start)))
+		expectError(t, w, status, code)
+	}
+
+	first := book("first@example.com", monday)
+	if want := monday.Add(testDriveSlotLength); !first.RequestedEnd.Equal(want) {
+		t.Errorf("requested_end = %s, want %s", first.RequestedEnd, want)
+	}
+
+	// Overlapping the booked hour, whether pending or not
+	expectRejected("second@example.com", monday.Add(30*time.Minute), http.StatusConflict, CodeSlotTaken)
+	expectRejected("second@example.com", monday.Add(-30*time.Minute), http.StatusConflict, CodeSlotTaken)
+
+	// The half-open interval lets the next slot start when this one ends
+	book("second@example.com", monday.Add(testDriveSlotLength))
+
+	// Outside the default opening hours
+	expectRejected("third@example.com", nextWeekday(time.Sunday, 10), http.StatusBadRequest, CodeInvalidTestDrive)
+	expectRejected("third@example.com", monday.Add(7*time.Hour+30*time.Minute), http.StatusBadRequest, CodeInvalidTestDrive)
+	expectRejected("third@example.com", monday.AddDate(0, 0, -14), http.StatusBadRequest, CodeInvalidTestDrive)
+
+	// Suspected spam does not hold a slot
+	spam := fmt.Sprintf(`{"vehicle_id":%d,"customer_name":"Bot","customer_email":"bot@example.com","website":"x",%s}`,
+		vehicle.ID, testDriveJSON(monday.Add(4*time.Hour)))
+	if w := ts.do(http.MethodPost, "/api/bookings", spam); w.Code != http.StatusCreated {
+		t.Fatalf("spam booking: %d %s", w.Code, w.Body.String())
+	}
+	book("third@example.com", monday.Add(4*time.Hour))
+
+	w := ts.do(http.MethodGet, fmt.Sprintf("/api/vehicles/%d/slots?date=%s", vehicle.ID, monday.Format("2006-01-02")), "")
+	var slots struct {
+		Open  bool `json:"open"`
+		Slots []struct {
+			Start     time.Time `json:"start"`
+			Available bool      `json:"available"`
+		} `json:"slots"`
+	}
+	decode(t, w, &slots)
+	var taken []string
+	for _, slot := range slots.Slots {
+		if !slot.Available {
+			taken = append(taken, slot.Start.In(time.Local).Format("15:04"))
+		}
+	}
+	if got := strings.Join(taken, ","); !slots.Open || got != "10:00,11:00,14:00" {
+		t.Errorf("taken slots = %q (open %v), want 10:00,11:00,14:00", got, slots.Open)
+	}
+
+	// Cancelling frees the slot
+	path := fmt.Sprintf("/api/admin/bookings/%d", first.ID)
+	if w := ts.do(http.MethodPut, path, `{"status":"cancelled"}`, "Authorization", auth, "If-Match", `"1"`); w.Code != http.StatusOK {
+		t.Fatalf("cancel: %d %s", w.Code, w.Body.String())
+	}
+	book("fourth@example.com", monday)
+}
+
+func TestTestDriveMaxLength(t *testing.T) {
+	ts := newTestServer(t)
+	vehicle := ts.addVehicle(t, models.Vehicle{Name: "Model 3", Year: 2024, Price: 45000})
+	monday := nextWeekday(time.Monday, 10)
+
+	request := func(email string, length time.Duration) string {
+		end := fmt.Sprintf(`"requested_end":%q`, monday.Add(length).Format(time.RFC3339))
+		return bookingJSON(vehicle.ID, email, testDriveJSON(monday), end)
+	}
+
+	w := ts.do(http.MethodPost, "/api/bookings", request("long@example.com", maxTestDriveLength+time.Minute))
+	body := expectError(t, w, http.StatusUnprocessableEntity, CodeValidationFailed)
+	if len(body.Error.Details) != 1 || body.Error.Details[0].Field != "requested_end" {
+		t.Errorf("details = %+v, want field requested_end", body.Error.Details)
+	}
+
+	w = ts.do(http.MethodPost, "/api/bookings", request("max@example.com", maxTestDriveLength))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("booking the longest test drive = %d: %s", w.Code, w.Body.String())
+	}
+}
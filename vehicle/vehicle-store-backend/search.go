@@ -0,0 +1,259 @@
+package main
+
+import (
+	"html"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"vehicle-store-backend/internal/models"
+)
+
+const (
+	// maxSearchTerms caps the words taken from a search so a pasted paragraph
+	// cannot build an enormous query
+	maxSearchTerms = 8
+	// snippetWords is how many words a search snippet shows
+	snippetWords = 12
+	// Markers wrapped around matched words in search snippets
+	snippetOpen  = "<mark>"
+	snippetClose = "</mark>"
+	snippetGap   = "…"
+	// Stand-ins for the markers while a snippet is escaped. They are in the
+	// private use area, so vehicle text will not contain them.
+	snippetOpenPlaceholder  = "\uE000"
+	snippetClosePlaceholder = "\uE001"
+)
+
+// snippetMarkers swaps the placeholders in an escaped snippet for the markers
+var snippetMarkers = strings.NewReplacer(snippetOpenPlaceholder, snippetOpen, snippetClosePlaceholder, snippetClose)
+
+// markSnippet HTML-escapes a snippet built with the marker placeholders, then
+// puts the markers in, so the result is safe to render as HTML
+func markSnippet(raw string) string {
+	return snippetMarkers.Replace(html.EscapeString(raw))
+}
+
+// vehicleSearchWeights rank a match by the field it is in, in the column
+// order of the full-text index: name, model, brand, description, engine
+// specs, safety features, fuel type, transmission
+var vehicleSearchWeights = []float64{10, 8, 6, 1, 2, 1, 4, 3}
+
+// joinWeights formats weights as the column arguments of bm25
+func joinWeights(weights []float64) string {
+	parts := make([]string, len(weights))
+	for i, w := range weights {
+		parts[i] = strconv.FormatFloat(w, 'f', -1, 64)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// vehicleSearchFields returns the searchable text of v in the column order of
+// the full-text index; v.Brand must be loaded
+func vehicleSearchFields(v models.Vehicle) []string {
+	return []string{
+		v.Name, v.Model, v.Brand.Name, v.Description, v.EngineSpecs, v.SafetyFeatures,
+		v.FuelType, v.Transmission,
+	}
+}
+
+// searchGroup is one word of a search followed by the alternatives it may
+// match instead. Each entry is a phrase of one or more words.
+type searchGroup [][]string
+
+// searchGroups splits filter's search into groups, every one of which a
+// vehicle must match, adding the alternatives from filter.Expansions
+func searchGroups(filter models.VehicleFilter) []searchGroup {
+	terms := searchTerms(filter.Search)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	groups := make([]searchGroup, len(terms))
+	for i, term := range terms {
+		group := searchGroup{{term}}
+		for _, alternative := range filter.Expansions[term] {
+			if words := searchWords(alternative); len(words) > 0 {
+				group = append(group, words)
+			}
+		}
+		groups[i] = group
+	}
+	return groups
+}
+
+// searchGroupWords returns every word of groups, for marking snippets
+func searchGroupWords(groups []searchGroup) []string {
+	var words []string
+	for _, group := range groups {
+		for _, phrase := range group {
+			words = append(words, phrase...)
+		}
+	}
+	return words
+}
+
+// searchTerms splits a search into lowercase words, dropping punctuation and
+// repeats
+func searchTerms(search string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, word := range searchWords(search) {
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+		if len(terms) == maxSearchTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// searchWords splits text into lowercase words, dropping punctuation
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), isSearchSeparator)
+}
+
+func isSearchSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// ftsQuery builds an FTS5 query matching rows that match every group, where
+// a group matches if one of its phrases appears with its last word as a
+// prefix. Words come from searchWords, so they hold no quotes.
+func ftsQuery(groups []searchGroup) string {
+	parts := make([]string, len(groups))
+	for i, group := range groups {
+		phrases := make([]string, len(group))
+		for j, phrase := range group {
+			phrases[j] = `"` + strings.Join(phrase, " ") + `"*`
+		}
+		if len(phrases) == 1 {
+			parts[i] = phrases[0]
+		} else {
+			parts[i] = "(" + strings.Join(phrases, " OR ") + ")"
+		}
+	}
+	// FTS5 only allows an implicit AND between plain phrases
+	return strings.Join(parts, " AND ")
+}
+
+// scoreVehicleSearch ranks fields, as returned by vehicleSearchFields, much
+// as the full-text index does: a group matches a field if every word of one
+// of its phrases starts a word there, every group must match some field, and
+// each match adds its field's weight. ok is false if a group matches nowhere.
+// best is the field with the most matching groups, for the snippet.
+func scoreVehicleSearch(fields []string, groups []searchGroup) (score float64, best int, ok bool) {
+	hits := make([]int, len(fields))
+	for _, group := range groups {
+		matched := false
+		for i, field := range fields {
+			if matchesSearchGroup(field, group) {
+				score += vehicleSearchWeights[i]
+				hits[i]++
+				matched = true
+			}
+		}
+		if !matched {
+			return 0, 0, false
+		}
+	}
+
+	for i := range hits {
+		if hits[i] > hits[best] {
+			best = i
+		}
+	}
+	return score, best, true
+}
+
+// matchesSearchGroup reports whether every word of one of group's phrases
+// starts a word in text
+func matchesSearchGroup(text string, group searchGroup) bool {
+	for _, phrase := range group {
+		matched := true
+		for _, word := range phrase {
+			if !containsWordPrefix(text, word) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// containsWordPrefix reports whether a word in text starts with term
+func containsWordPrefix(text, term string) bool {
+	for _, word := range searchWords(text) {
+		if strings.HasPrefix(word, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// searchSnippet returns an excerpt of text around the first word matching
+// one of terms, with matching words marked like the full-text index's
+// snippet function does, escaped by markSnippet
+func searchSnippet(text string, terms []string) string {
+	words := strings.Fields(text)
+
+	first := 0
+	for i, word := range words {
+		if matchesAnyTerm(word, terms) {
+			first = i
+			break
+		}
+	}
+
+	// Show a little context before the first match
+	start := max(0, min(first-2, len(words)-snippetWords))
+	end := min(len(words), start+snippetWords)
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString(snippetGap)
+	}
+	for i := start; i < end; i++ {
+		if i > start {
+			b.WriteByte(' ')
+		}
+		if matchesAnyTerm(words[i], terms) {
+			b.WriteString(snippetOpenPlaceholder + words[i] + snippetClosePlaceholder)
+		} else {
+			b.WriteString(words[i])
+		}
+	}
+	if end < len(words) {
+		b.WriteString(snippetGap)
+	}
+	return markSnippet(b.String())
+}
+
+// matchesAnyTerm reports whether a whitespace-separated word of text starts
+// with one of terms once its punctuation is ignored
+func matchesAnyTerm(word string, terms []string) bool {
+	for _, term := range terms {
+		if containsWordPrefix(word, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// setSearchSnippets fills in the snippet of each vehicle matching groups;
+// the vehicles' brands must be loaded
+func setSearchSnippets(vehicles []models.Vehicle, groups []searchGroup) {
+	words := searchGroupWords(groups)
+	for i := range vehicles {
+		fields := vehicleSearchFields(vehicles[i])
+		if _, best, ok := scoreVehicleSearch(fields, groups); ok {
+			vehicles[i].SearchSnippet = searchSnippet(fields[best], words)
+		}
+	}
+}
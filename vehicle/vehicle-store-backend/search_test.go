@@ -0,0 +1,169 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"vehicle-store-backend/internal/models"
+)
+
+// searchNames runs search through the vehicle repository and returns the
+// names of the results in order, with their snippets by name
+func (ts *testServer) searchNames(t *testing.T, search string) ([]string, map[string]string) {
+	t.Helper()
+
+	vehicles, _, err := ts.Vehicles.List(models.VehicleFilter{Search: search, Limit: 20})
+	if err != nil {
+		t.Fatalf("search %q: %v", search, err)
+	}
+
+	var names []string
+	snippets := make(map[string]string)
+	for _, v := range vehicles {
+		names = append(names, v.Name)
+		snippets[v.Name] = v.SearchSnippet
+	}
+	return names, snippets
+}
+
+func TestVehicleSearchIndex(t *testing.T) {
+	ts := newGormTestServer(t)
+
+	brand := models.Brand{Name: "Toyota"}
+	if err := ts.Brands.Create(&brand); err != nil {
+		t.Fatal(err)
+	}
+	camry := ts.addVehicle(t, models.Vehicle{
+		BrandID:        brand.ID,
+		Name:           "Camry",
+		Model:          "XLE",
+		Year:           2024,
+		Price:          28000,
+		Description:    "Panoramic sunroof and <b>heated</b> seats",
+		EngineSpecs:    "2.5L hybrid",
+		SafetyFeatures: "Lane keeping assist",
+	})
+	ts.addVehicle(t, models.Vehicle{
+		BrandID:     brand.ID,
+		Name:        "Sunroof Edition",
+		Year:        2023,
+		Price:       31000,
+		Description: "Limited run",
+	})
+	ts.addVehicle(t, models.Vehicle{BrandID: brand.ID, Name: "Corolla", Year: 2024, Price: 22000, Description: "Compact sedan"})
+
+	t.Run("indexes every text column", func(t *testing.T) {
+		for search, want := range map[string][]string{
+			"camry":  {"Camry"},
+			"xle":    {"Camry"},
+			"toyota": {"Camry", "Sunroof Edition", "Corolla"},
+			"seats":  {"Camry"},
+			"hybrid": {"Camry"},
+			"lane":   {"Camry"},
+		} {
+			if got, _ := ts.searchNames(t, search); !sameNames(got, want) {
+				t.Errorf("search %q = %v, want %v", search, got, want)
+			}
+		}
+	})
+
+	t.Run("prefixes of several words", func(t *testing.T) {
+		for search, want := range map[string][]string{
+			"pano sun":    {"Camry"},
+			"sun toy":     {"Camry", "Sunroof Edition"},
+			"pano compac": nil,
+			"cor sed":     {"Corolla"},
+		} {
+			if got, _ := ts.searchNames(t, search); !sameNames(got, want) {
+				t.Errorf("search %q = %v, want %v", search, got, want)
+			}
+		}
+	})
+
+	t.Run("ranked by relevance", func(t *testing.T) {
+		// A name match outranks a description match, whatever the IDs
+		got, _ := ts.searchNames(t, "sunroof")
+		if want := []string{"Sunroof Edition", "Camry"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("search sunroof = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("snippets are escaped and marked", func(t *testing.T) {
+		_, snippets := ts.searchNames(t, "heated")
+		want := "Panoramic sunroof and &lt;b&gt;<mark>heated</mark>&lt;/b&gt; seats"
+		if snippets["Camry"] != want {
+			t.Errorf("snippet = %q, want %q", snippets["Camry"], want)
+		}
+	})
+
+	t.Run("kept in sync", func(t *testing.T) {
+		camry.Description = "Cloth seats"
+		if err := ts.Vehicles.Update(camry); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := ts.searchNames(t, "sunroof"); !sameNames(got, []string{"Sunroof Edition"}) {
+			t.Errorf("after update, search sunroof = %v", got)
+		}
+		if got, _ := ts.searchNames(t, "cloth"); !sameNames(got, []string{"Camry"}) {
+			t.Errorf("after update, search cloth = %v", got)
+		}
+
+		brand.Name = "Lexus"
+		if err := ts.Brands.Update(&brand); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := ts.searchNames(t, "lexus"); len(got) != 3 {
+			t.Errorf("after brand rename, search lexus = %v", got)
+		}
+		if got, _ := ts.searchNames(t, "toyota"); len(got) != 0 {
+			t.Errorf("after brand rename, search toyota = %v", got)
+		}
+
+		if err := ts.Vehicles.Delete(camry.ID); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := ts.searchNames(t, "cloth"); len(got) != 0 {
+			t.Errorf("after delete, search cloth = %v", got)
+		}
+	})
+}
+
+func TestSearchSnippet(t *testing.T) {
+	tests := []struct {
+		text  string
+		terms []string
+		want  string
+	}{
+		{"Panoramic sunroof", []string{"sun"}, "Panoramic <mark>sunroof</mark>"},
+		{"Fits 5 & tows <2t>", []string{"tows"}, "Fits 5 &amp; <mark>tows</mark> &lt;2t&gt;"},
+		{
+			"one two three four five six seven eight nine ten eleven twelve thirteen fourteen",
+			[]string{"twelve"},
+			"…three four five six seven eight nine ten eleven <mark>twelve</mark> thirteen fourteen",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := searchSnippet(tt.text, tt.terms); got != tt.want {
+			t.Errorf("searchSnippet(%q, %q) = %q, want %q", tt.text, tt.terms, got, tt.want)
+		}
+	}
+}
+
+// sameNames reports whether got and want hold the same names in any order
+func sameNames(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	seen := make(map[string]int)
+	for _, name := range got {
+		seen[name]++
+	}
+	for _, name := range want {
+		if seen[name] == 0 {
+			return false
+		}
+		seen[name]--
+	}
+	return true
+}
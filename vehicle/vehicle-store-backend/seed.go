@@ -0,0 +1,248 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"time"
+
+	"vehicle-store-backend/internal/models"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// embeddedFixtures holds the built-in fixture sets, one file per environment
+//
+//go:embed fixtures
+var embeddedFixtures embed.FS
+
+// FixtureSet is the seed data for one environment. Vehicles reference their
+// brand by name and bookings reference their vehicle by brand, name and model.
+type FixtureSet struct {
+	Brands   []models.Brand   `json:"brands"`
+	Vehicles []VehicleFixture `json:"vehicles"`
+	Bookings []BookingFixture `json:"bookings"`
+}
+
+// VehicleFixture is a vehicle whose brand is given by name
+type VehicleFixture struct {
+	models.Vehicle
+	Brand string `json:"brand"`
+	// Available shadows Vehicle.Availability so an omitted value means true
+	Available *bool `json:"availability"`
+}
+
+// BookingFixture is a booking whose vehicle is given by brand, name and model
+type BookingFixture struct {
+	models.Booking
+	Brand   string `json:"brand"`
+	Vehicle string `json:"vehicle"`
+	Model   string `json:"model"`
+}
+
+// SeedData loads the fixture set named by SEED_ENV (demo, test or empty) from
+// SEED_DIR, or from the embedded fixtures when SEED_DIR is unset. Seeding is
+// insert-only by natural key, not an upsert: fixture records already in the
+// database are never updated, so edits made since the last run are kept.
+func SeedData(db *gorm.DB) {
+	env := GetEnv("SEED_ENV", "demo")
+
+	var fsys fs.FS
+	if dir := GetEnv("SEED_DIR", ""); dir != "" {
+		fsys = os.DirFS(dir)
+	} else {
+		sub, err := fs.Sub(embeddedFixtures, "fixtures")
+		if err != nil {
+			log.Fatal("Failed to open embedded fixtures:", err)
+		}
+		fsys = sub
+	}
+
+	if err := SeedFixtures(db, fsys, env); err != nil {
+		log.Fatal("Failed to seed database:", err)
+	}
+
+	log.Printf("Database seeded successfully (%s)", env)
+}
+
+// SeedFixtures inserts the records of the fixture set for env found in fsys
+// that are missing. Records are matched on their natural keys (brand name;
+// brand, name and model for vehicles; vehicle and customer email for bookings)
+// and existing ones are left as they are, so running it on every start keeps
+// changes made since. A seeded record that was deleted is inserted again.
+func SeedFixtures(db *gorm.DB, fsys fs.FS, env string) error {
+	if env == "empty" {
+		return nil
+	}
+
+	set, err := LoadFixtures(fsys, env)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		brandIDs := make(map[string]uint)
+		for _, brand := range set.Brands {
+			if err := seedBrand(tx, &brand); err != nil {
+				return fmt.Errorf("brand %q: %w", brand.Name, err)
+			}
+			brandIDs[brand.Name] = brand.ID
+		}
+
+		for _, f := range set.Vehicles {
+			brandID, err := lookupBrandID(tx, brandIDs, f.Brand)
+			if err != nil {
+				return fmt.Errorf("vehicle %q: %w", f.Name, err)
+			}
+
+			vehicle := f.Vehicle
+			vehicle.BrandID = brandID
+			vehicle.Availability = f.Available == nil || *f.Available
+			if err := seedVehicle(tx, &vehicle); err != nil {
+				return fmt.Errorf("vehicle %q: %w", f.Name, err)
+			}
+		}
+
+		for _, f := range set.Bookings {
+			brandID, err := lookupBrandID(tx, brandIDs, f.Brand)
+			if err != nil {
+				return fmt.Errorf("booking for %q: %w", f.CustomerEmail, err)
+			}
+
+			var vehicle models.Vehicle
+			if err := tx.Where("brand_id = ? AND name = ? AND model = ?", brandID, f.Vehicle, f.Model).
+				First(&vehicle).Error; err != nil {
+				return fmt.Errorf("booking for %q: vehicle %s %s %s: %w", f.CustomerEmail, f.Brand, f.Vehicle, f.Model, err)
+			}
+
+			booking := f.Booking
+			booking.VehicleID = vehicle.ID
+			if booking.Status == "" {
+				booking.Status = "pending"
+			}
+			if err := seedBooking(tx, &booking); err != nil {
+				return fmt.Errorf("booking for %q: %w", f.CustomerEmail, err)
+			}
+		}
+
+		return holdReservedVehicles(tx, time.Now())
+	})
+}
+
+// holdReservedVehicles takes every vehicle with an active hold off sale again,
+// so a hold outlives a restart whatever the fixtures say about availability
+func holdReservedVehicles(tx *gorm.DB, now time.Time) error {
+	held := tx.Model(&models.Reservation{}).Select("vehicle_id").
+		Where("released_at IS NULL AND expires_at > ?", now)
+	return tx.Model(&models.Vehicle{}).Where("id IN (?) AND availability = ?", held, true).
+		Update("availability", false).Error
+}
+
+// LoadFixtures reads <env>.json, <env>.yaml or <env>.yml from fsys
+func LoadFixtures(fsys fs.FS, env string) (*FixtureSet, error) {
+	for _, ext := range []string{".json", ".yaml", ".yml"} {
+		data, err := fs.ReadFile(fsys, env+ext)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return parseFixtures(data, path.Ext(env+ext))
+	}
+
+	return nil, fmt.Errorf("no fixtures found for environment %q", env)
+}
+
+// parseFixtures decodes a fixture file. YAML is converted to JSON first so
+// both formats share the models' json field names.
+func parseFixtures(data []byte, ext string) (*FixtureSet, error) {
+	if ext != ".json" {
+		var doc interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+
+		converted, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		data = converted
+	}
+
+	var set FixtureSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// lookupBrandID resolves a brand name from this fixture set or the database
+func lookupBrandID(tx *gorm.DB, brandIDs map[string]uint, name string) (uint, error) {
+	if id, ok := brandIDs[name]; ok {
+		return id, nil
+	}
+
+	var brand models.Brand
+	if err := tx.Where("name = ?", name).First(&brand).Error; err != nil {
+		return 0, fmt.Errorf("brand %q: %w", name, err)
+	}
+	brandIDs[name] = brand.ID
+	return brand.ID, nil
+}
+
+// seedBrand creates brand unless a brand with the same name exists, in which
+// case brand takes its ID
+func seedBrand(tx *gorm.DB, brand *models.Brand) error {
+	var existing models.Brand
+	err := tx.Where("name = ?", brand.Name).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return tx.Omit(clause.Associations).Create(brand).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	brand.ID = existing.ID
+	return nil
+}
+
+// seedVehicle creates vehicle unless a vehicle with the same brand, name and
+// model exists
+func seedVehicle(tx *gorm.DB, vehicle *models.Vehicle) error {
+	var existing models.Vehicle
+	err := tx.Where("brand_id = ? AND name = ? AND model = ?", vehicle.BrandID, vehicle.Name, vehicle.Model).
+		First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// GORM writes the column default in place of a false value and copies
+		// it back into vehicle, so note the availability before creating
+		available := vehicle.Availability
+		if err := tx.Omit(clause.Associations).Create(vehicle).Error; err != nil {
+			return err
+		}
+		if !available {
+			return tx.Model(vehicle).Update("availability", false).Error
+		}
+		return nil
+	}
+	return err
+}
+
+// seedBooking creates booking unless the same customer already booked the
+// same vehicle
+func seedBooking(tx *gorm.DB, booking *models.Booking) error {
+	var existing models.Booking
+	err := tx.Where("vehicle_id = ? AND customer_email = ?", booking.VehicleID, booking.CustomerEmail).
+		First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return tx.Omit(clause.Associations).Create(booking).Error
+	}
+	return err
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"vehicle-store-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// fixtureFS returns the embedded fixture sets
+func fixtureFS(t *testing.T) fs.FS {
+	t.Helper()
+
+	fsys, err := fs.Sub(embeddedFixtures, "fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fsys
+}
+
+// migratedSQLite returns an empty SQLite database with every migration applied
+func migratedSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db := openSQLite(t)
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// countRows returns the number of brands, vehicles and bookings in db
+func countRows(t *testing.T, db *gorm.DB) [3]int64 {
+	t.Helper()
+
+	var counts [3]int64
+	for i, model := range []interface{}{&models.Brand{}, &models.Vehicle{}, &models.Booking{}} {
+		if err := db.Model(model).Count(&counts[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return counts
+}
+
+func TestLoadFixtures(t *testing.T) {
+	fsys := fixtureFS(t)
+
+	tests := []struct {
+		env                        string
+		brands, vehicles, bookings int
+	}{
+		{"demo", 8, 8, 2},
+		{"test", 2, 3, 1},
+	}
+
+	for _, tt := range tests {
+		set, err := LoadFixtures(fsys, tt.env)
+		if err != nil {
+			t.Fatalf("LoadFixtures(%q): %v", tt.env, err)
+		}
+		if len(set.Brands) != tt.brands || len(set.Vehicles) != tt.vehicles || len(set.Bookings) != tt.bookings {
+			t.Errorf("%s fixtures have %d brands, %d vehicles and %d bookings; want %d, %d and %d", tt.env,
+				len(set.Brands), len(set.Vehicles), len(set.Bookings), tt.brands, tt.vehicles, tt.bookings)
+		}
+	}
+
+	// YAML uses the same field names as JSON
+	set, err := LoadFixtures(fsys, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	camry := set.Vehicles[0]
+	if camry.Brand != "Toyota" || camry.Name != "Camry" || camry.Model != "LE" || camry.Price != 28750 || camry.FuelType != "Petrol" {
+		t.Errorf("first test vehicle = %+v", camry)
+	}
+	if camry.Available != nil || set.Vehicles[2].Available == nil || *set.Vehicles[2].Available {
+		t.Error("availability is only set where the fixture gives it")
+	}
+
+	if _, err := LoadFixtures(fsys, "staging"); err == nil {
+		t.Error("LoadFixtures found fixtures for an unknown environment")
+	}
+
+	// A SEED_DIR may hold .yml files too
+	dir := fstest.MapFS{"local.yml": {Data: []byte("brands:\n  - name: Lada\n")}}
+	set, err = LoadFixtures(dir, "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Brands) != 1 || set.Brands[0].Name != "Lada" {
+		t.Errorf("local fixtures = %+v", set)
+	}
+}
+
+func TestSeedFixtures(t *testing.T) {
+	fsys := fixtureFS(t)
+
+	t.Run("empty", func(t *testing.T) {
+		db := migratedSQLite(t)
+		if err := SeedFixtures(db, fsys, "empty"); err != nil {
+			t.Fatal(err)
+		}
+		if got := countRows(t, db); got != [3]int64{} {
+			t.Errorf("empty seeded %v", got)
+		}
+	})
+
+	for env, want := range map[string][3]int64{"demo": {8, 8, 2}, "test": {2, 3, 1}} {
+		t.Run(env, func(t *testing.T) {
+			db := migratedSQLite(t)
+			if err := SeedFixtures(db, fsys, env); err != nil {
+				t.Fatal(err)
+			}
+			if got := countRows(t, db); got != want {
+				t.Errorf("seeded %v brands, vehicles and bookings; want %v", got, want)
+			}
+		})
+	}
+
+	t.Run("availability", func(t *testing.T) {
+		db := migratedSQLite(t)
+		if err := SeedFixtures(db, fsys, "test"); err != nil {
+			t.Fatal(err)
+		}
+
+		for name, want := range map[string]bool{"Camry": true, "Model 3": false} {
+			var vehicle models.Vehicle
+			if err := db.Where("name = ?", name).First(&vehicle).Error; err != nil {
+				t.Fatal(err)
+			}
+			if vehicle.Availability != want {
+				t.Errorf("%s availability = %v, want %v", name, vehicle.Availability, want)
+			}
+		}
+	})
+
+	t.Run("re-run is insert-only", func(t *testing.T) {
+		db := migratedSQLite(t)
+		if err := SeedFixtures(db, fsys, "test"); err != nil {
+			t.Fatal(err)
+		}
+
+		// Changes an admin might make after the first start
+		if err := db.Model(&models.Vehicle{}).Where("name = ?", "Camry").
+			Updates(map[string]interface{}{"price": 25000, "availability": false}).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Model(&models.Booking{}).Where("customer_email = ?", "test.customer@example.com").
+			Update("status", "confirmed").Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Where("name = ?", "Prius").Delete(&models.Vehicle{}).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		if err := SeedFixtures(db, fsys, "test"); err != nil {
+			t.Fatal(err)
+		}
+
+		// Nothing is duplicated and the deleted vehicle is inserted again
+		if got, want := countRows(t, db), [3]int64{2, 3, 1}; got != want {
+			t.Errorf("re-run left %v brands, vehicles and bookings; want %v", got, want)
+		}
+
+		// Records already there are not updated from the fixtures
+		var camry models.Vehicle
+		if err := db.Where("name = ?", "Camry").First(&camry).Error; err != nil {
+			t.Fatal(err)
+		}
+		if camry.Price != 25000 || camry.Availability {
+			t.Errorf("re-run overwrote the Camry: price %v, availability %v", camry.Price, camry.Availability)
+		}
+		var booking models.Booking
+		if err := db.Where("customer_email = ?", "test.customer@example.com").First(&booking).Error; err != nil {
+			t.Fatal(err)
+		}
+		if booking.Status != "confirmed" {
+			t.Errorf("re-run reset the booking status to %q", booking.Status)
+		}
+	})
+
+	t.Run("demo on top of test", func(t *testing.T) {
+		db := migratedSQLite(t)
+		for _, env := range []string{"test", "demo"} {
+			if err := SeedFixtures(db, fsys, env); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// Brands are shared by name, so the demo set adds none of its own twice
+		var toyotas int64
+		if err := db.Model(&models.Brand{}).Where("name = ?", "Toyota").Count(&toyotas).Error; err != nil {
+			t.Fatal(err)
+		}
+		if toyotas != 1 {
+			t.Errorf("%d brands named Toyota, want 1", toyotas)
+		}
+	})
+}
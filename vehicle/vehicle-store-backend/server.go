@@ -0,0 +1,93 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Server holds the dependencies shared by the HTTP handlers
+type Server struct {
+	Vehicles  VehicleRepository
+	Brands    BrandRepository
+	Bookings  BookingRepository
+	Analytics AnalyticsRepository
+	Admins    AdminUserRepository
+	Holds     ReservationRepository
+	Events    OutboxRepository
+	Webhooks  WebhookRepository
+	Hours     DealerHoursRepository
+	Replays   IdempotencyRepository
+	Synonyms  SynonymRepository
+	Queries   SearchQueryRepository
+	Tx        Transactor
+	Notifier  Notifier
+
+	// IdempotencyTTL is how long a response is kept for replay under its
+	// Idempotency-Key
+	IdempotencyTTL time.Duration
+	// RateLimiter throttles the public routes; nil disables rate limiting
+	RateLimiter *RateLimiter
+	// Suggestions completes searches from the inventory and past searches
+	Suggestions *SuggestIndex
+}
+
+// NewServer returns a Server using GORM repositories backed by db
+func NewServer(db *gorm.DB) *Server {
+	s := &Server{
+		Vehicles:  NewGormVehicleRepository(db),
+		Brands:    NewGormBrandRepository(db),
+		Bookings:  NewGormBookingRepository(db),
+		Analytics: NewGormAnalyticsRepository(db),
+		Admins:    NewGormAdminUserRepository(db),
+		Holds:     NewGormReservationRepository(db),
+		Events:    NewGormOutboxRepository(db),
+		Webhooks:  NewGormWebhookRepository(db),
+		Hours:     NewGormDealerHoursRepository(db),
+		Replays:   NewGormIdempotencyRepository(db),
+		Synonyms:  NewGormSynonymRepository(db),
+		Queries:   NewGormSearchQueryRepository(db),
+		Tx:        NewGormTransactor(db),
+		Notifier:  NopNotifier{},
+
+		IdempotencyTTL: defaultIdempotencyTTL,
+		RateLimiter:    NewRateLimiter(),
+	}
+	s.Suggestions = NewSuggestIndex(s.loadSuggestions)
+	return s
+}
+
+// NewMemoryServer returns a Server backed by empty in-memory repositories,
+// along with the repositories so callers can seed them. It has no rate
+// limiter, so tests can make as many requests as they need.
+func NewMemoryServer() (*Server, *MemoryRepositories) {
+	repos := NewMemoryRepositories()
+	s := &Server{
+		Vehicles:  repos.Vehicles,
+		Brands:    repos.Brands,
+		Bookings:  repos.Bookings,
+		Analytics: repos.Analytics,
+		Admins:    repos.Admins,
+		Holds:     repos.Holds,
+		Events:    repos.Events,
+		Webhooks:  repos.Webhooks,
+		Hours:     repos.Hours,
+		Replays:   repos.Replays,
+		Synonyms:  repos.Synonyms,
+		Queries:   repos.Queries,
+		Tx:        repos.Tx,
+		Notifier:  NopNotifier{},
+
+		IdempotencyTTL: defaultIdempotencyTTL,
+	}
+	s.Suggestions = NewSuggestIndex(s.loadSuggestions)
+	return s, repos
+}
+
+// idParam parses the :id route parameter
+func idParam(c *gin.Context) (uint, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	return uint(id), err
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"vehicle-store-backend/internal/models"
+)
+
+const (
+	// bookingDuplicateWindow is how far back an enquiry from the same contact
+	// for the same vehicle makes a new one a duplicate
+	bookingDuplicateWindow = 24 * time.Hour
+	// minBookingFillTime is the fastest a person can plausibly fill in the
+	// booking form
+	minBookingFillTime = 3 * time.Second
+)
+
+// Reasons a booking is flagged as suspected spam
+const (
+	SpamReasonDuplicate = "duplicate"
+	SpamReasonHoneypot  = "honeypot"
+	SpamReasonTooFast   = "too_fast"
+)
+
+// SpamReasonNoFormTime is recorded for a booking sent without the form's
+// start time. API clients and old cached forms omit it too, so it is only a
+// hint for reviewers and does not flag the booking.
+const SpamReasonNoFormTime = "no_form_time"
+
+// publicBooking is a booking as shown to the customer who made it. The spam
+// fields shadow the booking's and are always left out, so a bot cannot tell
+// which check caught it.
+type publicBooking struct {
+	*models.Booking
+	SuspectedSpam *bool   `json:"suspected_spam,omitempty"`
+	SpamReasons   *string `json:"spam_reasons,omitempty"`
+}
+
+// bookingSubmission is a public booking request: the booking plus the
+// anti-spam fields sent by the booking form. Neither field is stored.
+type bookingSubmission struct {
+	models.Booking
+	// Website is a honeypot field hidden from people; bots that fill in
+	// every input set it
+	Website string `json:"website"`
+	// FormStartedAt is when the form was shown to the customer
+	FormStartedAt *time.Time `json:"form_started_at"`
+}
+
+// flagSuspectedSpam marks the submitted booking as suspected spam if it trips
+// the honeypot, was filled in too quickly or repeats a recent enquiry. Flagged
+// bookings are still saved so staff can review them. A missing form start
+// time is noted in the reasons without flagging the booking. It must run
+// inside the booking transaction, after the vehicle row is locked.
+func flagSuspectedSpam(repos Repositories, sub *bookingSubmission, now time.Time) error {
+	var reasons []string
+
+	if strings.TrimSpace(sub.Website) != "" {
+		reasons = append(reasons, SpamReasonHoneypot)
+	}
+
+	// A start time in the future counts as too fast
+	if sub.FormStartedAt != nil && now.Sub(*sub.FormStartedAt) < minBookingFillTime {
+		reasons = append(reasons, SpamReasonTooFast)
+	}
+
+	recent, err := repos.Bookings.ListRecentForVehicle(sub.VehicleID, now.Add(-bookingDuplicateWindow))
+	if err != nil {
+		return err
+	}
+	if hasSameContact(&sub.Booking, recent) {
+		reasons = append(reasons, SpamReasonDuplicate)
+	}
+
+	sub.SuspectedSpam = len(reasons) > 0
+	if sub.FormStartedAt == nil {
+		reasons = append(reasons, SpamReasonNoFormTime)
+	}
+	sub.SpamReasons = strings.Join(reasons, ",")
+	return nil
+}
+
+// hasSameContact reports whether any of others shares booking's email
+// address or phone number
+func hasSameContact(booking *models.Booking, others []models.Booking) bool {
+	email := strings.ToLower(strings.TrimSpace(booking.CustomerEmail))
+	phone := normalizePhone(booking.CustomerPhone)
+
+	for _, other := range others {
+		if email != "" && strings.ToLower(strings.TrimSpace(other.CustomerEmail)) == email {
+			return true
+		}
+		if phone != "" && normalizePhone(other.CustomerPhone) == phone {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizePhone strips everything but digits so formatting differences do
+// not hide a repeated number
+func normalizePhone(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, phone)
+}
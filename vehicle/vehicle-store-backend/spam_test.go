@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"vehicle-store-backend/internal/models"
+)
+
+// countingNotifier counts the booking notifications it is asked to send
+type countingNotifier struct {
+	mu      sync.Mutex
+	created int
+}
+
+func (n *countingNotifier) BookingCreated(*models.Booking) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.created++
+	return nil
+}
+
+func (n *countingNotifier) BookingStatusChanged(*models.Booking, string, string) error { return nil }
+
+// bookingEvents returns the number of unprocessed booking.created events
+func (ts *testServer) bookingEvents(t *testing.T) int {
+	t.Helper()
+
+	events, err := ts.repos.Events.ListUnprocessed(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, event := range events {
+		if event.EventType == EventBookingCreated {
+			n++
+		}
+	}
+	return n
+}
+
+func TestBookingSpamChecks(t *testing.T) {
+	formStartedAt := func(ago time.Duration) string {
+		return fmt.Sprintf(`"form_started_at":%q`, time.Now().Add(-ago).Format(time.RFC3339))
+	}
+
+	tests := []struct {
+		name    string
+		fields  []string
+		spam    bool
+		reasons string
+	}{
+		{"genuine", []string{formStartedAt(time.Minute)}, false, ""},
+		{"honeypot", []string{formStartedAt(time.Minute), `"website":"http://spam.example"`}, true, SpamReasonHoneypot},
+		{"too fast", []string{formStartedAt(0)}, true, SpamReasonTooFast},
+		{"started in the future", []string{formStartedAt(-time.Hour)}, true, SpamReasonTooFast},
+		// Without a start time there is nothing to time, so the booking is
+		// only noted for review
+		{"no form time", nil, false, SpamReasonNoFormTime},
+		{"no form time and honeypot", []string{`"website":"x"`}, true, SpamReasonHoneypot + "," + SpamReasonNoFormTime},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t)
+			notifier := &countingNotifier{}
+			ts.Notifier = notifier
+			vehicle := ts.addVehicle(t, models.Vehicle{Name: "Camry", Year: 2024, Price: 28000})
+
+			body := fmt.Sprintf(`{"vehicle_id":%d,"customer_name":"Jane","customer_email":"jane@example.com"`, vehicle.ID)
+			for _, field := range tt.fields {
+				body += "," + field
+			}
+			w := ts.do(http.MethodPost, "/api/bookings", body+"}")
+			if w.Code != http.StatusCreated {
+				t.Fatalf("booking = %d: %s", w.Code, w.Body.String())
+			}
+			var public map[string]interface{}
+			decode(t, w, &public)
+			if _, ok := public["suspected_spam"]; ok {
+				t.Error("response tells the customer whether the booking looks like spam")
+			}
+
+			stored, err := ts.repos.Bookings.GetByID(uint(public["id"].(float64)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.SuspectedSpam != tt.spam || stored.SpamReasons != tt.reasons {
+				t.Errorf("suspected spam = %v (%q), want %v (%q)", stored.SuspectedSpam, stored.SpamReasons, tt.spam, tt.reasons)
+			}
+
+			want := 1
+			if tt.spam {
+				want = 0
+			}
+			if notifier.created != want {
+				t.Errorf("%d notifications sent, want %d", notifier.created, want)
+			}
+			if n := ts.bookingEvents(t); n != want {
+				t.Errorf("%d booking events queued, want %d", n, want)
+			}
+		})
+	}
+}
+
+func TestBookingSpamDuplicates(t *testing.T) {
+	tests := []struct {
+		name  string
+		email string
+		phone string
+		after time.Duration
+		spam  bool
+	}{
+		{"same email", "JANE@example.com ", "", time.Hour, true},
+		{"same phone", "other@example.com", "+44 (20) 7946-0000", time.Hour, true},
+		{"different contact", "other@example.com", "+44 20 7946 0001", time.Hour, false},
+		{"same email at the window edge", "jane@example.com", "", bookingDuplicateWindow, true},
+		{"same email after the window", "jane@example.com", "", bookingDuplicateWindow + time.Minute, false},
+		{"same phone after the window", "other@example.com", "+442079460000", bookingDuplicateWindow + time.Minute, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t)
+			vehicle := ts.addVehicle(t, models.Vehicle{Name: "Camry", Year: 2024, Price: 28000})
+			earlier := models.Booking{
+				VehicleID:     vehicle.ID,
+				CustomerName:  "Jane",
+				CustomerEmail: "jane@example.com",
+				CustomerPhone: "+44 20 7946 0000",
+			}
+			if err := ts.repos.Bookings.Create(&earlier); err != nil {
+				t.Fatal(err)
+			}
+
+			started := earlier.CreatedAt
+			sub := bookingSubmission{
+				Booking: models.Booking{
+					VehicleID:     vehicle.ID,
+					CustomerEmail: tt.email,
+					CustomerPhone: tt.phone,
+				},
+				FormStartedAt: &started,
+			}
+			err := ts.Tx.WithinTransaction(func(repos Repositories) error {
+				return flagSuspectedSpam(repos, &sub, earlier.CreatedAt.Add(tt.after))
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wantReasons := ""
+			if tt.spam {
+				wantReasons = SpamReasonDuplicate
+			}
+			if sub.SuspectedSpam != tt.spam || sub.SpamReasons != wantReasons {
+				t.Errorf("suspected spam = %v (%q), want %v (%q)", sub.SuspectedSpam, sub.SpamReasons, tt.spam, wantReasons)
+			}
+		})
+	}
+}
+
+func TestGetBookingsSpamFilter(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.adminToken(t, RoleAdmin)
+	vehicle := ts.addVehicle(t, models.Vehicle{Name: "Camry", Year: 2024, Price: 28000})
+
+	for _, booking := range []models.Booking{
+		{VehicleID: vehicle.ID, CustomerName: "Jane", CustomerEmail: "jane@example.com"},
+		{VehicleID: vehicle.ID, CustomerName: "Bot", CustomerEmail: "bot@example.com", SuspectedSpam: true, SpamReasons: SpamReasonHoneypot},
+		{VehicleID: vehicle.ID, CustomerName: "API", CustomerEmail: "api@example.com", SpamReasons: SpamReasonNoFormTime},
+	} {
+		if err := ts.repos.Bookings.Create(&booking); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"Jane", "Bot", "API"}},
+		{"?suspected_spam=true", []string{"Bot"}},
+		{"?suspected_spam=false", []string{"Jane", "API"}},
+	}
+
+	for _, tt := range tests {
+		w := ts.do(http.MethodGet, "/api/admin/bookings"+tt.query, "", "Authorization", token)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d: %s", tt.query, w.Code, w.Body.String())
+		}
+		var bookings []models.Booking
+		decode(t, w, &bookings)
+
+		var names []string
+		for _, b := range bookings {
+			names = append(names, b.CustomerName)
+		}
+		if !sameNames(names, tt.want) {
+			t.Errorf("GET %s = %v, want %v", tt.query, names, tt.want)
+		}
+	}
+
+	w := ts.do(http.MethodGet, "/api/admin/bookings?suspected_spam=maybe", "", "Authorization", token)
+	expectError(t, w, http.StatusBadRequest, CodeBadRequest)
+}
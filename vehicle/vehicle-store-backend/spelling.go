@@ -0,0 +1,190 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"vehicle-store-backend/internal/models"
+)
+
+// maxCorrections caps the vocabulary words a misspelt search word is
+// expanded to
+const maxCorrections = 3
+
+// allowedTypos is how many edits a search word may be from the word it was
+// meant to be. Short words get none, as nearly everything is close to them.
+func allowedTypos(word string) int {
+	switch n := utf8.RuneCountInString(word); {
+	case n <= 3:
+		return 0
+	case n <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// searchVocabulary is what searches are expanded and corrected against: the
+// words of the inventory's vehicle, model and brand names, and the synonyms
+type searchVocabulary struct {
+	words    []string // sorted, without repeats
+	synonyms map[string][]string
+}
+
+// loadSearchVocabulary reads the search vocabulary from the repositories
+func (s *Server) loadSearchVocabulary() (*searchVocabulary, error) {
+	names, err := s.Vehicles.ListNames()
+	if err != nil {
+		return nil, err
+	}
+	synonyms, err := s.Synonyms.List()
+	if err != nil {
+		return nil, err
+	}
+
+	vocabulary := &searchVocabulary{synonyms: make(map[string][]string)}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		for _, word := range searchWords(name.Brand + " " + name.Name + " " + name.Model) {
+			if !seen[word] {
+				seen[word] = true
+				vocabulary.words = append(vocabulary.words, word)
+			}
+		}
+	}
+	sort.Strings(vocabulary.words)
+
+	for _, synonym := range synonyms {
+		vocabulary.synonyms[synonym.Term] = append(vocabulary.synonyms[synonym.Term], synonym.Synonym)
+	}
+	return vocabulary, nil
+}
+
+// knows reports whether term needs no correction: it has synonyms or starts
+// a vocabulary word
+func (v *searchVocabulary) knows(term string) bool {
+	if len(v.synonyms[term]) > 0 {
+		return true
+	}
+	i := sort.SearchStrings(v.words, term)
+	return i < len(v.words) && strings.HasPrefix(v.words[i], term)
+}
+
+// expansions returns the alternatives each of terms may match instead: its
+// synonyms, or for a word the vocabulary does not know, the vocabulary words
+// within allowedTypos of it
+func (v *searchVocabulary) expansions(terms []string) map[string][]string {
+	expansions := make(map[string][]string)
+	for _, term := range terms {
+		if synonyms := v.synonyms[term]; len(synonyms) > 0 {
+			expansions[term] = synonyms
+			continue
+		}
+		if v.knows(term) || hasDigit(term) {
+			continue
+		}
+		if words := v.closest(term, allowedTypos(term)); len(words) > 0 {
+			expansions[term] = words
+		}
+	}
+	return expansions
+}
+
+// correction rewrites terms with each unknown word replaced by the closest
+// vocabulary word, allowing one more typo than expansions does. ok is false
+// if no word could be corrected.
+func (v *searchVocabulary) correction(terms []string) (corrected string, ok bool) {
+	words := make([]string, len(terms))
+	for i, term := range terms {
+		words[i] = term
+		if v.knows(term) || hasDigit(term) {
+			continue
+		}
+		if closest := v.closest(term, allowedTypos(term)+1); len(closest) > 0 {
+			words[i] = closest[0]
+			ok = true
+		}
+	}
+	return strings.Join(words, " "), ok
+}
+
+// didYouMean returns a corrected search for filter, which found nothing, or
+// "" if there is no correction or it finds nothing either
+func (s *Server) didYouMean(vocabulary *searchVocabulary, filter models.VehicleFilter, terms []string) (string, error) {
+	corrected, ok := vocabulary.correction(terms)
+	if !ok {
+		return "", nil
+	}
+
+	filter.Search = corrected
+	filter.Expansions = vocabulary.expansions(searchTerms(corrected))
+	filter.Limit, filter.Offset = 1, 0
+	_, total, err := s.Vehicles.List(filter)
+	if err != nil || total == 0 {
+		return "", err
+	}
+	return corrected, nil
+}
+
+// closest returns up to maxCorrections vocabulary words at most maxEdits
+// edits from term, nearest first
+func (v *searchVocabulary) closest(term string, maxEdits int) []string {
+	type candidate struct {
+		word     string
+		distance int
+	}
+
+	var candidates []candidate
+	termRunes := []rune(term)
+	for _, word := range v.words {
+		if d := editDistance(termRunes, []rune(word)); d <= maxEdits {
+			candidates = append(candidates, candidate{word, d})
+		}
+	}
+	// The words are sorted, so ties stay in alphabetical order
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	words := make([]string, 0, min(len(candidates), maxCorrections))
+	for _, c := range candidates[:min(len(candidates), maxCorrections)] {
+		words = append(words, c.word)
+	}
+	return words
+}
+
+// editDistance is the optimal string alignment distance between a and b:
+// the insertions, deletions, substitutions and swaps of adjacent letters
+// needed to turn one into the other
+func editDistance(a, b []rune) int {
+	// rows[0] is two rows back, rows[1] the previous row and rows[2] the current one
+	rows := [3][]int{make([]int, len(b)+1), make([]int, len(b)+1), make([]int, len(b)+1)}
+	for j := range rows[1] {
+		rows[1][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		rows[2][0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d := min(rows[1][j]+1, rows[2][j-1]+1, rows[1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d = min(d, rows[0][j-2]+1)
+			}
+			rows[2][j] = d
+		}
+		rows[0], rows[1], rows[2] = rows[1], rows[2], rows[0]
+	}
+	return rows[1][len(b)]
+}
+
+// hasDigit reports whether word contains a digit; years and model numbers
+// are not spelling mistakes
+func hasDigit(word string) bool {
+	return strings.IndexFunc(word, unicode.IsDigit) >= 0
+}
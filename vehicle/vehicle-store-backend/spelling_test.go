@@ -0,0 +1,156 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"vehicle-store-backend/internal/models"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"camry", "", 5},
+		{"camry", "camry", 0},
+		{"camri", "camry", 1},  // substitution
+		{"camr", "camry", 1},   // deletion
+		{"camrey", "camry", 1}, // insertion
+		{"cmary", "camry", 1},  // adjacent letters swapped
+		{"macry", "camry", 2},  // letters apart swapped
+		{"kitten", "sitting", 3},
+		// Swapped letters are not edited again, unlike Damerau-Levenshtein
+		{"ca", "abc", 3},
+		{"citroen", "citroën", 1},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := editDistance([]rune(tt.b), []rune(tt.a)); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestSearchVocabularyExpansions(t *testing.T) {
+	vocabulary := &searchVocabulary{
+		words:    []string{"camry", "chevrolet", "civic", "corvette", "gti", "golf", "volkswagen"},
+		synonyms: map[string][]string{"vw": {"volkswagen"}},
+	}
+
+	tests := []struct {
+		term string
+		want []string
+	}{
+		// Up to three letters: no typos allowed
+		{"gtx", nil},
+		{"gt", nil},
+		// Four or five letters: one
+		{"gulf", []string{"golf"}},
+		{"cmary", []string{"camry"}},
+		{"cmari", nil},
+		// Six or more: two
+		{"chevrolte", []string{"chevrolet"}},
+		{"corvete", []string{"corvette"}},
+		{"korvete", []string{"corvette"}},
+		{"korvet", nil},
+		// Known words, prefixes of them, synonyms and numbers are left alone
+		{"civic", nil},
+		{"volks", nil},
+		{"vw", []string{"volkswagen"}},
+		{"2024", nil},
+	}
+
+	for _, tt := range tests {
+		got := vocabulary.expansions([]string{tt.term})[tt.term]
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expansions(%q) = %v, want %v", tt.term, got, tt.want)
+		}
+	}
+
+	// A correction allows one more typo than an expansion
+	for terms, want := range map[string]string{
+		"gtx":       "gti",
+		"vw gtx":    "vw gti",
+		"cmari":     "camry",
+		"korvet":    "corvette",
+		"xyz civic": "",
+	} {
+		corrected, ok := vocabulary.correction(searchTerms(terms))
+		if ok != (want != "") || (ok && corrected != want) {
+			t.Errorf("correction(%q) = %q, %v; want %q", terms, corrected, ok, want)
+		}
+	}
+}
+
+func TestSearchSynonymsAndTypos(t *testing.T) {
+	servers := map[string]func(t *testing.T) *testServer{
+		"memory": newTestServer,
+		"sqlite": newGormTestServer,
+	}
+
+	for name, newServer := range servers {
+		t.Run(name, func(t *testing.T) {
+			ts := newServer(t)
+			vw := ts.addBrand(t, "Volkswagen")
+			chevrolet := ts.addBrand(t, "Chevrolet")
+			ts.addVehicle(t, models.Vehicle{BrandID: vw.ID, Name: "Golf", Model: "GTI", Year: 2024, Price: 35000})
+			ts.addVehicle(t, models.Vehicle{BrandID: chevrolet.ID, Name: "Corvette", Year: 2024, Price: 70000})
+			if err := ts.Synonyms.Create(&models.SearchSynonym{Term: "vw", Synonym: "volkswagen"}); err != nil {
+				t.Fatal(err)
+			}
+
+			tests := []struct {
+				search     string
+				want       []string
+				didYouMean string
+			}{
+				{"vw", []string{"Golf"}, ""},
+				{"chevrolte", []string{"Corvette"}, ""},
+				{"vw gulf", []string{"Golf"}, ""},
+				// Too short to expand, so nothing is found, but close
+				// enough to suggest
+				{"vw gtx", nil, "vw gti"},
+				{"korvet", nil, "corvette"},
+				{"tractor", nil, ""},
+			}
+
+			for _, tt := range tests {
+				w := ts.do(http.MethodGet, "/api/vehicles?search="+url.QueryEscape(tt.search), "")
+				if w.Code != http.StatusOK {
+					t.Fatalf("search %q = %d: %s", tt.search, w.Code, w.Body.String())
+				}
+				var body struct {
+					Vehicles   []models.Vehicle `json:"vehicles"`
+					DidYouMean string           `json:"did_you_mean"`
+				}
+				decode(t, w, &body)
+
+				var names []string
+				for _, v := range body.Vehicles {
+					names = append(names, v.Name)
+				}
+				if !reflect.DeepEqual(names, tt.want) || body.DidYouMean != tt.didYouMean {
+					t.Errorf("search %q = %v, did you mean %q; want %v, %q",
+						tt.search, names, body.DidYouMean, tt.want, tt.didYouMean)
+				}
+			}
+
+			// The suggestion finds the vehicle
+			w := ts.do(http.MethodGet, "/api/vehicles?search="+url.QueryEscape("vw gti"), "")
+			var body struct {
+				Total int `json:"total"`
+			}
+			decode(t, w, &body)
+			if body.Total != 1 {
+				t.Errorf("search \"vw gti\" found %d vehicles, want 1", body.Total)
+			}
+		})
+	}
+}
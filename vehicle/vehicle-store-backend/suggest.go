@@ -0,0 +1,275 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Suggestion types
+	SuggestionBrand   = "brand"
+	SuggestionVehicle = "vehicle"
+	SuggestionModel   = "model"
+	SuggestionQuery   = "query"
+
+	defaultSuggestions = 8
+	maxSuggestions     = 20
+	// minPopularSearches is how often a search must have been made before it
+	// is suggested to others, so one customer's searches are not shown to the
+	// next
+	minPopularSearches = 3
+	// maxPopularQueries caps the past searches held in the index
+	maxPopularQueries = 1000
+	// maxRecordedQuery is the longest search counted for suggestions
+	maxRecordedQuery = 200
+	// suggestIndexMaxAge is how long the index is used before it is rebuilt
+	// to pick up newly popular searches and changes made elsewhere
+	suggestIndexMaxAge = 5 * time.Minute
+)
+
+// Suggestion is one completion offered for a partly typed search
+type Suggestion struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+}
+
+// suggestEntry is an indexed suggestion. Its weight is how many available
+// vehicles it covers, or for a past search how often it was made.
+type suggestEntry struct {
+	Suggestion
+	weight int64
+}
+
+// suggestKey indexes an entry by the words of its text from one word on, so
+// "clas" completes "Mercedes-Benz C-Class" as well as "merc" does
+type suggestKey struct {
+	key   string
+	entry int
+	// start is set for the key made from the first word, which ranks higher
+	start bool
+}
+
+// SuggestIndex answers search completions from memory. It is loaded on first
+// use and rebuilt in the background once invalidated or too old, serving the
+// previous index meanwhile.
+type SuggestIndex struct {
+	load func() ([]suggestEntry, error)
+
+	mu      sync.RWMutex
+	entries []suggestEntry
+	keys    []suggestKey // sorted by key
+	builtAt time.Time
+
+	stale    atomic.Bool
+	building atomic.Bool
+}
+
+// NewSuggestIndex returns an empty index filled by load
+func NewSuggestIndex(load func() ([]suggestEntry, error)) *SuggestIndex {
+	return &SuggestIndex{load: load}
+}
+
+// Invalidate starts a rebuild, as the inventory has changed
+func (x *SuggestIndex) Invalidate() {
+	x.stale.Store(true)
+	x.refresh()
+}
+
+// refresh rebuilds the index in the background unless a rebuild is running
+func (x *SuggestIndex) refresh() {
+	if !x.building.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer x.building.Store(false)
+		if err := x.Rebuild(); err != nil {
+			log.Println("Failed to rebuild search suggestions:", err)
+		}
+	}()
+}
+
+// Rebuild loads the entries and swaps them in
+func (x *SuggestIndex) Rebuild() error {
+	// Cleared first so a change made while loading marks the index stale again
+	x.stale.Store(false)
+	entries, err := x.load()
+	if err != nil {
+		x.stale.Store(true)
+		return err
+	}
+
+	var keys []suggestKey
+	for i, entry := range entries {
+		words := searchWords(entry.Text)
+		for j := range words {
+			keys = append(keys, suggestKey{key: strings.Join(words[j:], " "), entry: i, start: j == 0})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].key < keys[j].key })
+
+	x.mu.Lock()
+	x.entries, x.keys, x.builtAt = entries, keys, time.Now()
+	x.mu.Unlock()
+	return nil
+}
+
+// Lookup returns up to limit entries with a word run starting with query,
+// those starting with it first and then by weight
+func (x *SuggestIndex) Lookup(query string, limit int) ([]Suggestion, error) {
+	x.mu.RLock()
+	builtAt := x.builtAt
+	x.mu.RUnlock()
+
+	switch {
+	case builtAt.IsZero():
+		if err := x.Rebuild(); err != nil {
+			return nil, err
+		}
+	case x.stale.Load() || time.Since(builtAt) > suggestIndexMaxAge:
+		x.refresh()
+	}
+
+	prefix := strings.Join(searchWords(query), " ")
+	if prefix == "" {
+		return []Suggestion{}, nil
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	// An entry can match through several keys; starting with the query wins
+	matches := make(map[int]bool)
+	for i := sort.Search(len(x.keys), func(i int) bool { return x.keys[i].key >= prefix }); i < len(x.keys); i++ {
+		key := x.keys[i]
+		if !strings.HasPrefix(key.key, prefix) {
+			break
+		}
+		matches[key.entry] = matches[key.entry] || key.start
+	}
+
+	ids := make([]int, 0, len(matches))
+	for id := range matches {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := x.entries[ids[i]], x.entries[ids[j]]
+		if matches[ids[i]] != matches[ids[j]] {
+			return matches[ids[i]]
+		}
+		if a.weight != b.weight {
+			return a.weight > b.weight
+		}
+		if len(a.Text) != len(b.Text) {
+			return len(a.Text) < len(b.Text)
+		}
+		return a.Text < b.Text
+	})
+
+	suggestions := make([]Suggestion, 0, min(limit, len(ids)))
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if len(suggestions) == limit {
+			break
+		}
+		entry := x.entries[id]
+		// A past search may read exactly like a brand or vehicle
+		if text := strings.ToLower(entry.Text); !seen[text] {
+			seen[text] = true
+			suggestions = append(suggestions, entry.Suggestion)
+		}
+	}
+	return suggestions, nil
+}
+
+// loadSuggestions lists the brands, vehicles and models in stock and the
+// popular past searches
+func (s *Server) loadSuggestions() ([]suggestEntry, error) {
+	names, err := s.Vehicles.ListNames()
+	if err != nil {
+		return nil, err
+	}
+	queries, err := s.Queries.ListPopular(minPopularSearches, maxPopularQueries)
+	if err != nil {
+		return nil, err
+	}
+
+	// Names are ordered by brand and name, so totals are summed over runs
+	var entries []suggestEntry
+	brand, vehicle := -1, -1
+	for _, name := range names {
+		if brand < 0 || entries[brand].Text != name.Brand {
+			entries = append(entries, suggestEntry{Suggestion{name.Brand, SuggestionBrand}, 0})
+			brand, vehicle = len(entries)-1, -1
+		}
+		entries[brand].weight += name.Count
+
+		vehicleText := name.Brand + " " + name.Name
+		if vehicle < 0 || entries[vehicle].Text != vehicleText {
+			entries = append(entries, suggestEntry{Suggestion{vehicleText, SuggestionVehicle}, 0})
+			vehicle = len(entries) - 1
+		}
+		entries[vehicle].weight += name.Count
+
+		if name.Model != "" {
+			entries = append(entries, suggestEntry{Suggestion{vehicleText + " " + name.Model, SuggestionModel}, name.Count})
+		}
+	}
+
+	for _, query := range queries {
+		entries = append(entries, suggestEntry{Suggestion{query.Query, SuggestionQuery}, query.Searches})
+	}
+	return entries, nil
+}
+
+// recordSearch counts a search that found vehicles towards the popular
+// searches. Failures are only logged; they must not fail the search.
+func (s *Server) recordSearch(terms []string) {
+	query := strings.Join(terms, " ")
+	if query == "" || len(query) > maxRecordedQuery {
+		return
+	}
+	if err := s.Queries.Record(query, time.Now()); err != nil {
+		log.Println("Failed to record search:", err)
+	}
+}
+
+// RefreshSuggestions rebuilds the search suggestions after a request that
+// changed the inventory succeeds. Failed requests leave their error in
+// c.Errors for the error middleware to write, so the status alone still
+// reads 200 here.
+func (s *Server) RefreshSuggestions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 && c.Writer.Status() < http.StatusMultipleChoices {
+			s.Suggestions.Invalidate()
+		}
+	}
+}
+
+// SuggestVehicles handles GET /api/vehicles/suggest?q=, completing a partly
+// typed search
+func (s *Server) SuggestVehicles(c *gin.Context) {
+	limit := defaultSuggestions
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= maxSuggestions {
+		limit = l
+	}
+
+	suggestions, err := s.Suggestions.Lookup(c.Query("q"), limit)
+	if err != nil {
+		respondError(c, internalError("Failed to fetch suggestions", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":       c.Query("q"),
+		"suggestions": suggestions,
+	})
+}
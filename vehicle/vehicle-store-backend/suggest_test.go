@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"vehicle-store-backend/internal/models"
+)
+
+// addBrand stores a brand named name
+func (ts *testServer) addBrand(t *testing.T, name string) *models.Brand {
+	t.Helper()
+
+	brand := models.Brand{Name: name}
+	if err := ts.Brands.Create(&brand); err != nil {
+		t.Fatal(err)
+	}
+	return &brand
+}
+
+// suggest returns the suggestions for q as "type:text" strings
+func (ts *testServer) suggest(t *testing.T, q string, limit int) []string {
+	t.Helper()
+
+	w := ts.do(http.MethodGet, fmt.Sprintf("/api/vehicles/suggest?q=%s&limit=%d", url.QueryEscape(q), limit), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("suggest %q = %d: %s", q, w.Code, w.Body.String())
+	}
+	var body struct {
+		Suggestions []Suggestion `json:"suggestions"`
+	}
+	decode(t, w, &body)
+
+	got := []string{}
+	for _, s := range body.Suggestions {
+		got = append(got, s.Type+":"+s.Text)
+	}
+	return got
+}
+
+func TestSuggestRanking(t *testing.T) {
+	ts := newTestServer(t)
+	toyota := ts.addBrand(t, "Toyota")
+	honda := ts.addBrand(t, "Honda")
+	for _, v := range []models.Vehicle{
+		{BrandID: toyota.ID, Name: "Camry", Model: "XLE"},
+		{BrandID: toyota.ID, Name: "Camry", Model: "XLE"},
+		{BrandID: toyota.ID, Name: "Camry", Model: "LE"},
+		{BrandID: toyota.ID, Name: "Corolla"},
+		{BrandID: honda.ID, Name: "Civic", Model: "Type R"},
+	} {
+		v.Year, v.Price = 2024, 30000
+		ts.addVehicle(t, v)
+	}
+	sold := models.Vehicle{BrandID: honda.ID, Name: "Accord", Year: 2024, Price: 30000, FuelType: "Petrol"}
+	if err := ts.Vehicles.Create(&sold); err != nil {
+		t.Fatal(err)
+	}
+
+	for query, searches := range map[string]int{
+		"camry hybrid": 5,
+		"toyota":       4,
+		"cheap cars":   minPopularSearches - 1,
+	} {
+		for i := 0; i < searches; i++ {
+			if err := ts.Queries.Record(query, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		q     string
+		limit int
+		want  []string
+	}{
+		// Brands and vehicles rank by the vehicles they cover; the popular
+		// search for "toyota" reads like the brand and is shown once
+		{"to", 8, []string{
+			"brand:Toyota",
+			"vehicle:Toyota Camry",
+			"model:Toyota Camry XLE",
+			"vehicle:Toyota Corolla",
+			"model:Toyota Camry LE",
+		}},
+		{"to", 2, []string{"brand:Toyota", "vehicle:Toyota Camry"}},
+		// Text starting with the query outranks a match on a later word,
+		// even one covering more vehicles
+		{"cam", 8, []string{
+			"query:camry hybrid",
+			"vehicle:Toyota Camry",
+			"model:Toyota Camry XLE",
+			"model:Toyota Camry LE",
+		}},
+		{"toyota camry x", 8, []string{"model:Toyota Camry XLE"}},
+		{"type", 8, []string{"model:Honda Civic Type R"}},
+		{"HONDA  civic", 8, []string{"vehicle:Honda Civic", "model:Honda Civic Type R"}},
+		// Sold vehicles and searches made too rarely are not suggested
+		{"acc", 8, []string{}},
+		{"chea", 8, []string{}},
+		{"  ", 8, []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := ts.suggest(t, tt.q, tt.limit); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("suggest %q limit %d = %v, want %v", tt.q, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestRefreshSuggestionsOnlyAfterSuccess(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.adminToken(t, RoleAdmin)
+
+	// The index's load blocks until the test ends, so a rebuild started by
+	// a request is still running when the request returns
+	gate := make(chan struct{})
+	t.Cleanup(func() { close(gate) })
+	ts.Suggestions = NewSuggestIndex(func() ([]suggestEntry, error) {
+		<-gate
+		return nil, nil
+	})
+
+	failures := []struct {
+		name, method, path, body string
+	}{
+		{"invalid vehicle", http.MethodPost, "/api/admin/vehicles", `{"name":""}`},
+		{"missing vehicle", http.MethodDelete, "/api/admin/vehicles/999", ""},
+		{"duplicate brand", http.MethodPost, "/api/admin/brands", `{"name":"Toyota"}`},
+	}
+	ts.addBrand(t, "Toyota")
+
+	for _, tt := range failures {
+		w := ts.do(tt.method, tt.path, tt.body, "Authorization", token)
+		if w.Code < http.StatusBadRequest {
+			t.Fatalf("%s = %d, want an error", tt.name, w.Code)
+		}
+		if ts.Suggestions.building.Load() {
+			t.Fatalf("%s (%d) rebuilt the suggestions", tt.name, w.Code)
+		}
+	}
+
+	w := ts.do(http.MethodPost, "/api/admin/brands", `{"name":"Honda"}`, "Authorization", token)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create brand = %d: %s", w.Code, w.Body.String())
+	}
+	if !ts.Suggestions.building.Load() {
+		t.Error("creating a brand did not rebuild the suggestions")
+	}
+}
+
+func BenchmarkSuggestLookup(b *testing.B) {
+	var entries []suggestEntry
+	for brand := 0; brand < 50; brand++ {
+		brandText := fmt.Sprintf("Brand%02d", brand)
+		entries = append(entries, suggestEntry{Suggestion{brandText, SuggestionBrand}, 40})
+		for name := 0; name < 20; name++ {
+			vehicleText := fmt.Sprintf("%s Model%02d", brandText, name)
+			entries = append(entries,
+				suggestEntry{Suggestion{vehicleText, SuggestionVehicle}, 2},
+				suggestEntry{Suggestion{vehicleText + " Sport", SuggestionModel}, 1})
+		}
+	}
+	for i := 0; i < maxPopularQueries; i++ {
+		entries = append(entries, suggestEntry{Suggestion{fmt.Sprintf("model%02d search %d", i%20, i), SuggestionQuery}, int64(i)})
+	}
+
+	index := NewSuggestIndex(func() ([]suggestEntry, error) { return entries, nil })
+	if err := index.Rebuild(); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, q := range []string{"b", "brand2", "mod", "model07 spo"} {
+		b.Run(q, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := index.Lookup(q, defaultSuggestions); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
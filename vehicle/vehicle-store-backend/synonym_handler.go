@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"vehicle-store-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// normalizeSynonym trims synonym and lowercases its term, which must be a
+// single word as searches are expanded word by word
+func normalizeSynonym(synonym *models.SearchSynonym) error {
+	synonym.Term = strings.ToLower(strings.TrimSpace(synonym.Term))
+	synonym.Synonym = strings.Join(strings.Fields(synonym.Synonym), " ")
+
+	if words := searchWords(synonym.Term); len(words) != 1 || words[0] != synonym.Term {
+		return validationError(FieldError{Field: "term", Message: "must be a single word of letters and digits"})
+	}
+	if len(searchWords(synonym.Synonym)) == 0 {
+		return validationError(FieldError{Field: "synonym", Message: "must contain a word"})
+	}
+	if strings.EqualFold(synonym.Synonym, synonym.Term) {
+		return validationError(FieldError{Field: "synonym", Message: "must differ from the term"})
+	}
+	return nil
+}
+
+// synonymExistsError is returned when a term already has the synonym
+func synonymExistsError() *APIError {
+	return NewAPIError(http.StatusConflict, CodeSynonymExists, "The term already has this synonym")
+}
+
+// GetSearchSynonyms handles GET /api/admin/search-synonyms
+func (s *Server) GetSearchSynonyms(c *gin.Context) {
+	synonyms, err := s.Synonyms.List()
+	if err != nil {
+		respondError(c, internalError("Failed to fetch synonyms", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, synonyms)
+}
+
+// CreateSearchSynonym handles POST /api/admin/search-synonyms
+func (s *Server) CreateSearchSynonym(c *gin.Context) {
+	var synonym models.SearchSynonym
+	if !bindJSON(c, &synonym) {
+		return
+	}
+
+	if err := normalizeSynonym(&synonym); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	err := s.Synonyms.Create(&synonym)
+	switch {
+	case errors.Is(err, ErrDuplicate):
+		respondError(c, synonymExistsError())
+		return
+	case err != nil:
+		respondError(c, internalError("Failed to create synonym", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, synonym)
+}
+
+// UpdateSearchSynonym handles PUT /api/admin/search-synonyms/:id
+func (s *Server) UpdateSearchSynonym(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		respondError(c, notFoundError("Synonym"))
+		return
+	}
+
+	synonym, err := s.Synonyms.GetByID(id)
+	if err != nil {
+		respondError(c, notFoundError("Synonym"))
+		return
+	}
+
+	if !bindJSON(c, synonym) {
+		return
+	}
+	synonym.ID = id
+
+	if err := normalizeSynonym(synonym); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	err = s.Synonyms.Update(synonym)
+	switch {
+	case errors.Is(err, ErrDuplicate):
+		respondError(c, synonymExistsError())
+		return
+	case err != nil:
+		respondError(c, internalError("Failed to update synonym", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, synonym)
+}
+
+// DeleteSearchSynonym handles DELETE /api/admin/search-synonyms/:id
+func (s *Server) DeleteSearchSynonym(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		respondError(c, notFoundError("Synonym"))
+		return
+	}
+
+	if err := s.Synonyms.Delete(id); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			respondError(c, notFoundError("Synonym"))
+			return
+		}
+		respondError(c, internalError("Failed to delete synonym", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Synonym deleted successfully"})
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// firstVehicleYear is the year of the first production motor car
+const firstVehicleYear = 1886
+
+// phonePattern accepts international and local formats such as
+// "+44 20 7946 0958" or "(555) 010-1234"
+var phonePattern = regexp.MustCompile(`^\+?[0-9 ().-]{7,20}$`)
+
+// FieldError describes one invalid field in a request body
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// registerValidators adds the custom binding tags to gin's validator and
+// makes it report fields by their JSON names
+func registerValidators() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("unexpected validator engine")
+	}
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	if err := v.RegisterValidation("vehicle_year", func(fl validator.FieldLevel) bool {
+		year := fl.Field().Int()
+		// Next year's models go on sale before the year starts
+		return year >= firstVehicleYear && year <= int64(time.Now().Year()+1)
+	}); err != nil {
+		return err
+	}
+
+	return v.RegisterValidation("phone", func(fl validator.FieldLevel) bool {
+		phone := fl.Field().String()
+		return phonePattern.MatchString(phone) && len(normalizePhone(phone)) >= 7
+	})
+}
+
+// bindJSON binds the request body into obj. Malformed JSON gets a 400 and
+// values breaking the binding tags get a 422 listing every invalid field.
+// It reports whether obj is ready to use.
+func bindJSON(c *gin.Context, obj interface{}) bool {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		respondError(c, bindError(err))
+		return false
+	}
+	return true
+}
+
+// bindError converts an error from ShouldBindJSON into an APIError without
+// passing the decoder's wording on to the client
+func bindError(err error) *APIError {
+	var invalid validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+
+	switch {
+	case errors.As(err, &invalid):
+		details := make([]FieldError, len(invalid))
+		for i, fe := range invalid {
+			details[i] = FieldError{Field: fe.Field(), Message: fieldErrorMessage(fe)}
+		}
+		return validationError(details...)
+	case errors.As(err, &typeErr):
+		return validationError(FieldError{Field: typeErr.Field, Message: "must be " + jsonTypeName(typeErr.Type.Kind())})
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return NewAPIError(http.StatusBadRequest, CodeInvalidJSON, "Request body must be valid JSON")
+	default:
+		return badRequestError("Request body is invalid")
+	}
+}
+
+// jsonTypeName describes the JSON value expected for a Go kind
+func jsonTypeName(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+// fieldErrorMessage describes a failed binding tag in words
+func fieldErrorMessage(fe validator.FieldError) string {
+	isString := fe.Kind() == reflect.String
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "phone":
+		return "must be a valid phone number"
+	case "vehicle_year":
+		return fmt.Sprintf("must be between %d and %d", firstVehicleYear, time.Now().Year()+1)
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte", "min":
+		if isString {
+			return fmt.Sprintf("must be at least %s characters", fe.Param())
+		}
+		return "must be at least " + fe.Param()
+	case "lte", "max":
+		if isString {
+			return fmt.Sprintf("must be at most %s characters", fe.Param())
+		}
+		return "must be at most " + fe.Param()
+	default:
+		return "is invalid"
+	}
+}
+
+// requireBrand returns a validation error if brandID does not exist
+func requireBrand(brands BrandRepository, brandID uint) error {
+	_, err := brands.GetByID(brandID)
+	if errors.Is(err, ErrNotFound) {
+		return validationError(FieldError{Field: "brand_id", Message: "does not match an existing brand"})
+	}
+	return err
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"vehicle-store-backend/internal/models"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+func TestCustomValidators(t *testing.T) {
+	if err := registerValidators(); err != nil {
+		t.Fatal(err)
+	}
+	nextYear := time.Now().Year() + 1
+
+	t.Run("vehicle_year", func(t *testing.T) {
+		for year, want := range map[int]bool{
+			0:                    false,
+			firstVehicleYear - 1: false,
+			firstVehicleYear:     true,
+			2024:                 true,
+			nextYear:             true,
+			nextYear + 1:         false,
+		} {
+			v := struct {
+				Year int `binding:"vehicle_year"`
+			}{year}
+			if got := binding.Validator.ValidateStruct(v) == nil; got != want {
+				t.Errorf("vehicle_year %d valid = %v, want %v", year, got, want)
+			}
+		}
+	})
+
+	t.Run("phone", func(t *testing.T) {
+		for phone, want := range map[string]bool{
+			"+44 20 7946 0958":           true,
+			"(555) 010-1234":             true,
+			"555.010.1234":               true,
+			"5550101":                    true,
+			"555010":                     false, // too few digits
+			"(55) 5-0":                   false,
+			"+1 (((( ))))--..":           false, // long enough, but hardly any digits
+			"555 010 1234 ext":           false,
+			"call me":                    false,
+			"+44 20 7946 0958 0958 0958": false, // too long
+		} {
+			v := struct {
+				Phone string `binding:"phone"`
+			}{phone}
+			if got := binding.Validator.ValidateStruct(v) == nil; got != want {
+				t.Errorf("phone %q valid = %v, want %v", phone, got, want)
+			}
+		}
+
+		// Booking phones are optional
+		v := struct {
+			Phone string `binding:"omitempty,phone"`
+		}{}
+		if err := binding.Validator.ValidateStruct(v); err != nil {
+			t.Errorf("empty optional phone: %v", err)
+		}
+	})
+}
+
+func TestBindingTagErrors(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.adminToken(t, RoleAdmin)
+	brand := ts.addBrand(t, "Toyota")
+	vehicle := ts.addVehicle(t, models.Vehicle{BrandID: brand.ID, Name: "Camry", Year: 2024, Price: 28000})
+
+	tests := []struct {
+		name, path, body string
+		want             []FieldError
+	}{
+		{
+			name: "vehicle",
+			path: "/api/admin/vehicles",
+			body: fmt.Sprintf(`{"brand_id":%d,"name":"","year":1885,"price":0,"fuel_type":"Steam",
+				"description":%q,"mileage":-1,"financing_rate":100.5,"warranty_years":21}`, brand.ID, strings.Repeat("x", 5001)),
+			want: []FieldError{
+				{"name", "is required"},
+				{"year", fmt.Sprintf("must be between %d and %d", firstVehicleYear, time.Now().Year()+1)},
+				{"price", "must be greater than 0"},
+				{"fuel_type", "must be one of: Petrol, Diesel, Electric, Hybrid"},
+				{"description", "must be at most 5000 characters"},
+				{"mileage", "must be at least 0"},
+				{"financing_rate", "must be at most 100"},
+				{"warranty_years", "must be at most 20"},
+			},
+		},
+		{
+			name: "vehicle year from the future",
+			path: "/api/admin/vehicles",
+			body: fmt.Sprintf(`{"brand_id":%d,"name":"Camry","year":%d,"price":28000,"fuel_type":"Petrol"}`, brand.ID, time.Now().Year()+2),
+			want: []FieldError{
+				{"year", fmt.Sprintf("must be between %d and %d", firstVehicleYear, time.Now().Year()+1)},
+			},
+		},
+		{
+			name: "brand",
+			path: "/api/admin/brands",
+			body: fmt.Sprintf(`{"name":%q}`, strings.Repeat("x", 101)),
+			want: []FieldError{{"name", "must be at most 100 characters"}},
+		},
+		{
+			name: "booking",
+			path: "/api/bookings",
+			body: fmt.Sprintf(`{"vehicle_id":%d,"customer_email":"not-an-email","customer_phone":"call me"}`, vehicle.ID),
+			want: []FieldError{
+				{"customer_name", "is required"},
+				{"customer_email", "must be a valid email address"},
+				{"customer_phone", "must be a valid phone number"},
+			},
+		},
+		{
+			name: "wrong JSON type",
+			path: "/api/admin/vehicles",
+			body: fmt.Sprintf(`{"brand_id":%d,"name":"Camry","year":"2024","price":28000,"fuel_type":"Petrol"}`, brand.ID),
+			want: []FieldError{{"year", "must be a whole number"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := ts.do(http.MethodPost, tt.path, tt.body, "Authorization", token)
+			body := expectError(t, w, http.StatusUnprocessableEntity, CodeValidationFailed)
+			if !reflect.DeepEqual(body.Error.Details, tt.want) {
+				t.Errorf("details = %+v\nwant %+v", body.Error.Details, tt.want)
+			}
+		})
+	}
+}
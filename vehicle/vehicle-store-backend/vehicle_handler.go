@@ -1,22 +1,23 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 
-	"vehicle-store-backend/internal/database"
 	"vehicle-store-backend/internal/models"
 
 	"github.com/gin-gonic/gin"
 )
 
-// GetVehicles handles GET /api/vehicles with filters
-func GetVehicles(c *gin.Context) {
-	var vehicles []models.Vehicle
+// vehicleFilterFromQuery reads the vehicle filters shared by the vehicle
+// list and its facets from the query string
+func vehicleFilterFromQuery(c *gin.Context) models.VehicleFilter {
 	var filter models.VehicleFilter
 
-	// Parse query parameters
 	if brandID := c.Query("brand_id"); brandID != "" {
 		if id, err := strconv.ParseUint(brandID, 10, 32); err == nil {
 			filter.BrandID = uint(id)
@@ -24,8 +25,16 @@ func GetVehicles(c *gin.Context) {
 	}
 
 	filter.FuelType = c.Query("fuel_type")
+	filter.Transmission = c.Query("transmission")
+	filter.Color = c.Query("color")
 	filter.Search = c.Query("search")
 
+	if year := c.Query("year"); year != "" {
+		if y, err := strconv.Atoi(year); err == nil {
+			filter.Year = y
+		}
+	}
+
 	if minPrice := c.Query("min_price"); minPrice != "" {
 		if price, err := strconv.ParseFloat(minPrice, 64); err == nil {
 			filter.MinPrice = price
@@ -38,6 +47,65 @@ func GetVehicles(c *gin.Context) {
 		}
 	}
 
+	return filter
+}
+
+// maxSortKeys caps the keys a vehicle list can be sorted by
+const maxSortKeys = 4
+
+// parseVehicleSort reads a sort parameter such as "-price,year": fields from
+// vehicleSortFields separated by commas, each prefixed with "-" to sort
+// descending. Repeated fields are ignored after their first use.
+func parseVehicleSort(param string) ([]models.VehicleSort, error) {
+	if strings.TrimSpace(param) == "" {
+		return nil, nil
+	}
+
+	var keys []models.VehicleSort
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(param, ",") {
+		part = strings.TrimSpace(part)
+		key := models.VehicleSort{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if !slices.Contains(vehicleSortFields, key.Field) {
+			return nil, validationError(FieldError{
+				Field:   "sort",
+				Message: fmt.Sprintf("has unknown field %q; use %s", key.Field, strings.Join(vehicleSortFields, ", ")),
+			})
+		}
+		if seen[key.Field] {
+			continue
+		}
+		seen[key.Field] = true
+		keys = append(keys, key)
+	}
+
+	if len(keys) > maxSortKeys {
+		return nil, validationError(FieldError{Field: "sort", Message: fmt.Sprintf("may have at most %d fields", maxSortKeys)})
+	}
+	return keys, nil
+}
+
+// expandSearch lets filter's search words also match their synonyms and,
+// when misspelt, the inventory's names they are close to. It returns the
+// vocabulary used, or nil if there is no search.
+func (s *Server) expandSearch(filter *models.VehicleFilter) (*searchVocabulary, error) {
+	terms := searchTerms(filter.Search)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	vocabulary, err := s.loadSearchVocabulary()
+	if err != nil {
+		return nil, err
+	}
+	filter.Expansions = vocabulary.expansions(terms)
+	return vocabulary, nil
+}
+
+// GetVehicles handles GET /api/vehicles with filters
+func (s *Server) GetVehicles(c *gin.Context) {
+	filter := vehicleFilterFromQuery(c)
+
 	// Default pagination
 	filter.Limit = 20
 	filter.Offset = 0
@@ -54,147 +122,284 @@ func GetVehicles(c *gin.Context) {
 		}
 	}
 
-	// Build query
-	query := database.DB.Preload("Brand")
-
-	// Apply filters
-	if filter.BrandID > 0 {
-		query = query.Where("brand_id = ?", filter.BrandID)
-	}
-
-	if filter.FuelType != "" {
-		query = query.Where("fuel_type = ?", filter.FuelType)
+	sortKeys, err := parseVehicleSort(c.Query("sort"))
+	if err != nil {
+		respondError(c, err)
+		return
 	}
+	filter.Sort = sortKeys
 
-	if filter.MinPrice > 0 {
-		query = query.Where("price >= ?", filter.MinPrice)
+	vocabulary, err := s.expandSearch(&filter)
+	if err != nil {
+		respondError(c, internalError("Failed to fetch vehicles", err))
+		return
 	}
+	terms := searchTerms(filter.Search)
 
-	if filter.MaxPrice > 0 {
-		query = query.Where("price <= ?", filter.MaxPrice)
+	vehicles, total, err := s.Vehicles.List(filter)
+	if err != nil {
+		respondError(c, internalError("Failed to fetch vehicles", err))
+		return
 	}
 
-	if filter.Search != "" {
-		searchTerm := "%" + strings.ToLower(filter.Search) + "%"
-		query = query.Joins("JOIN brands ON brands.id = vehicles.brand_id").
-			Where("LOWER(vehicles.name) LIKE ? OR LOWER(vehicles.model) LIKE ? OR LOWER(brands.name) LIKE ?",
-				searchTerm, searchTerm, searchTerm)
+	response := gin.H{
+		"vehicles": vehicles,
+		"total":    total,
+		"limit":    filter.Limit,
+		"offset":   filter.Offset,
 	}
 
-	// Apply availability filter (only show available vehicles by default)
-	query = query.Where("availability = ?", true)
-
-	// Execute query with pagination
-	if err := query.Limit(filter.Limit).Offset(filter.Offset).Find(&vehicles).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch vehicles"})
-		return
+	// Only first pages count, so paging through results is one search
+	if total > 0 && filter.Offset == 0 {
+		s.recordSearch(terms)
 	}
 
-	// Get total count for pagination
-	var total int64
-	countQuery := database.DB.Model(&models.Vehicle{})
-
-	if filter.BrandID > 0 {
-		countQuery = countQuery.Where("brand_id = ?", filter.BrandID)
-	}
-	if filter.FuelType != "" {
-		countQuery = countQuery.Where("fuel_type = ?", filter.FuelType)
-	}
-	if filter.MinPrice > 0 {
-		countQuery = countQuery.Where("price >= ?", filter.MinPrice)
-	}
-	if filter.MaxPrice > 0 {
-		countQuery = countQuery.Where("price <= ?", filter.MaxPrice)
-	}
-	if filter.Search != "" {
-		searchTerm := "%" + strings.ToLower(filter.Search) + "%"
-		countQuery = countQuery.Joins("JOIN brands ON brands.id = vehicles.brand_id").
-			Where("LOWER(vehicles.name) LIKE ? OR LOWER(vehicles.model) LIKE ? OR LOWER(brands.name) LIKE ?",
-				searchTerm, searchTerm, searchTerm)
+	if total == 0 && vocabulary != nil {
+		suggestion, err := s.didYouMean(vocabulary, filter, terms)
+		if err != nil {
+			respondError(c, internalError("Failed to fetch vehicles", err))
+			return
+		}
+		if suggestion != "" {
+			response["did_you_mean"] = suggestion
+		}
 	}
-	countQuery = countQuery.Where("availability = ?", true)
-
-	countQuery.Count(&total)
 
-	c.JSON(http.StatusOK, gin.H{
-		"vehicles": vehicles,
-		"total":    total,
-		"limit":    filter.Limit,
-		"offset":   filter.Offset,
-	})
+	c.JSON(http.StatusOK, response)
 }
 
 // GetVehicleByID handles GET /api/vehicles/:id
-func GetVehicleByID(c *gin.Context) {
-	id := c.Param("id")
-	var vehicle models.Vehicle
+func (s *Server) GetVehicleByID(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		respondError(c, notFoundError("Vehicle"))
+		return
+	}
 
-	if err := database.DB.Preload("Brand").First(&vehicle, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle not found"})
+	vehicle, err := s.Vehicles.GetByID(id)
+	if err != nil {
+		respondError(c, notFoundError("Vehicle"))
 		return
 	}
 
+	setETag(c, vehicle.Version)
 	c.JSON(http.StatusOK, vehicle)
 }
 
 // CreateVehicle handles POST /api/admin/vehicles
-func CreateVehicle(c *gin.Context) {
+func (s *Server) CreateVehicle(c *gin.Context) {
 	var vehicle models.Vehicle
 
-	if err := c.ShouldBindJSON(&vehicle); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &vehicle) {
 		return
 	}
 
-	if err := database.DB.Create(&vehicle).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create vehicle"})
+	err := s.Tx.WithinTransaction(func(repos Repositories) error {
+		if err := requireBrand(repos.Brands, vehicle.BrandID); err != nil {
+			return err
+		}
+		if err := repos.Vehicles.Create(&vehicle); err != nil {
+			return err
+		}
+		return enqueueEvent(repos, EventVehicleCreated, vehicle)
+	})
+
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr):
+		respondError(c, apiErr)
+		return
+	case err != nil:
+		respondError(c, internalError("Failed to create vehicle", err))
 		return
 	}
 
 	// Fetch the created vehicle with brand information
-	database.DB.Preload("Brand").First(&vehicle, vehicle.ID)
+	if created, err := s.Vehicles.GetByID(vehicle.ID); err == nil {
+		vehicle = *created
+	}
 
+	setETag(c, vehicle.Version)
 	c.JSON(http.StatusCreated, vehicle)
 }
 
 // UpdateVehicle handles PUT /api/admin/vehicles/:id
-func UpdateVehicle(c *gin.Context) {
-	id := c.Param("id")
-	var vehicle models.Vehicle
+func (s *Server) UpdateVehicle(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		respondError(c, notFoundError("Vehicle"))
+		return
+	}
+
+	var vehicle *models.Vehicle
+	var bindErr error
+	err = s.Tx.WithinTransaction(func(repos Repositories) error {
+		v, err := repos.Vehicles.GetByIDForUpdate(id)
+		if err != nil {
+			return err
+		}
+
+		if err := checkIfMatch(c, v.Version); err != nil {
+			return err
+		}
+
+		// The body replaces the editable fields only. It may echo the fields
+		// it was read with, but the path names the vehicle and If-Match its
+		// version.
+		current := *v
+		if bindErr = c.ShouldBindJSON(v); bindErr != nil {
+			return bindErr
+		}
+		v.ID, v.Version, v.CreatedAt = current.ID, current.Version, current.CreatedAt
+		v.Brand, v.Bookings = current.Brand, current.Bookings
+
+		if err := requireBrand(repos.Brands, v.BrandID); err != nil {
+			return err
+		}
+		// A PUT restates availability, so only a change is taken as set by
+		// hand; echoing a held vehicle's availability leaves the hold alone
+		if v.Availability != current.Availability {
+			if err := keepHandSetAvailability(repos, v); err != nil {
+				return err
+			}
+		}
+
+		vehicle = v
+		if err := repos.Vehicles.Update(v); err != nil {
+			return err
+		}
+		return enqueueEvent(repos, EventVehicleUpdated, v)
+	})
+
+	var apiErr *APIError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		respondError(c, notFoundError("Vehicle"))
+		return
+	case errors.Is(err, ErrVersionConflict):
+		respondError(c, versionConflictError())
+		return
+	case bindErr != nil:
+		respondError(c, bindError(bindErr))
+		return
+	case errors.As(err, &apiErr):
+		respondError(c, apiErr)
+		return
+	case err != nil:
+		respondError(c, internalError("Failed to update vehicle", err))
+		return
+	}
+
+	// Fetch the updated vehicle with brand information
+	if updated, err := s.Vehicles.GetByID(vehicle.ID); err == nil {
+		vehicle = updated
+	}
+
+	setETag(c, vehicle.Version)
+	c.JSON(http.StatusOK, vehicle)
+}
 
-	if err := database.DB.First(&vehicle, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle not found"})
+// PatchVehicle handles PATCH /api/admin/vehicles/:id, updating only the
+// fields present in a JSON Merge Patch
+func (s *Server) PatchVehicle(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		respondError(c, notFoundError("Vehicle"))
 		return
 	}
 
-	if err := c.ShouldBindJSON(&vehicle); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	patch, err := readMergePatch(c, vehicleReadOnlyFields...)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	if err := database.DB.Save(&vehicle).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update vehicle"})
+	var vehicle *models.Vehicle
+	err = s.Tx.WithinTransaction(func(repos Repositories) error {
+		v, err := repos.Vehicles.GetByIDForUpdate(id)
+		if err != nil {
+			return err
+		}
+
+		if err := checkIfMatch(c, v.Version); err != nil {
+			return err
+		}
+		if err := applyMergePatch(v, patch); err != nil {
+			return err
+		}
+		if err := requireBrand(repos.Brands, v.BrandID); err != nil {
+			return err
+		}
+		if _, ok := patch["availability"]; ok {
+			if err := keepHandSetAvailability(repos, v); err != nil {
+				return err
+			}
+		}
+
+		vehicle = v
+		if err := repos.Vehicles.Update(v); err != nil {
+			return err
+		}
+		return enqueueEvent(repos, EventVehicleUpdated, v)
+	})
+
+	var apiErr *APIError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		respondError(c, notFoundError("Vehicle"))
+		return
+	case errors.Is(err, ErrVersionConflict):
+		respondError(c, versionConflictError())
+		return
+	case errors.As(err, &apiErr):
+		respondError(c, apiErr)
+		return
+	case err != nil:
+		respondError(c, internalError("Failed to update vehicle", err))
 		return
 	}
 
 	// Fetch the updated vehicle with brand information
-	database.DB.Preload("Brand").First(&vehicle, vehicle.ID)
+	if updated, err := s.Vehicles.GetByID(vehicle.ID); err == nil {
+		vehicle = updated
+	}
 
+	setETag(c, vehicle.Version)
 	c.JSON(http.StatusOK, vehicle)
 }
 
 // DeleteVehicle handles DELETE /api/admin/vehicles/:id
-func DeleteVehicle(c *gin.Context) {
-	id := c.Param("id")
-	var vehicle models.Vehicle
-
-	if err := database.DB.First(&vehicle, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle not found"})
+func (s *Server) DeleteVehicle(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		respondError(c, notFoundError("Vehicle"))
 		return
 	}
 
-	if err := database.DB.Delete(&vehicle).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete vehicle"})
+	err = s.Tx.WithinTransaction(func(repos Repositories) error {
+		vehicle, err := repos.Vehicles.GetByIDForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if err := checkIfMatch(c, vehicle.Version); err != nil {
+			return err
+		}
+		if err := repos.Vehicles.Delete(id); err != nil {
+			return err
+		}
+		return enqueueEvent(repos, EventVehicleDeleted, vehicle)
+	})
+
+	var apiErr *APIError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		respondError(c, notFoundError("Vehicle"))
+		return
+	case errors.As(err, &apiErr):
+		respondError(c, apiErr)
+		return
+	case err != nil:
+		respondError(c, internalError("Failed to delete vehicle", err))
 		return
 	}
 
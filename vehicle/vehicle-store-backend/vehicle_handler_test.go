@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"vehicle-store-backend/internal/models"
+)
+
+func TestParseVehicleSort(t *testing.T) {
+	tests := []struct {
+		param string
+		want  []models.VehicleSort
+	}{
+		{"", nil},
+		{"  ", nil},
+		{"price", []models.VehicleSort{{Field: SortPrice}}},
+		{"-price", []models.VehicleSort{{Field: SortPrice, Desc: true}}},
+		{
+			"-year, price,name",
+			[]models.VehicleSort{{Field: SortYear, Desc: true}, {Field: SortPrice}, {Field: SortName}},
+		},
+		{
+			"popularity,-popularity,mileage",
+			[]models.VehicleSort{{Field: SortPopularity}, {Field: SortMileage}},
+		},
+		{
+			"price,year,mileage,created_at,price",
+			[]models.VehicleSort{{Field: SortPrice}, {Field: SortYear}, {Field: SortMileage}, {Field: SortCreatedAt}},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := parseVehicleSort(tt.param)
+		if err != nil {
+			t.Errorf("parseVehicleSort(%q): %v", tt.param, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseVehicleSort(%q) = %+v, want %+v", tt.param, got, tt.want)
+		}
+	}
+}
+
+func TestParseVehicleSortErrors(t *testing.T) {
+	for _, param := range []string{
+		"colour",
+		"-",
+		"price,",
+		"Price",
+		"price;drop",
+		"price,year,mileage,created_at,name",
+	} {
+		_, err := parseVehicleSort(param)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.Code != CodeValidationFailed {
+			t.Errorf("parseVehicleSort(%q) error = %v, want a validation error", param, err)
+		}
+	}
+}
+
+func TestGetVehiclesSort(t *testing.T) {
+	ts := newTestServer(t)
+	civic := ts.addVehicle(t, models.Vehicle{Name: "Civic", Year: 2022, Price: 24000, Mileage: 30000})
+	camry := ts.addVehicle(t, models.Vehicle{Name: "Camry", Year: 2024, Price: 28000, Mileage: 1000})
+	accord := ts.addVehicle(t, models.Vehicle{Name: "Accord", Year: 2024, Price: 24000, Mileage: 5000})
+
+	// Civic has the most real bookings once spam is left out
+	for _, booking := range []models.Booking{
+		{VehicleID: civic.ID},
+		{VehicleID: civic.ID},
+		{VehicleID: camry.ID},
+		{VehicleID: accord.ID, SuspectedSpam: true},
+		{VehicleID: accord.ID, SuspectedSpam: true},
+		{VehicleID: accord.ID, SuspectedSpam: true},
+	} {
+		booking.CustomerName = "Test Customer"
+		booking.CustomerEmail = "test@example.com"
+		booking.Status = "pending"
+		if err := ts.repos.Bookings.Create(&booking); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		sort string
+		want []string
+	}{
+		{"", []string{"Civic", "Camry", "Accord"}},
+		{"price", []string{"Civic", "Accord", "Camry"}},
+		{"-year,price", []string{"Accord", "Camry", "Civic"}},
+		{"-year,-price", []string{"Camry", "Accord", "Civic"}},
+		{"price,-mileage", []string{"Civic", "Accord", "Camry"}},
+		{"name", []string{"Accord", "Camry", "Civic"}},
+		{"-popularity", []string{"Civic", "Camry", "Accord"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			w := ts.do(http.MethodGet, "/api/vehicles?sort="+tt.sort, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("got %d: %s", w.Code, w.Body.String())
+			}
+
+			var body struct {
+				Vehicles []models.Vehicle `json:"vehicles"`
+			}
+			decode(t, w, &body)
+
+			var names []string
+			for _, v := range body.Vehicles {
+				names = append(names, v.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("order = %v, want %v", names, tt.want)
+			}
+		})
+	}
+
+	t.Run("unknown field", func(t *testing.T) {
+		w := ts.do(http.MethodGet, "/api/vehicles?sort=-colour", "")
+		body := expectError(t, w, http.StatusUnprocessableEntity, CodeValidationFailed)
+		if len(body.Error.Details) == 0 || body.Error.Details[0].Field != "sort" {
+			t.Errorf("details = %+v, want field sort", body.Error.Details)
+		}
+	})
+}
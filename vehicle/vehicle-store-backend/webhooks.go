@@ -0,0 +1,492 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"vehicle-store-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Webhook event types
+const (
+	EventVehicleCreated       = "vehicle.created"
+	EventVehicleUpdated       = "vehicle.updated"
+	EventVehicleDeleted       = "vehicle.deleted"
+	EventBookingCreated       = "booking.created"
+	EventBookingUpdated       = "booking.updated"
+	EventBookingStatusChanged = "booking.status_changed"
+)
+
+// webhookEventTypes lists the event types subscriptions may filter on
+var webhookEventTypes = []string{
+	EventVehicleCreated, EventVehicleUpdated, EventVehicleDeleted,
+	EventBookingCreated, EventBookingUpdated, EventBookingStatusChanged,
+}
+
+const (
+	// webhookMaxAttempts is how many times a delivery is tried before it is
+	// marked failed
+	webhookMaxAttempts = 8
+	// webhookBaseBackoff is the wait after the first failure; it doubles
+	// after each further failure up to webhookMaxBackoff
+	webhookBaseBackoff = 30 * time.Second
+	webhookMaxBackoff  = time.Hour
+	// webhookBatchSize bounds the events and deliveries handled per tick
+	webhookBatchSize = 100
+	// webhookSubscriptionConcurrency bounds the requests in flight to one
+	// subscription
+	webhookSubscriptionConcurrency = 4
+)
+
+// webhookClient sends webhook requests
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// enqueueEvent records an outbox event carrying data. Call it with the
+// repositories of the transaction that makes the change, so the event is
+// committed if and only if the change is.
+func enqueueEvent(repos Repositories, eventType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return repos.Events.Enqueue(&models.OutboxEvent{EventType: eventType, Payload: string(payload)})
+}
+
+// subscribedTo reports whether sub wants events of eventType
+func subscribedTo(sub models.WebhookSubscription, eventType string) bool {
+	events := strings.TrimSpace(sub.Events)
+	if events == "" || events == "*" {
+		return true
+	}
+	for _, e := range strings.Split(events, ",") {
+		if strings.TrimSpace(e) == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookSignature returns the hex HMAC-SHA256 of "timestamp.body" keyed by secret
+func webhookSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff returns the wait before retrying after attempts failures
+func webhookBackoff(attempts int) time.Duration {
+	backoff := webhookBaseBackoff
+	for i := 1; i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, webhookMaxBackoff)
+}
+
+// FanOutEvents turns unprocessed outbox events into pending deliveries for
+// each matching subscription. Each event is fanned out in its own transaction.
+func (s *Server) FanOutEvents() error {
+	events, err := s.Events.ListUnprocessed(webhookBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		err := s.Tx.WithinTransaction(func(repos Repositories) error {
+			subs, err := repos.Webhooks.ListActive()
+			if err != nil {
+				return err
+			}
+
+			for _, sub := range subs {
+				if !subscribedTo(sub, event.EventType) {
+					continue
+				}
+				delivery := models.WebhookDelivery{
+					SubscriptionID: sub.ID,
+					EventID:        event.ID,
+					EventType:      event.EventType,
+					Payload:        event.Payload,
+					OccurredAt:     event.CreatedAt,
+					Status:         "pending",
+					NextAttemptAt:  time.Now(),
+				}
+				if err := repos.Webhooks.CreateDelivery(&delivery); err != nil {
+					return err
+				}
+			}
+
+			return repos.Events.MarkProcessed(event.ID, time.Now())
+		})
+		if err != nil {
+			return fmt.Errorf("event %d: %w", event.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// DeliverWebhooks attempts every delivery that is due. Subscriptions are
+// served in parallel with at most webhookSubscriptionConcurrency requests in
+// flight to each, so a slow subscriber only holds back its own deliveries.
+// Deliveries to an inactive or deleted subscription are marked failed. An
+// error with one delivery does not stop the others; they are all returned.
+func (s *Server) DeliverWebhooks() error {
+	deliveries, err := s.Webhooks.ListDueDeliveries(time.Now(), webhookBatchSize)
+	if err != nil {
+		return err
+	}
+
+	var subIDs []uint
+	bySub := make(map[uint][]models.WebhookDelivery)
+	for _, delivery := range deliveries {
+		if _, ok := bySub[delivery.SubscriptionID]; !ok {
+			subIDs = append(subIDs, delivery.SubscriptionID)
+		}
+		bySub[delivery.SubscriptionID] = append(bySub[delivery.SubscriptionID], delivery)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	report := func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+
+	for _, subID := range subIDs {
+		sub, err := s.Webhooks.GetByID(subID)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			s.abandonDeliveries(bySub[subID], "subscription deleted", report)
+			continue
+		case err != nil:
+			// Leave the deliveries pending for the next tick
+			report(fmt.Errorf("subscription %d: %w", subID, err))
+			continue
+		case !sub.Active:
+			s.abandonDeliveries(bySub[subID], "subscription inactive", report)
+			continue
+		}
+
+		wg.Add(1)
+		go func(sub *models.WebhookSubscription, deliveries []models.WebhookDelivery) {
+			defer wg.Done()
+
+			var inFlight sync.WaitGroup
+			slots := make(chan struct{}, webhookSubscriptionConcurrency)
+			for _, delivery := range deliveries {
+				slots <- struct{}{}
+				inFlight.Add(1)
+				go func(delivery models.WebhookDelivery) {
+					defer func() {
+						<-slots
+						inFlight.Done()
+					}()
+					if err := s.attemptDelivery(sub, &delivery); err != nil {
+						report(fmt.Errorf("delivery %d: %w", delivery.ID, err))
+					}
+				}(delivery)
+			}
+			inFlight.Wait()
+		}(sub, bySub[subID])
+	}
+
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// attemptDelivery sends delivery to sub once and records the outcome,
+// scheduling a retry with backoff or giving up after webhookMaxAttempts
+func (s *Server) attemptDelivery(sub *models.WebhookSubscription, delivery *models.WebhookDelivery) error {
+	code, sendErr := sendWebhook(sub, delivery)
+
+	delivery.Attempts++
+	delivery.ResponseCode = code
+	if sendErr == nil {
+		now := time.Now()
+		delivery.Status = "succeeded"
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	} else {
+		delivery.LastError = sendErr.Error()
+		if delivery.Attempts >= webhookMaxAttempts {
+			delivery.Status = "failed"
+		} else {
+			delivery.NextAttemptAt = time.Now().Add(webhookBackoff(delivery.Attempts))
+		}
+	}
+
+	return s.Webhooks.UpdateDelivery(delivery)
+}
+
+// abandonDeliveries marks deliveries failed without sending them
+func (s *Server) abandonDeliveries(deliveries []models.WebhookDelivery, reason string, report func(error)) {
+	for _, delivery := range deliveries {
+		delivery.Status = "failed"
+		delivery.LastError = reason
+		if err := s.Webhooks.UpdateDelivery(&delivery); err != nil {
+			report(fmt.Errorf("delivery %d: %w", delivery.ID, err))
+		}
+	}
+}
+
+// sendWebhook posts the signed delivery payload to sub and returns the
+// response status code
+func sendWebhook(sub *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, error) {
+	body, err := json.Marshal(struct {
+		ID         uint            `json:"id"`
+		Type       string          `json:"type"`
+		OccurredAt time.Time       `json:"occurred_at"`
+		Data       json.RawMessage `json:"data"`
+	}{delivery.EventID, delivery.EventType, delivery.OccurredAt, json.RawMessage(delivery.Payload)})
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+webhookSignature(sub.Secret, timestamp, body))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// StartWebhookDispatcher fans out outbox events and delivers due webhooks
+// every interval until ctx is done. Undelivered events survive restarts in
+// the outbox and delivery tables, so delivery is at least once.
+func (s *Server) StartWebhookDispatcher(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.FanOutEvents(); err != nil {
+				log.Println("Failed to fan out webhook events:", err)
+			}
+			if err := s.DeliverWebhooks(); err != nil {
+				log.Println("Failed to deliver webhooks:", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// webhookRequest is the body accepted when creating or updating a subscription
+type webhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active"`
+}
+
+// validate checks the URL and event names
+func (req webhookRequest) validate() error {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return validationError(FieldError{Field: "url", Message: "must be an absolute http or https URL"})
+	}
+	for _, e := range req.Events {
+		if e == "*" {
+			continue
+		}
+		known := false
+		for _, t := range webhookEventTypes {
+			if e == t {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return validationError(FieldError{Field: "events", Message: fmt.Sprintf("contains unknown event type %q", e)})
+		}
+	}
+	return nil
+}
+
+// newWebhookSecret returns a random signing secret
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// GetWebhooks handles GET /api/admin/webhooks
+func (s *Server) GetWebhooks(c *gin.Context) {
+	subs, err := s.Webhooks.List()
+	if err != nil {
+		respondError(c, internalError("Failed to fetch webhooks", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhooks":    subs,
+		"event_types": webhookEventTypes,
+	})
+}
+
+// CreateWebhook handles POST /api/admin/webhooks. The signing secret is only
+// returned in this response.
+func (s *Server) CreateWebhook(c *gin.Context) {
+	var req webhookRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		respondError(c, internalError("Failed to create webhook", err))
+		return
+	}
+
+	sub := models.WebhookSubscription{
+		URL:    req.URL,
+		Secret: secret,
+		Events: strings.Join(req.Events, ","),
+		Active: req.Active == nil || *req.Active,
+	}
+
+	if err := s.Webhooks.Create(&sub); err != nil {
+		respondError(c, internalError("Failed to create webhook", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"webhook": sub,
+		"secret":  secret,
+	})
+}
+
+// UpdateWebhook handles PUT /api/admin/webhooks/:id
+func (s *Server) UpdateWebhook(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		respondError(c, notFoundError("Webhook"))
+		return
+	}
+
+	sub, err := s.Webhooks.GetByID(id)
+	if err != nil {
+		respondError(c, notFoundError("Webhook"))
+		return
+	}
+
+	var req webhookRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	sub.URL = req.URL
+	sub.Events = strings.Join(req.Events, ",")
+	if req.Active != nil {
+		sub.Active = *req.Active
+	}
+
+	if err := s.Webhooks.Update(sub); err != nil {
+		respondError(c, internalError("Failed to update webhook", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+// DeleteWebhook handles DELETE /api/admin/webhooks/:id
+func (s *Server) DeleteWebhook(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		respondError(c, notFoundError("Webhook"))
+		return
+	}
+
+	if err := s.Webhooks.Delete(id); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			respondError(c, notFoundError("Webhook"))
+			return
+		}
+		respondError(c, internalError("Failed to delete webhook", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// GetWebhookDeliveries handles GET /api/admin/webhooks/:id/deliveries
+func (s *Server) GetWebhookDeliveries(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		respondError(c, notFoundError("Webhook"))
+		return
+	}
+
+	if _, err := s.Webhooks.GetByID(id); err != nil {
+		respondError(c, notFoundError("Webhook"))
+		return
+	}
+
+	limit := 50
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+
+	deliveries, err := s.Webhooks.ListDeliveries(id, limit)
+	if err != nil {
+		respondError(c, internalError("Failed to fetch deliveries", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"vehicle-store-backend/internal/models"
+)
+
+// receivedWebhook is a request received by a webhookReceiver
+type receivedWebhook struct {
+	Header http.Header
+	Body   []byte
+}
+
+// webhookReceiver is a subscriber endpoint that answers with status
+type webhookReceiver struct {
+	*httptest.Server
+	status int
+
+	mu       sync.Mutex
+	received []receivedWebhook
+}
+
+func newWebhookReceiver(t *testing.T, status int) *webhookReceiver {
+	t.Helper()
+
+	r := &webhookReceiver{status: status}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.received = append(r.received, receivedWebhook{Header: req.Header.Clone(), Body: body})
+		r.mu.Unlock()
+		w.WriteHeader(r.status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+// Received returns the requests received so far
+func (r *webhookReceiver) Received() []receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedWebhook(nil), r.received...)
+}
+
+// subscribe stores a webhook subscription to url
+func (ts *testServer) subscribe(t *testing.T, url string, active bool) *models.WebhookSubscription {
+	t.Helper()
+
+	sub := models.WebhookSubscription{URL: url, Secret: "s3cret", Active: active}
+	if err := ts.Webhooks.Create(&sub); err != nil {
+		t.Fatal(err)
+	}
+	return &sub
+}
+
+// queueDelivery stores a delivery to subscriptionID that is due now
+func (ts *testServer) queueDelivery(t *testing.T, subscriptionID uint) *models.WebhookDelivery {
+	t.Helper()
+
+	delivery := models.WebhookDelivery{
+		SubscriptionID: subscriptionID,
+		EventID:        1,
+		EventType:      EventVehicleUpdated,
+		Payload:        `{"id":7}`,
+		OccurredAt:     time.Now(),
+		Status:         "pending",
+		NextAttemptAt:  time.Now(),
+	}
+	if err := ts.Webhooks.CreateDelivery(&delivery); err != nil {
+		t.Fatal(err)
+	}
+	return &delivery
+}
+
+// delivery returns the stored delivery with id for subscriptionID
+func (ts *testServer) delivery(t *testing.T, subscriptionID, id uint) models.WebhookDelivery {
+	t.Helper()
+
+	deliveries, err := ts.Webhooks.ListDeliveries(subscriptionID, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range deliveries {
+		if d.ID == id {
+			return d
+		}
+	}
+	t.Fatalf("delivery %d not found", id)
+	return models.WebhookDelivery{}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	ts := newTestServer(t)
+	receiver := newWebhookReceiver(t, http.StatusNoContent)
+	sub := ts.subscribe(t, receiver.URL, true)
+	delivery := ts.queueDelivery(t, sub.ID)
+
+	if err := ts.DeliverWebhooks(); err != nil {
+		t.Fatal(err)
+	}
+
+	received := receiver.Received()
+	if len(received) != 1 {
+		t.Fatalf("received %d requests, want 1", len(received))
+	}
+	req := received[0]
+
+	mac := hmac.New(sha256.New, []byte(sub.Secret))
+	mac.Write([]byte(req.Header.Get("X-Webhook-Timestamp") + "." + string(req.Body)))
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); req.Header.Get("X-Webhook-Signature") != want {
+		t.Errorf("signature = %q, want %q", req.Header.Get("X-Webhook-Signature"), want)
+	}
+	if got := req.Header.Get("X-Webhook-Event"); got != EventVehicleUpdated {
+		t.Errorf("event header = %q", got)
+	}
+
+	var body struct {
+		ID   uint            `json:"id"`
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(req.Body, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.ID != delivery.EventID || body.Type != EventVehicleUpdated || string(body.Data) != `{"id":7}` {
+		t.Errorf("body = %s", req.Body)
+	}
+
+	if got := ts.delivery(t, sub.ID, delivery.ID); got.Status != "succeeded" || got.ResponseCode != http.StatusNoContent {
+		t.Errorf("delivery status = %q, code = %d", got.Status, got.ResponseCode)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{6, 16 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{20, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := webhookBackoff(tt.attempts); got != tt.want {
+			t.Errorf("webhookBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookRetriesUntilAttemptCap(t *testing.T) {
+	ts := newTestServer(t)
+	receiver := newWebhookReceiver(t, http.StatusInternalServerError)
+	sub := ts.subscribe(t, receiver.URL, true)
+	delivery := ts.queueDelivery(t, sub.ID)
+
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		before := time.Now()
+		if err := ts.DeliverWebhooks(); err != nil {
+			t.Fatal(err)
+		}
+		after := time.Now()
+
+		got := ts.delivery(t, sub.ID, delivery.ID)
+		if got.Attempts != attempt || got.ResponseCode != http.StatusInternalServerError {
+			t.Fatalf("attempt %d: attempts = %d, code = %d", attempt, got.Attempts, got.ResponseCode)
+		}
+		if attempt == webhookMaxAttempts {
+			if got.Status != "failed" {
+				t.Errorf("status after %d attempts = %q, want failed", attempt, got.Status)
+			}
+			break
+		}
+
+		backoff := webhookBackoff(attempt)
+		if got.Status != "pending" || got.NextAttemptAt.Before(before.Add(backoff)) || got.NextAttemptAt.After(after.Add(backoff)) {
+			t.Fatalf("attempt %d: status = %q, next attempt in %v, want pending in %v",
+				attempt, got.Status, got.NextAttemptAt.Sub(before), backoff)
+		}
+
+		// Not due yet, so another tick leaves it alone
+		if err := ts.DeliverWebhooks(); err != nil {
+			t.Fatal(err)
+		}
+		if n := len(receiver.Received()); n != attempt {
+			t.Fatalf("attempt %d: %d requests sent before the backoff elapsed", attempt, n)
+		}
+
+		got.NextAttemptAt = time.Now()
+		if err := ts.Webhooks.UpdateDelivery(&got); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := ts.DeliverWebhooks(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(receiver.Received()); n != webhookMaxAttempts {
+		t.Errorf("%d requests sent, want %d", n, webhookMaxAttempts)
+	}
+}
+
+func TestWebhookSkipsInactiveAndDeletedSubscriptions(t *testing.T) {
+	ts := newTestServer(t)
+	inactiveReceiver := newWebhookReceiver(t, http.StatusOK)
+	activeReceiver := newWebhookReceiver(t, http.StatusOK)
+
+	inactive := ts.subscribe(t, inactiveReceiver.URL, false)
+	active := ts.subscribe(t, activeReceiver.URL, true)
+	skipped := ts.queueDelivery(t, inactive.ID)
+	orphaned := ts.queueDelivery(t, 9999)
+	sent := ts.queueDelivery(t, active.ID)
+
+	if err := ts.DeliverWebhooks(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(inactiveReceiver.Received()); n != 0 {
+		t.Errorf("inactive subscription received %d requests", n)
+	}
+	if got := ts.delivery(t, inactive.ID, skipped.ID); got.Status != "failed" || got.Attempts != 0 || got.LastError != "subscription inactive" {
+		t.Errorf("inactive delivery = %+v", got)
+	}
+	if got := ts.delivery(t, 9999, orphaned.ID); got.Status != "failed" || got.LastError != "subscription deleted" {
+		t.Errorf("orphaned delivery = %+v", got)
+	}
+	if got := ts.delivery(t, active.ID, sent.ID); got.Status != "succeeded" {
+		t.Errorf("active delivery status = %q, want succeeded", got.Status)
+	}
+}
+
+func TestWebhookSlowSubscriber(t *testing.T) {
+	ts := newTestServer(t)
+
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	started.Add(webhookSubscriptionConcurrency)
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+			if maxInFlight <= webhookSubscriptionConcurrency {
+				started.Done()
+			}
+		}
+		mu.Unlock()
+
+		<-release
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	t.Cleanup(slow.Close)
+	served := make(chan struct{}, 1)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served <- struct{}{}
+	}))
+	t.Cleanup(fast.Close)
+
+	slowSub := ts.subscribe(t, slow.URL, true)
+	fastSub := ts.subscribe(t, fast.URL, true)
+	for i := 0; i < 2*webhookSubscriptionConcurrency; i++ {
+		ts.queueDelivery(t, slowSub.ID)
+	}
+	fastDelivery := ts.queueDelivery(t, fastSub.ID)
+
+	done := make(chan error)
+	go func() { done <- ts.DeliverWebhooks() }()
+
+	// The fast subscriber is served while the slow one holds every slot
+	started.Wait()
+	select {
+	case <-served:
+	case <-time.After(5 * time.Second):
+		close(release)
+		t.Fatal("fast subscriber was not served while the slow one was busy")
+	}
+	close(release)
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if maxInFlight != webhookSubscriptionConcurrency {
+		t.Errorf("%d requests in flight to one subscription, want %d", maxInFlight, webhookSubscriptionConcurrency)
+	}
+	if got := ts.delivery(t, fastSub.ID, fastDelivery.ID); got.Status != "succeeded" {
+		t.Errorf("fast delivery status = %q", got.Status)
+	}
+}
+
+func TestWebhookOutbox(t *testing.T) {
+	servers := map[string]func(t *testing.T) *testServer{
+		"memory": newTestServer,
+		"sqlite": newGormTestServer,
+	}
+
+	for name, newServer := range servers {
+		t.Run(name, func(t *testing.T) {
+			ts := newServer(t)
+			receiver := newWebhookReceiver(t, http.StatusOK)
+			sub := ts.subscribe(t, receiver.URL, true)
+			brand := models.Brand{Name: "Toyota"}
+			if err := ts.Brands.Create(&brand); err != nil {
+				t.Fatal(err)
+			}
+
+			// createVehicle stores a vehicle and its event in one transaction,
+			// failing after both are written when fail is set
+			createVehicle := func(name string, fail error) error {
+				return ts.Tx.WithinTransaction(func(repos Repositories) error {
+					vehicle := models.Vehicle{BrandID: brand.ID, Name: name, Year: 2024, Price: 28000, FuelType: "Petrol"}
+					if err := repos.Vehicles.Create(&vehicle); err != nil {
+						return err
+					}
+					if err := enqueueEvent(repos, EventVehicleCreated, vehicle); err != nil {
+						return err
+					}
+					return fail
+				})
+			}
+
+			rollback := errors.New("rollback")
+			if err := createVehicle("Corolla", rollback); !errors.Is(err, rollback) {
+				t.Fatalf("rolled back transaction returned %v", err)
+			}
+			if err := createVehicle("Camry", nil); err != nil {
+				t.Fatal(err)
+			}
+
+			events, err := ts.Events.ListUnprocessed(100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != 1 {
+				t.Fatalf("%d events queued, want only the committed one", len(events))
+			}
+
+			if err := ts.FanOutEvents(); err != nil {
+				t.Fatal(err)
+			}
+			if err := ts.DeliverWebhooks(); err != nil {
+				t.Fatal(err)
+			}
+
+			received := receiver.Received()
+			if len(received) != 1 {
+				t.Fatalf("received %d webhooks, want 1", len(received))
+			}
+			var body struct {
+				Type string         `json:"type"`
+				Data models.Vehicle `json:"data"`
+			}
+			if err := json.Unmarshal(received[0].Body, &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Type != EventVehicleCreated || body.Data.Name != "Camry" {
+				t.Errorf("webhook = %s %q, want %s Camry", body.Type, body.Data.Name, EventVehicleCreated)
+			}
+
+			if events, _ := ts.Events.ListUnprocessed(100); len(events) != 0 {
+				t.Errorf("%d events left unprocessed after fan-out", len(events))
+			}
+			if deliveries, _ := ts.Webhooks.ListDeliveries(sub.ID, 100); len(deliveries) != 1 {
+				t.Errorf("%d deliveries, want 1", len(deliveries))
+			}
+		})
+	}
+}